@@ -0,0 +1,529 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/ningen/v3"
+)
+
+// messageRef references a message within a channel.
+type messageRef struct {
+	ChannelID discord.ChannelID
+	MessageID discord.MessageID
+}
+
+// isForumPost returns true if the message is the starter message of a forum
+// post, which shares its ID with the post's thread.
+func (ref messageRef) isForumPost() bool {
+	return discord.Snowflake(ref.ChannelID) == discord.Snowflake(ref.MessageID)
+}
+
+// announcementRecord is the record of an announcement that the bot has
+// posted. It is keyed by the ID of the first message of the announcement.
+type announcementRecord struct {
+	AuthorID  discord.UserID
+	ChannelID discord.ChannelID
+	// Copies are the copies of the same announcement that were posted to
+	// other channels.
+	Copies []messageRef
+	// Time is the time that the announcement was posted.
+	Time time.Time
+	// Translations are the translated copies of the announcement, keyed by
+	// language.
+	Translations map[string]messageRef
+	// Reach is how far the announcement reached in its first day, or nil if
+	// it hasn't been measured yet.
+	Reach *announcementReach
+	// Staged is the message in the staging channel that the announcement was
+	// promoted from, if it was staged. It is edited along with the
+	// announcement.
+	Staged *messageRef
+	// Revisions are the versions of the announcement's content, oldest
+	// first. Records from before revisions were kept have none.
+	Revisions []announcementRevision
+	// Body and Options are what the latest revision was rendered from.
+	// Records from before they were kept have neither.
+	Body    string
+	Options announceOptions
+	// Footer is the line below the body that says who last edited the
+	// announcement, if EditFooter is set. It is kept apart from the body so
+	// that it is replaced on the next edit.
+	Footer string
+	// Number is the number of the announcement, if NumberAnnouncements was
+	// set when it was posted.
+	Number int
+}
+
+// withFooter returns the body with the given lines of the announcement's
+// footer below it. Empty lines are left out.
+func withFooter(body string, lines ...string) string {
+	lines = slices.DeleteFunc(lines, func(line string) bool { return line == "" })
+	if len(lines) == 0 {
+		return body
+	}
+	return body + "\n\n" + strings.Join(lines, "\n")
+}
+
+// announcementMessage is the rendered message of an announcement.
+type announcementMessage struct {
+	Content string
+	Embeds  []discord.Embed
+	// Attachments are the files of the message, and Files are what they
+	// were downloaded as, which are uploaded again along with the message.
+	// Files must be downloaded before the message is sent.
+	Attachments []discord.Attachment
+	Files       []attachmentFile
+	// ClearAttachments removes the files of the message when it is edited,
+	// such as when it is retracted. Otherwise, edits keep them.
+	ClearAttachments bool
+	// Flags are the flags that the message is sent with, such as
+	// SuppressEmbeds.
+	Flags discord.MessageFlags
+	// TTS sends the message as text-to-speech. Forum posts can't be.
+	TTS bool
+	// Mentions are the mentions in the content that ping. Nothing pings if
+	// it is nil.
+	Mentions *api.AllowedMentions
+}
+
+// pendingAnnouncement is an announcement that is waiting for its author to
+// pick the channels to post in.
+type pendingAnnouncement struct {
+	AuthorID discord.UserID
+	Body     string
+	Options  announceOptions
+	Created  time.Time
+	// Command is the command message that created the announcement, if it
+	// came from one.
+	Command *messageRef
+	// Notes describe what was changed in the body when it was sanitized.
+	Notes []string
+	// Attachments are the files to post along with the body.
+	Attachments []discord.Attachment
+}
+
+// pendingAnnouncementTTL is how long a pending announcement is kept around
+// before it is forgotten.
+const pendingAnnouncementTTL = 15 * time.Minute
+
+// channelPickerPrefix prefixes the custom ID of the channel picker.
+const channelPickerPrefix = "announce-channels:"
+
+// announceChannels returns all channels that announcements may be sent to.
+func (b botState) announceChannels() []discord.ChannelID {
+	return append([]discord.ChannelID{b.TargetChannelID}, b.ExtraChannelIDs...)
+}
+
+// cooldownRemaining returns the time remaining until the next announcement
+// may be sent. It is zero or negative if one may be sent now. While an
+// announcement is still being sent, the whole cooldown remains.
+func (b botState) cooldownRemaining() time.Duration {
+	if b.Announcing > 0 {
+		return b.MinAnnounceTimeGap
+	}
+	return b.MinAnnounceTimeGap - time.Since(b.LastAnnouncedTime)
+}
+
+// checkCooldown refuses to post while the cooldown holds, unless the invoker is
+// exempt from it, in which case the exemption is noted in the audit channel.
+// It returns false if the post was refused, in which case the invoker has
+// been told why.
+func (h *commandHandler) checkCooldown(inv *invocation) bool {
+	remaining := h.bot.cooldownRemaining()
+	if remaining <= 0 {
+		return true
+	}
+
+	if !h.bot.cooldownExempt(inv.member()) {
+		sendRejection(h.session, inv, inv.textWith(msgCooldown, replyData{Remaining: remaining.Round(time.Second)}))
+		return false
+	}
+
+	h.sendAudit(msgCooldownExempted, replyData{
+		Author:    inv.Author.Mention(),
+		Remaining: remaining.Round(time.Second),
+	})
+	return true
+}
+
+// cooldownExempt returns true if the member may announce during the cooldown.
+func (b botState) cooldownExempt(member *discord.Member) bool {
+	return member != nil && hasAnyRole(member, b.CooldownExemptRoleIDs)
+}
+
+// ttsAllowed returns true if the member may post text-to-speech
+// announcements. Invocations without a member, such as from the console, may.
+func (b botState) ttsAllowed(member *discord.Member) bool {
+	return member == nil || hasAnyRole(member, b.TTSRoleIDs)
+}
+
+// foreignChannel returns the first of the given channels that isn't an
+// announcement channel, if any.
+func (b botState) foreignChannel(channelIDs []discord.ChannelID) (discord.ChannelID, bool) {
+	allowed := b.announceChannels()
+	for _, id := range channelIDs {
+		if !slices.Contains(allowed, id) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// askChannels replies to the author with a select menu of the channels that
+// the announcement can be posted in. The announcement is kept pending until
+// the author picks.
+func (h *commandHandler) askChannels(inv *invocation, pending *pendingAnnouncement) {
+	// Forget any pending announcements that were never picked.
+	for id, p := range h.pending {
+		if time.Since(p.Created) > pendingAnnouncementTTL {
+			delete(h.pending, id)
+		}
+	}
+
+	id := inv.ID()
+	h.pending[id] = pending
+
+	state := h.session.Offline()
+
+	channels := h.bot.announceChannels()
+	options := make([]discord.SelectOption, len(channels))
+	for i, channelID := range channels {
+		label := channelID.String()
+		if ch, err := state.Cabinet.Channel(channelID); err == nil {
+			label = "#" + ch.Name
+		}
+		options[i] = discord.SelectOption{
+			Label: label,
+			Value: channelID.String(),
+		}
+	}
+
+	components := discord.Components(
+		&discord.StringSelectComponent{
+			CustomID:    discord.ComponentID(channelPickerPrefix + id),
+			Options:     options,
+			Placeholder: inv.text(msgPickerPlaceholder),
+			ValueLimits: [2]int{1, len(options)},
+		},
+	)
+
+	sendComponents(h.session, inv, inv.text(msgPickChannels), components)
+}
+
+// handleCommandEdit updates the pending announcement of a command message
+// that its author has edited while picking channels, so that the edited
+// version is what gets posted.
+func (h *commandHandler) handleCommandEdit(ev *gateway.MessageUpdateEvent) {
+	// Updates without an edit timestamp only add embeds to the message.
+	if !ev.EditedTimestamp.IsValid() {
+		return
+	}
+
+	id := ev.ID.String()
+	pending, ok := h.pending[id]
+	if !ok || time.Since(pending.Created) > pendingAnnouncementTTL || ev.Author.ID != pending.AuthorID {
+		return
+	}
+
+	msg := &gateway.MessageCreateEvent{Message: ev.Message, Member: ev.Member}
+	command, err := parseCommand(h.session, *h.bot, msg)
+	if err != nil || command == nil || command.Command != "announce" {
+		return
+	}
+
+	inv := newMessageInvocation(msg, h.bot.locale())
+	defer h.trackReplies(inv)
+
+	updated, ok := h.prepareAnnouncement(inv, command)
+	if !ok {
+		// The author has been told what's wrong with the edit, and the
+		// announcement stays as it was.
+		return
+	}
+	// The edit doesn't buy the author more time to pick.
+	updated.Created = pending.Created
+	h.pending[id] = updated
+
+	slog.Info(
+		"Bot has updated a pending announcement after its command was edited.",
+		"author_id", pending.AuthorID,
+		"message_id", ev.ID)
+
+	h.acknowledge(inv, inv.text(msgPendingUpdated))
+}
+
+// pickChannels posts the pending announcement with the given ID into the
+// channels that the author has picked.
+func (h *commandHandler) pickChannels(inv *invocation, id string, values []string) {
+	pending, ok := h.pending[id]
+	if !ok || time.Since(pending.Created) > pendingAnnouncementTTL {
+		sendRejection(h.session, inv, inv.text(msgNoLongerPending))
+		return
+	}
+
+	if pending.AuthorID != inv.Author.ID {
+		sendRejection(h.session, inv, inv.text(msgNotPendingAuthor))
+		return
+	}
+
+	channelIDs := make([]discord.ChannelID, 0, len(values))
+	for _, value := range values {
+		sf, err := discord.ParseSnowflake(value)
+		if err != nil {
+			sendRejection(h.session, inv, inv.text(msgInvalidPick))
+			return
+		}
+		channelIDs = append(channelIDs, discord.ChannelID(sf))
+	}
+
+	if id, ok := h.bot.foreignChannel(channelIDs); ok {
+		sendRejection(h.session, inv, inv.textWith(msgChannelNotAllowed, replyData{Channel: id.Mention()}))
+		return
+	}
+
+	delete(h.pending, id)
+	h.postAnnouncement(inv, pending, channelIDs)
+}
+
+// postAnnouncement posts the announcement into the given channels and records
+// it.
+func (h *commandHandler) postAnnouncement(inv *invocation, pending *pendingAnnouncement, channelIDs []discord.ChannelID) {
+	// Check the pause state again, since the announcement may have been
+	// pending for a while.
+	if h.bot.Runtime.Paused {
+		sendRejection(h.session, inv, inv.textWith(msgPaused, replyData{Reason: h.bot.Runtime.PauseReason}))
+		return
+	}
+
+	// Staged announcements are checked against the cooldown once they are
+	// promoted. Announcements that need approval must never skip staging.
+	if h.bot.StagingChannelID.IsValid() || h.bot.needsApproval(pending.Options, pending.Body) {
+		h.stageAnnouncement(inv, pending, channelIDs)
+		return
+	}
+
+	// Scheduled announcements are checked against the cooldown once they
+	// are due.
+	if pending.Options.SendAt.After(time.Now()) {
+		scheduled := h.scheduleAnnouncement(inv, scheduledAnnouncement{
+			AuthorID:    pending.AuthorID,
+			Body:        pending.Body,
+			Options:     pending.Options,
+			ChannelIDs:  channelIDs,
+			Attachments: pending.Attachments,
+			Created:     time.Now(),
+		})
+		if scheduled && pending.Command != nil {
+			h.deleteCommand(*pending.Command, pending.Options)
+		}
+		return
+	}
+
+	h.downloadThen(inv, pending.Attachments, func(files []attachmentFile, err error) {
+		if err != nil {
+			sendRejection(h.session, inv, inv.textWith(msgAttachmentFailed, replyData{Error: err}))
+			return
+		}
+		h.sendPending(inv, pending, channelIDs, files)
+	})
+}
+
+// sendPending sends the announcement into the given channels, along with the
+// files that its attachments were downloaded as.
+func (h *commandHandler) sendPending(inv *invocation, pending *pendingAnnouncement, channelIDs []discord.ChannelID, files []attachmentFile) {
+	// The bot may have been paused, or another announcement sent, while the
+	// attachments were downloading.
+	if h.bot.Runtime.Paused {
+		sendRejection(h.session, inv, inv.textWith(msgPaused, replyData{Reason: h.bot.Runtime.PauseReason}))
+		return
+	}
+
+	if !h.checkCooldown(inv) {
+		return
+	}
+
+	// The number is taken first, since it is rendered along with the body.
+	number := h.nextNumber()
+
+	msg, err := h.bot.renderAnnouncement(pending.Options, withFooter(pending.Body, h.bot.numberLine(number)))
+	if err != nil {
+		h.releaseNumber(number)
+
+		// This was already checked when the announcement was made.
+		slog.Error(
+			"Bot has failed to render the pending announcement.",
+			"author_id", pending.AuthorID,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	msg.Attachments = pending.Attachments
+	msg.Files = files
+
+	// Make sure that the same announcement isn't sent twice, such as when the
+	// command is redelivered or retried after a timeout.
+	dedupe, ok := h.claimDedupeKey(pending.AuthorID, pending.Body)
+	if !ok {
+		h.releaseNumber(number)
+		sendRejection(h.session, inv, inv.text(msgDuplicate))
+		return
+	}
+
+	// Write the announcement to the outbox before sending it, so that it can
+	// be resumed if the bot dies halfway.
+	entry := &outboxEntry{
+		AuthorID:    pending.AuthorID,
+		Body:        pending.Body,
+		Options:     pending.Options,
+		ChannelIDs:  channelIDs,
+		Created:     time.Now(),
+		Attachments: pending.Attachments,
+		Number:      number,
+	}
+	h.storeOutbox(dedupe, entry)
+
+	h.deliverOutbox(inv, dedupe, entry, msg, func() {
+		sent := entry.Sent
+		if len(sent) == 0 {
+			h.deleteOutbox(dedupe)
+			h.releaseDedupeKey(dedupe)
+			h.releaseNumber(number)
+
+			slog.Error(
+				"Bot has failed to send the announcement to any of its channels.",
+				"author_id", pending.AuthorID,
+				"ref", inv.errorRef())
+
+			replyInternalError(h.session, inv)
+			return
+		}
+
+		h.finishAnnouncement(dedupe, entry, msg)
+
+		// Send a reply to the author, linking them to the announcement so that
+		// they can check how it rendered.
+		data := replyData{Link: messageLink(h.bot.TargetGuildID, sent[0])}
+		switch {
+		case len(sent) < len(channelIDs):
+			sendReply(h.session, inv, inv.textWith(msgPartiallyAnnounced, data))
+		case len(pending.Notes) > 0:
+			// The author should know about the changes even in quiet mode.
+			sendReply(h.session, inv, inv.textWith(msgAnnounced, data)+"\n"+
+				inv.textWith(msgSanitized, replyData{Report: formatBulletList(pending.Notes)}))
+		default:
+			h.acknowledge(inv, inv.textWith(msgAnnounced, data))
+		}
+
+		if pending.Command != nil {
+			h.deleteCommand(*pending.Command, pending.Options)
+		}
+
+		if h.bot.SendReceipts && inv.Console == nil {
+			h.sendReceipt(inv, sent[0])
+		}
+	})
+}
+
+// announcementSender sends and edits the messages of announcements. It holds
+// a copy of the settings, so unlike the handler, it may be used off the event
+// loop.
+type announcementSender struct {
+	session *ningen.State
+	bot     botState
+}
+
+// sender returns an announcementSender that uses the current settings.
+func (h *commandHandler) sender() announcementSender {
+	return announcementSender{session: h.session, bot: *h.bot}
+}
+
+// sendAnnouncement sends the rendered announcement content into the channel.
+// If the channel is a forum, a new post is created in it instead.
+func (h *commandHandler) sendAnnouncement(channelID discord.ChannelID, msg announcementMessage, opts announceOptions) (*discord.Message, error) {
+	return h.sender().send(channelID, msg, opts)
+}
+
+// editAnnouncement replaces the given announcement message.
+func (h *commandHandler) editAnnouncement(ref messageRef, msg announcementMessage) error {
+	return h.sender().edit(ref, msg)
+}
+
+// send sends the rendered announcement content into the channel. If the
+// channel is a forum, a new post is created in it instead.
+func (s announcementSender) send(channelID discord.ChannelID, msg announcementMessage, opts announceOptions) (*discord.Message, error) {
+	if ch, err := s.session.Cabinet.Channel(channelID); err == nil && ch.Type == discord.GuildForum {
+		return s.sendForumPost(ch, msg, opts)
+	}
+
+	mentions := msg.Mentions
+	if mentions == nil {
+		mentions = &api.AllowedMentions{}
+	}
+
+	data := api.SendMessageData{
+		Content:         msg.Content,
+		Embeds:          msg.Embeds,
+		Files:           uploadFiles(msg.Files),
+		Flags:           msg.Flags,
+		TTS:             msg.TTS,
+		AllowedMentions: mentions,
+	}
+
+	return s.session.SendMessageComplex(channelID, data)
+}
+
+// edit replaces the given announcement message.
+func (s announcementSender) edit(ref messageRef, msg announcementMessage) error {
+	embeds := msg.Embeds
+	if embeds == nil {
+		// Explicitly clear any embeds that the old message had.
+		embeds = []discord.Embed{}
+	}
+
+	// Only SuppressEmbeds can be changed once the message is sent, and it is
+	// always given so that turning the option off shows the embeds again.
+	flags := msg.Flags & discord.SuppressEmbeds
+
+	data := api.EditMessageData{
+		Content: option.NewNullableString(msg.Content),
+		Embeds:  &embeds,
+		Flags:   &flags,
+	}
+	if msg.ClearAttachments {
+		data.Attachments = &[]discord.Attachment{}
+	}
+
+	_, err := s.session.EditMessageComplex(ref.ChannelID, ref.MessageID, data)
+	return err
+}
+
+// sendReceipt DMs the author a receipt of their announcement.
+func (h *commandHandler) sendReceipt(inv *invocation, msg messageRef) {
+	cooldownEnds := h.bot.LastAnnouncedTime.Add(h.bot.MinAnnounceTimeGap)
+
+	content := inv.textWith(msgReceipt, replyData{
+		Link:         messageLink(h.bot.TargetGuildID, msg),
+		CooldownEnds: timestampMarkup(cooldownEnds, timestampRelative),
+	})
+
+	dm, err := h.session.CreatePrivateChannel(inv.Author.ID)
+	if err == nil {
+		_, err = h.session.SendMessage(dm.ID, content)
+	}
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to DM the author a receipt.",
+			"author_id", inv.Author.ID,
+			"err", err)
+	}
+}
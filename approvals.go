@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// requiredApprovals is the number of different approvers that must promote a
+// high-impact announcement before it is posted.
+const requiredApprovals = 2
+
+// needsApproval returns true if the announcement must be approved by
+// ApproverRoleIDs before it is posted, which is when it is critical or pings
+// everyone. Such announcements are always staged, since ApproverRoleIDs needs
+// StagingChannelID.
+func (b botState) needsApproval(opts announceOptions, body string) bool {
+	if len(b.ApproverRoleIDs) == 0 {
+		return false
+	}
+	return opts.Critical || massMentionPattern.MatchString(body)
+}
+
+// checkEditApproval refuses edits that would need approval, since edits
+// aren't staged. Approvers may still edit announcements that were approved
+// already, such as to fix a typo. It returns false if the edit was refused,
+// in which case the invoker has been told why.
+func (h *commandHandler) checkEditApproval(inv *invocation, opts announceOptions, body string, record announcementRecord) bool {
+	if !h.bot.needsApproval(opts, body) {
+		return true
+	}
+
+	if h.bot.needsApproval(record.Options, record.Body) {
+		// The console has no roles, so it can't approve anything.
+		if member := h.invokerMember(inv); member != nil && hasAnyRole(member, h.bot.ApproverRoleIDs) {
+			return true
+		}
+	}
+
+	sendRejection(h.session, inv, inv.text(msgEditNeedsApproval))
+	return false
+}
+
+// approve records the invoker's approval of the staged announcement with the
+// given ID. It returns true once the announcement has enough approvals to be
+// promoted, or tells the invoker why not and returns false. Authors can't
+// approve their own announcements, so that a single account can't post one.
+func (h *commandHandler) approve(inv *invocation, id discord.MessageID, staged *stagedAnnouncement) bool {
+	// The console has no roles, so it can't approve anything.
+	member := inv.member()
+	if member == nil || !hasAnyRole(member, h.bot.ApproverRoleIDs) {
+		sendRejection(h.session, inv, inv.text(msgNotApprover))
+		return false
+	}
+
+	if inv.Author.ID == staged.AuthorID {
+		sendRejection(h.session, inv, inv.text(msgCannotApproveOwn))
+		return false
+	}
+
+	if slices.Contains(staged.Approvals, inv.Author.ID) {
+		// Promoting again is fine if the announcement was held up after its
+		// last approval, such as by the cooldown.
+		if len(staged.Approvals) >= requiredApprovals {
+			return true
+		}
+		sendRejection(h.session, inv, inv.text(msgAlreadyApproved))
+		return false
+	}
+
+	staged.Approvals = append(staged.Approvals, inv.Author.ID)
+	if err := h.staged.Store(id, *staged); err != nil {
+		slog.Error(
+			"Bot has failed to store the approval of the staged announcement.",
+			"message_id", id,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return false
+	}
+
+	slog.Info(
+		"A staged announcement has been approved.",
+		"message_id", id,
+		"author_id", staged.AuthorID,
+		"approved_by", inv.Author.ID,
+		"approvals", len(staged.Approvals))
+
+	link := messageLink(h.bot.TargetGuildID, messageRef{ChannelID: h.bot.StagingChannelID, MessageID: id})
+	remaining := requiredApprovals - len(staged.Approvals)
+
+	if remaining > 0 {
+		h.sendAudit(msgApprovalAudit, replyData{Author: inv.Author.Mention(), Link: link, Count: remaining})
+		h.acknowledge(inv, inv.textWith(msgApprovalNoted, replyData{Count: remaining}))
+		return false
+	}
+
+	h.sendAudit(msgSignedOffAudit, replyData{Author: inv.Author.Mention(), Link: link})
+	return true
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+const (
+	// maxAttachmentSize is the largest attachment that the bot copies into
+	// an announcement. Discord refuses larger uploads from bots.
+	maxAttachmentSize = 10 << 20
+	// attachmentTimeout is how long downloading each attachment may take.
+	attachmentTimeout = 30 * time.Second
+	// maxAttachments is the most attachments that a message may have.
+	maxAttachments = 10
+)
+
+// mediaLinkPattern matches a line that is only a link to an image or a video,
+// such as "https://example.com/banner.png". Plain HTTP links are left in the
+// body, since they can't be downloaded safely.
+var mediaLinkPattern = regexp.MustCompile(`^<?(https://\S+/([^/?#\s]+\.(?i:png|jpe?g|gif|webp|mp4|webm|mov)))(?:[?#]\S*)?>?$`)
+
+// cutMediaLinks takes the lines of the body that are only links to media out
+// of it, returning them as attachments to upload instead, so that the
+// announcement keeps working when the host removes the file. Links beyond
+// what the message has room for, given the attachments that it already has,
+// are left in the body.
+func cutMediaLinks(body string, existing int) (string, []discord.Attachment) {
+	var media []discord.Attachment
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		m := mediaLinkPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil || existing+len(media) >= maxAttachments {
+			lines = append(lines, line)
+			continue
+		}
+		media = append(media, discord.Attachment{
+			URL:      m[1],
+			Filename: m[2],
+			// The type is checked against what the host says when the
+			// file is downloaded.
+			ContentType: mime.TypeByExtension(path.Ext(strings.ToLower(m[2]))),
+		})
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), media
+}
+
+// attachmentFile is a downloaded attachment. It is kept in memory, so that
+// it can be uploaded into every channel of an announcement without being
+// downloaded again.
+type attachmentFile struct {
+	Name string
+	Data []byte
+}
+
+// uploadFiles returns the files to upload along with a message. Each file
+// reads from the start, so the same files can be uploaded more than once.
+func uploadFiles(files []attachmentFile) []sendpart.File {
+	parts := make([]sendpart.File, 0, len(files))
+	for _, f := range files {
+		parts = append(parts, sendpart.File{Name: f.Name, Reader: bytes.NewReader(f.Data)})
+	}
+	return parts
+}
+
+// downloadThen downloads the attachments off the event loop, and then calls
+// done with the files on the event loop. If there is nothing to download,
+// done is called right away. An interaction that waits for the download is
+// deferred, since Discord only waits a few seconds for its response.
+func (h *commandHandler) downloadThen(inv *invocation, attachments []discord.Attachment, done func([]attachmentFile, error)) {
+	if len(attachments) == 0 {
+		done(nil, nil)
+		return
+	}
+
+	if inv != nil {
+		h.deferInteraction(inv)
+	}
+
+	h.inBackground(func() func() {
+		files, err := downloadAttachments(attachments)
+		return func() { done(files, err) }
+	})
+}
+
+// downloadAttachments downloads the attachments so that they can be uploaded
+// again along with an announcement. Discord has no way to reuse an uploaded
+// file in another message.
+func downloadAttachments(attachments []discord.Attachment) ([]attachmentFile, error) {
+	files := make([]attachmentFile, 0, len(attachments))
+	for _, a := range attachments {
+		if a.Size > maxAttachmentSize {
+			return nil, fmt.Errorf("attachment %q is larger than %d MB", a.Filename, maxAttachmentSize>>20)
+		}
+
+		b, err := downloadAttachment(a.URL, a.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download attachment %q: %w", a.Filename, err)
+		}
+
+		files = append(files, attachmentFile{Name: a.Filename, Data: b})
+	}
+	return files, nil
+}
+
+// attachmentClient downloads attachments. It only follows HTTPS and only
+// connects to public addresses, so that the links in announcements can't be
+// used to reach the network that the bot runs in.
+var attachmentClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: attachmentTimeout,
+			Control: dialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: attachmentTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return errors.New("redirected away from HTTPS")
+		}
+		if len(via) >= 10 {
+			return errors.New("redirected too many times")
+		}
+		return nil
+	},
+}
+
+// sharedAddressSpace is the range of addresses that carriers use for their
+// own networks, which netip doesn't count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// dialPublicOnly refuses to connect to addresses that aren't public. It is
+// called with the address that the host was resolved to, so hosts that
+// resolve to private addresses are refused too.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%s is not a public address", ip)
+	}
+	return nil
+}
+
+// downloadAttachment downloads the file at the URL, which must be HTTPS. If
+// contentType is given, the file must be of the same kind, such as any image
+// for "image/png".
+func downloadAttachment(link discord.URL, contentType string) ([]byte, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, errors.New("only HTTPS links can be downloaded")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), attachmentTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := attachmentClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if contentType != "" {
+		want, _, _ := strings.Cut(contentType, "/")
+		got, _, _ := strings.Cut(resp.Header.Get("Content-Type"), "/")
+		if !strings.EqualFold(want, got) {
+			return nil, fmt.Errorf("expected a file of type %s, got %q", contentType, resp.Header.Get("Content-Type"))
+		}
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxAttachmentSize {
+		return nil, fmt.Errorf("larger than %d MB", maxAttachmentSize>>20)
+	}
+	return b, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+// sendAudit posts an alert into the audit channel, along with the given
+// files. The alert is always logged and added to the audit log, so that it
+// isn't lost if no audit channel is configured. The post carries the hash
+// that the audit log now ends in, so that the chain can't be rewritten in the
+// state directory alone without it showing. The files are added to the audit
+// log along with the alert, since they are often what matters in it, such as
+// the diff of an edit.
+func (h *commandHandler) sendAudit(key messageKey, data replyData, files ...sendpart.File) {
+	content := localize(h.bot.locale(), key, data)
+
+	entry := content
+	for i, f := range files {
+		b, err := io.ReadAll(f.Reader)
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to read a file attached to an alert.",
+				"key", key,
+				"file", f.Name,
+				"err", err)
+		}
+		files[i].Reader = bytes.NewReader(b)
+		entry += "\n\n" + f.Name + ":\n" + string(b)
+	}
+	logged := h.appendAudit(key, entry)
+
+	slog.Warn(
+		"Bot has raised an alert.",
+		"key", key,
+		"alert", content)
+
+	if !h.bot.AuditChannelID.IsValid() {
+		return
+	}
+
+	if logged {
+		content = withFooter(content, localize(h.bot.locale(), msgAuditAnchor, replyData{
+			Count: int(h.auditSeq),
+			Hash:  h.auditHash,
+		}))
+	}
+
+	_, err := h.session.SendMessageComplex(h.bot.AuditChannelID, api.SendMessageData{
+		Content: content,
+		Files:   files,
+		// Alerts should never ping anyone.
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		slog.Error(
+			"Bot has failed to post an alert into the audit channel.",
+			"channel_id", h.bot.AuditChannelID,
+			"err", err)
+	}
+}
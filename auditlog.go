@@ -0,0 +1,112 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"libdb.so/persist"
+)
+
+// auditEntry is an alert that the bot has raised, as kept in the audit log.
+// It is keyed by its sequence number, starting at 1. Each entry's hash covers
+// the hash of the entry before it, so changing or removing an entry breaks
+// the chain from there on. Since the chain could be recomputed by whoever can
+// write to the state directory, the hash of each entry is also posted along
+// with its alert into the audit channel, where it can be compared against.
+//
+// Only alerts are chained. Announcement records aren't, so the audit log
+// can't tell whether they were changed.
+type auditEntry struct {
+	Time    time.Time
+	Key     messageKey
+	Content string
+	// Prev is the hash of the entry before, or empty for the first entry.
+	Prev string
+	// Hash is the hash of the entry, as computed by hashAuditEntry.
+	Hash string
+}
+
+// hashAuditEntry returns the hash of the entry with the given sequence
+// number. The time is hashed to the second, since that is all that is
+// stored.
+func hashAuditEntry(seq uint64, e auditEntry) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%d\n%s\n", seq, e.Prev, e.Time.Unix(), e.Key)
+	h.Write([]byte(e.Content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lastAuditEntry returns the sequence number and hash of the latest entry in
+// the audit log, which the next entry is chained to.
+func lastAuditEntry(auditLog persist.Map[uint64, auditEntry]) (uint64, string) {
+	var seq uint64
+	var hash string
+	auditLog.All()(func(s uint64, e auditEntry) bool {
+		if s > seq {
+			seq, hash = s, e.Hash
+		}
+		return true
+	})
+	return seq, hash
+}
+
+// appendAudit adds the alert to the end of the audit log. It returns false if
+// the alert could not be added.
+func (h *commandHandler) appendAudit(key messageKey, content string) bool {
+	entry := auditEntry{
+		Time:    time.Now(),
+		Key:     key,
+		Content: content,
+		Prev:    h.auditHash,
+	}
+	seq := h.auditSeq + 1
+	entry.Hash = hashAuditEntry(seq, entry)
+
+	if err := h.auditLog.Store(seq, entry); err != nil {
+		slog.Error(
+			"Bot has failed to add the alert to the audit log.",
+			"key", key,
+			"err", err)
+		return false
+	}
+
+	h.auditSeq, h.auditHash = seq, entry.Hash
+	return true
+}
+
+// verifyAuditLog checks that the audit log is an unbroken chain, returning
+// the number of entries checked and the hash of the latest one. If it isn't,
+// the error says at which entry it breaks.
+func verifyAuditLog(auditLog persist.Map[uint64, auditEntry]) (int, string, error) {
+	type item struct {
+		seq   uint64
+		entry auditEntry
+	}
+
+	var items []item
+	auditLog.All()(func(seq uint64, e auditEntry) bool {
+		items = append(items, item{seq, e})
+		return true
+	})
+	slices.SortFunc(items, func(a, b item) int { return cmp.Compare(a.seq, b.seq) })
+
+	var prev string
+	for i, item := range items {
+		switch {
+		case item.seq != uint64(i+1):
+			return i, prev, fmt.Errorf("entry %d is missing", i+1)
+		case item.entry.Prev != prev:
+			return i, prev, fmt.Errorf("entry %d doesn't follow the entry before it", item.seq)
+		case item.entry.Hash != hashAuditEntry(item.seq, item.entry):
+			return i, prev, fmt.Errorf("entry %d has been changed", item.seq)
+		}
+		prev = item.entry.Hash
+	}
+
+	return len(items), prev, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/ningen/v3"
+	"libdb.so/persist"
+	persistbadgerdb "libdb.so/persist/driver/badgerdb"
+)
+
+// backfillMessageLimit is how many of each channel's most recent messages are
+// looked through when backfilling.
+const backfillMessageLimit = 1000
+
+// messageLinkPattern matches a jump link to a message, capturing its message
+// ID.
+var messageLinkPattern = regexp.MustCompile(`https://discord\.com/channels/\d+/\d+/(\d+)`)
+
+// backfillChannels returns the channels whose history is looked through for
+// the bot's replies to commands: the target channel, then the command
+// channels.
+func (b botSettings) backfillChannels() []discord.ChannelID {
+	channels := []discord.ChannelID{b.TargetChannelID}
+	for _, id := range b.CommandChannelIDs {
+		if !slices.Contains(channels, id) {
+			channels = append(channels, id)
+		}
+	}
+	return channels
+}
+
+// backfillLastSent seeds lastSentAuthors from the channel history, so that
+// edit works for announcements made before the bot remembered who made them.
+// Each announcement posted by the bot in the target channel is attributed to
+// its author using its announcement record, or else using the bot's reply to
+// the command that made it, which is looked for in the target channel and in
+// the command channels. Authors that already have a last announcement are
+// left alone. It returns the number of authors that were backfilled.
+func backfillLastSent(
+	session *ningen.State,
+	selfID discord.UserID,
+	bot botSettings,
+	announcements persist.Map[discord.MessageID, announcementRecord],
+	lastSentAuthors persist.Map[discord.UserID, discord.MessageID],
+) (int, error) {
+	var replies []discord.Message
+	posted := make(map[discord.MessageID]bool)
+	for _, channelID := range bot.backfillChannels() {
+		messages, err := session.Client.Messages(channelID, backfillMessageLimit)
+		if err != nil {
+			return 0, fmt.Errorf("cannot fetch the history of channel %d: %w", channelID, err)
+		}
+
+		for _, msg := range messages {
+			if msg.Author.ID != selfID {
+				continue
+			}
+			if channelID == bot.TargetChannelID {
+				posted[msg.ID] = true
+			}
+			replies = append(replies, msg)
+		}
+	}
+
+	latest := make(map[discord.UserID]discord.MessageID)
+	attribute := func(authorID discord.UserID, id discord.MessageID) {
+		if authorID.IsValid() && id > latest[authorID] {
+			latest[authorID] = id
+		}
+	}
+
+	for _, msg := range replies {
+		if posted[msg.ID] {
+			if record, ok, err := announcements.Load(msg.ID); err == nil && ok {
+				attribute(record.AuthorID, msg.ID)
+				continue
+			}
+		}
+
+		// The bot replies to each command with a link to the announcement
+		// that it made, either as a reply to the command message or as the
+		// response to the interaction.
+		var authorID discord.UserID
+		switch {
+		case msg.ReferencedMessage != nil:
+			authorID = msg.ReferencedMessage.Author.ID
+		case msg.Interaction != nil:
+			authorID = msg.Interaction.User.ID
+		}
+		if !authorID.IsValid() || authorID == selfID {
+			continue
+		}
+
+		for _, match := range messageLinkPattern.FindAllStringSubmatch(msg.Content, -1) {
+			sf, err := discord.ParseSnowflake(match[1])
+			if err != nil {
+				continue
+			}
+			if id := discord.MessageID(sf); posted[id] {
+				attribute(authorID, id)
+			}
+		}
+	}
+
+	var backfilled int
+	for authorID, id := range latest {
+		if _, ok, err := lastSentAuthors.Load(authorID); err != nil || ok {
+			continue
+		}
+		if err := lastSentAuthors.Store(authorID, id); err != nil {
+			return backfilled, fmt.Errorf("cannot store the last announcement of %d: %w", authorID, err)
+		}
+		backfilled++
+	}
+
+	return backfilled, nil
+}
+
+// backfillOnce backfills lastSentAuthors in the background, once. It is
+// skipped if the bot already remembers someone's last announcement, since it
+// has then been running since before backfilling was needed. Either way, it
+// is recorded as done, so that the history isn't fetched again on the next
+// start.
+func (h *commandHandler) backfillOnce() {
+	if h.bot.Runtime.Backfilled {
+		return
+	}
+
+	empty := true
+	h.lastSentAuthors.Keys()(func(discord.UserID) bool {
+		empty = false
+		return false
+	})
+	if !empty {
+		h.bot.Runtime.Backfilled = true
+		h.saveRuntime()
+		return
+	}
+
+	session := h.session
+	selfID := h.bot.SelfID
+	bot := h.bot.botSettings
+	announcements := h.announcements
+	lastSentAuthors := h.lastSentAuthors
+
+	h.inBackground(func() func() {
+		n, err := backfillLastSent(session, selfID, bot, announcements, lastSentAuthors)
+		return func() {
+			if err != nil {
+				slog.Warn(
+					"Bot has failed to backfill the last announcements from the channel history. It will try again on the next start.",
+					"err", err)
+				return
+			}
+
+			h.bot.Runtime.Backfilled = true
+			h.saveRuntime()
+
+			slog.Info(
+				"Bot has backfilled the last announcements from the channel history.",
+				"authors", n)
+		}
+	})
+}
+
+func backfillSubcommand(ctx context.Context, args []string) int {
+	token := os.Getenv("DISCORD_TOKEN")
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "backfilling requires $DISCORD_TOKEN to be set")
+		return 1
+	}
+
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "the config is invalid:", err)
+		return 1
+	}
+
+	release, err := lockStateOffline(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot use the state:", err)
+		return 1
+	}
+	defer release()
+
+	announcements, err := persist.NewMap[discord.MessageID, announcementRecord](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "announcements-v1"),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot open the announcements database:", err)
+		return 1
+	}
+	defer closeStore("announcements", announcements)
+
+	lastSentAuthors, err := persist.NewMap[discord.UserID, discord.MessageID](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "last-sent-authors-v1"),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot open the last-sent-authors database:", err)
+		return 1
+	}
+	defer closeStore("last-sent-authors", lastSentAuthors)
+
+	// Only the REST API is needed, so the gateway is never opened.
+	session := newSession(token).WithContext(ctx)
+
+	me, err := session.Me()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot get the bot's own user:", err)
+		return 1
+	}
+
+	n, err := backfillLastSent(session, me.ID, settings, announcements, lastSentAuthors)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("backfilled the last announcement of %d authors\n", n)
+	return 0
+}
@@ -0,0 +1,34 @@
+package main
+
+import "time"
+
+// inBackground runs work on its own goroutine, so that slow requests don't
+// hold up the event loop, and then runs the function that work returns on the
+// event loop. The handler may only be used from the event loop, so work must
+// only use what it was given, such as a sender. The returned function may be
+// nil. If the event loop has stopped by the time work is done, the returned
+// function is dropped.
+func (h *commandHandler) inBackground(work func() func()) {
+	h.backgroundTasks++
+	go func() {
+		done := work()
+		select {
+		case h.completions <- func() {
+			h.backgroundTasks--
+			if done != nil {
+				done()
+			}
+		}:
+		case <-h.stopped:
+		}
+	}()
+}
+
+// after runs fn on the event loop once d has passed, without holding up the
+// event loop in the meantime.
+func (h *commandHandler) after(d time.Duration, fn func()) {
+	h.inBackground(func() func() {
+		time.Sleep(d)
+		return fn
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// isBlocked returns true if the user may not use this bot at all, either
+// because they are listed in BlockedUserIDs or because they have been blocked
+// at runtime.
+func (b botState) isBlocked(userID discord.UserID) bool {
+	return slices.Contains(b.BlockedUserIDs, userID) || slices.Contains(b.Runtime.BlockedUserIDs, userID)
+}
+
+// block adds or removes the user given in the command's arguments to or from
+// the runtime blocklist.
+func (h *commandHandler) block(inv *invocation, command *parsedCommand, block bool) {
+	userID, err := parseUserMention(command.Args)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidUser, replyData{Error: err}))
+		return
+	}
+
+	data := replyData{Target: userID.Mention()}
+
+	if block {
+		if userID == inv.Author.ID {
+			sendRejection(h.session, inv, inv.text(msgCannotBlockSelf))
+			return
+		}
+		if !slices.Contains(h.bot.Runtime.BlockedUserIDs, userID) {
+			h.bot.Runtime.BlockedUserIDs = append(h.bot.Runtime.BlockedUserIDs, userID)
+		}
+	} else {
+		if slices.Contains(h.bot.BlockedUserIDs, userID) {
+			sendRejection(h.session, inv, inv.textWith(msgBlockedByConfig, data))
+			return
+		}
+		h.bot.Runtime.BlockedUserIDs = slices.DeleteFunc(h.bot.Runtime.BlockedUserIDs, func(id discord.UserID) bool {
+			return id == userID
+		})
+	}
+	h.saveRuntime()
+
+	slog.Info(
+		"The blocklist has been changed.",
+		"author_id", inv.Author.ID,
+		"user_id", userID,
+		"blocked", block)
+
+	if block {
+		h.acknowledge(inv, inv.textWith(msgBlocked, data))
+	} else {
+		h.acknowledge(inv, inv.textWith(msgUnblocked, data))
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	// bumpCheckInterval is how often the bot looks for announcements whose
+	// "bump-after" time has come.
+	bumpCheckInterval = time.Minute
+	// minBumpAfter and maxBumpAfter bound the "bump-after" option. Bumping
+	// any sooner would only be noise, and any later would be forgotten.
+	minBumpAfter = time.Hour
+	maxBumpAfter = 30 * 24 * time.Hour
+)
+
+// scheduleBump schedules the announcement whose primary message is id to be
+// bumped at the given time. A zero time cancels the bump.
+func (h *commandHandler) scheduleBump(id discord.MessageID, at time.Time) {
+	var err error
+	if at.IsZero() {
+		err = h.bumps.Delete(id)
+	} else {
+		err = h.bumps.Store(id, at)
+	}
+	if err != nil {
+		slog.Error(
+			"Bot has failed to schedule the bump of an announcement.",
+			"message_id", id,
+			"bump_at", at,
+			"err", err)
+	}
+}
+
+// rescheduleBump schedules the bump of an edited announcement from when it
+// was posted. Announcements that have already been bumped aren't bumped
+// again.
+func (h *commandHandler) rescheduleBump(id discord.MessageID, record announcementRecord) {
+	_, scheduled, err := h.bumps.Load(id)
+	if err != nil {
+		return
+	}
+
+	at := record.Time.Add(record.Options.BumpAfter)
+	switch {
+	case record.Options.BumpAfter == 0:
+		h.scheduleBump(id, time.Time{})
+	case scheduled || at.After(time.Now()):
+		h.scheduleBump(id, at)
+	}
+}
+
+// bumpDueAnnouncements links to the announcements whose "bump-after" time has
+// come in their channels once more, for those who missed them the first time.
+func (h *commandHandler) bumpDueAnnouncements() {
+	now := time.Now()
+
+	var due []discord.MessageID
+	h.bumps.All()(func(id discord.MessageID, at time.Time) bool {
+		if !now.Before(at) {
+			due = append(due, id)
+		}
+		return true
+	})
+
+	for _, id := range due {
+		record, ok, err := h.announcements.Load(id)
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to look up an announcement that is due to be bumped. It will try again later.",
+				"message_id", id,
+				"err", err)
+			continue
+		}
+
+		// The announcement may have been deleted since.
+		if ok {
+			h.bumpAnnouncement(id, record)
+		}
+		h.scheduleBump(id, time.Time{})
+	}
+}
+
+// bumpAnnouncement posts a link to the announcement in each of its channels.
+// Forum posts aren't bumped, since every post is its own thread.
+func (h *commandHandler) bumpAnnouncement(id discord.MessageID, record announcementRecord) {
+	messages := []messageRef{{ChannelID: record.ChannelID, MessageID: id}}
+	messages = append(messages, record.Copies...)
+
+	for _, ref := range messages {
+		if ref.isForumPost() {
+			continue
+		}
+
+		_, err := h.session.SendMessageComplex(ref.ChannelID, api.SendMessageData{
+			Content: localize(h.bot.locale(), msgBump, replyData{Link: messageLink(h.bot.TargetGuildID, ref)}),
+			// The announcement already pinged everyone it should have.
+			AllowedMentions: &api.AllowedMentions{},
+		})
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to bump an announcement.",
+				"channel_id", ref.ChannelID,
+				"message_id", ref.MessageID,
+				"err", err)
+			continue
+		}
+
+		slog.Info(
+			"Bot has bumped an announcement.",
+			"channel_id", ref.ChannelID,
+			"message_id", ref.MessageID)
+	}
+}
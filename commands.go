@@ -0,0 +1,796 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/ningen/v3"
+	"libdb.so/persist"
+)
+
+// commandHandler handles parsed commands. It is only ever used from the event
+// loop, so none of its fields need to be guarded.
+type commandHandler struct {
+	session         *ningen.State
+	lastSentAuthors persist.Map[discord.UserID, discord.MessageID]
+	announcements   persist.Map[discord.MessageID, announcementRecord]
+	// subscribePrompts maps each channel to its subscribe prompt message.
+	subscribePrompts persist.Map[discord.ChannelID, discord.MessageID]
+	runtimeStates    persist.Map[string, runtimeState]
+	// dedupeKeys maps the idempotency key of each recent announcement to
+	// when it was claimed.
+	dedupeKeys persist.Map[string, time.Time]
+	// outbox holds announcements that are yet to be fully sent.
+	outbox persist.Map[string, outboxEntry]
+	// staged holds announcements that are waiting in the staging channel to
+	// be promoted.
+	staged persist.Map[discord.MessageID, stagedAnnouncement]
+	// translator translates announcements, or is nil if they aren't
+	// translated.
+	translator translator
+	// processors are run over the body of each announcement before it is
+	// posted, in order.
+	processors []processor
+	// metrics records how long commands take and how they went.
+	metrics *metrics
+	// gateway tracks the health of the gateway connection.
+	gateway *gatewayMonitor
+	// draining is true once the bot has been asked to drain. It takes no new
+	// announcements and exits once nothing is left to send.
+	draining bool
+	// recentEvents are the events that the event loop has most recently
+	// processed, oldest first.
+	recentEvents []processedEvent
+	bot          *botState
+
+	// pending holds announcements that are waiting for their authors to pick
+	// channels. It is keyed by the ID of the invocation.
+	pending map[string]*pendingAnnouncement
+	stats   sessionStats
+	flood   floodGuard
+	// forgetRequests maps each user who has asked to be forgotten to when
+	// they asked, until they confirm.
+	forgetRequests map[discord.UserID]time.Time
+	// editRequests maps each author who has reacted to edit an announcement
+	// to what they are editing, until they reply with the new body.
+	editRequests map[discord.UserID]editRequest
+	// templates maps the name of each saved template to it.
+	templates persist.Map[string, announcementTemplate]
+	// snippets maps the name of each snippet to its text.
+	snippets persist.Map[string, snippet]
+	// deletions maps the primary message of each announcement that deletes
+	// itself to when it does.
+	deletions persist.Map[discord.MessageID, time.Time]
+	// scheduled maps the number of each announcement that waits for its
+	// "send-at" time to it.
+	scheduled persist.Map[int, scheduledAnnouncement]
+	// sendingScheduled holds the numbers of the scheduled announcements whose
+	// attachments are being downloaded, so that they aren't sent twice.
+	sendingScheduled map[int]struct{}
+	// stickies maps each channel to the announcement that is kept at its
+	// bottom, and pushedStickies holds the channels whose sticky
+	// announcement has been pushed up since it was last posted.
+	stickies       persist.Map[discord.ChannelID, stickyAnnouncement]
+	pushedStickies map[discord.ChannelID]struct{}
+	// bumps maps the primary message of each announcement that is yet to be
+	// bumped to when it is.
+	bumps persist.Map[discord.MessageID, time.Time]
+	// slowmodes maps each channel that the bot has slowed down for an
+	// announcement to the slowmode that it restores.
+	slowmodes persist.Map[discord.ChannelID, slowmodeRestore]
+	// auditLog holds every alert that the bot has raised, and auditSeq and
+	// auditHash are the sequence number and hash of its latest entry.
+	auditLog  persist.Map[uint64, auditEntry]
+	auditSeq  uint64
+	auditHash string
+	// signatures maps each signature that has been used to when, so that it
+	// can't be used again.
+	signatures persist.Map[string, time.Time]
+	// searchTerms maps each term in the search index to the announcements
+	// that contain it, and searchDocuments maps each indexed announcement to
+	// its terms. searchVocabulary holds every indexed term, sorted, so that
+	// prefixes can be looked up.
+	searchTerms      persist.Map[string, searchPostings]
+	searchDocuments  persist.Map[discord.MessageID, []string]
+	searchVocabulary []string
+	// countdowns maps each countdown message to what it counts down to.
+	countdowns persist.Map[discord.MessageID, countdown]
+	// presenceIndex is the index of the activity that is being shown.
+	presenceIndex int
+
+	// roleNames maps each role in the target guild to its last known name.
+	roleNames map[discord.RoleID]string
+	// replies maps each recent command message to the replies that the bot
+	// has sent to it.
+	replies map[discord.MessageID]*trackedReplies
+	// botDeletions are the command messages that the bot has deleted itself
+	// and whose delete events are yet to arrive.
+	botDeletions map[discord.MessageID]struct{}
+	// completions receives what is left to do on the event loop once work
+	// in the background is done, and backgroundTasks counts the work that
+	// isn't done yet.
+	completions     chan func()
+	backgroundTasks int
+	// stopped is closed once the event loop has stopped, after which
+	// nothing receives from completions.
+	stopped chan struct{}
+}
+
+// invocation describes where a command came from. Exactly one of Message,
+// Interaction and Console is set.
+type invocation struct {
+	Author    discord.User
+	ChannelID discord.ChannelID
+	// Locale is the locale to reply in.
+	Locale string
+
+	Message     *gateway.MessageCreateEvent
+	Interaction *discord.InteractionEvent
+	// Console is where replies are printed for commands typed into the REPL.
+	Console io.Writer
+
+	// consoleID is the unique ID of a command typed into the REPL.
+	consoleID string
+
+	// responded is true if the interaction has already been responded to, in
+	// which case further replies must be sent as follow-ups.
+	responded bool
+	// outcome is how the command went, as recorded in the metrics. It is
+	// empty while nothing has gone wrong.
+	outcome string
+	// ref is the correlation ID of the internal error that the invocation
+	// ran into, if any.
+	ref string
+	// replies are the messages that have been sent in reply to the command
+	// message, if the invocation has one.
+	replies []discord.MessageID
+}
+
+// errorRef returns the correlation ID of the invocation's internal error. It
+// is included both in the logs and in the reply, so that users can report
+// exactly which failure they hit.
+func (inv *invocation) errorRef() string {
+	if inv.ref == "" {
+		var b [3]byte
+		rand.Read(b[:])
+		inv.ref = strings.ToUpper(hex.EncodeToString(b[:]))
+	}
+	return inv.ref
+}
+
+func newMessageInvocation(msg *gateway.MessageCreateEvent, locale string) *invocation {
+	return &invocation{
+		Author:    msg.Author,
+		ChannelID: msg.ChannelID,
+		Locale:    locale,
+		Message:   msg,
+	}
+}
+
+func newInteractionInvocation(ev *discord.InteractionEvent, locale string) *invocation {
+	return &invocation{
+		Author:      *ev.Sender(),
+		ChannelID:   ev.ChannelID,
+		Locale:      locale,
+		Interaction: ev,
+	}
+}
+
+// text renders the message with the given key in the invocation's locale.
+func (inv *invocation) text(key messageKey) string {
+	return inv.textWith(key, replyData{})
+}
+
+// textWith is like text, except the message template is given the data.
+func (inv *invocation) textWith(key messageKey, data replyData) string {
+	data.Author = inv.Author.Mention()
+	return localize(inv.Locale, key, data)
+}
+
+// member returns the guild member who made the invocation.
+func (inv *invocation) member() *discord.Member {
+	switch {
+	case inv.Interaction != nil:
+		return inv.Interaction.Member
+	case inv.Message != nil:
+		return inv.Message.Member
+	default:
+		return nil
+	}
+}
+
+// ID returns a unique ID for the invocation.
+func (inv *invocation) ID() string {
+	switch {
+	case inv.Interaction != nil:
+		return inv.Interaction.ID.String()
+	case inv.Message != nil:
+		return inv.Message.ID.String()
+	default:
+		return inv.consoleID
+	}
+}
+
+// commandSpec describes a command that the bot understands.
+type commandSpec struct {
+	// Public is true if anyone in the guild may use the command, not just
+	// those in AllowedRoleIDs.
+	Public bool
+	// Admin is true if only those in AdminRoleIDs may use the command.
+	Admin bool
+	// NeedsBody is true if the command must have a body.
+	NeedsBody bool
+	// Posts is true if the command changes announcements. Such commands are
+	// refused while announcements are paused.
+	Posts bool
+}
+
+// commandSpecs maps each command name to its spec.
+var commandSpecs = map[string]commandSpec{
+	"announce":    {NeedsBody: true, Posts: true},
+	"edit":        {NeedsBody: true, Posts: true},
+	"poll":        {NeedsBody: true, Posts: true},
+	"countdown":   {NeedsBody: true, Posts: true},
+	"snooze":      {Posts: true},
+	"retract":     {Posts: true},
+	"get":         {},
+	"search":      {},
+	"stage":       {Posts: true},
+	"template":    {},
+	"snippet":     {Admin: true},
+	"promote":     {Admin: true, Posts: true},
+	"subscribe":   {Public: true},
+	"unsubscribe": {Public: true},
+	"pause":       {Admin: true},
+	"resume":      {Admin: true},
+	"doctor":      {Admin: true},
+	"block":       {Admin: true},
+	"unblock":     {Admin: true},
+	"lock":        {Admin: true},
+	"unlock":      {Admin: true},
+	"stats":       {},
+	"status":      {},
+	"errors":      {Admin: true},
+	"export-me":   {Public: true},
+	"forget-me":   {Public: true},
+}
+
+func (h *commandHandler) handleCommand(inv *invocation, command *parsedCommand) {
+	defer h.trackReplies(inv)
+
+	if !h.admitCommand(inv) {
+		return
+	}
+
+	start := time.Now()
+	defer func() { h.observeCommand(inv, command, time.Since(start)) }()
+
+	// Let the author know that the command was received if it takes a while,
+	// such as when processors or translations are slow.
+	defer h.startTyping(inv)()
+
+	slog.Info(
+		"This bot has received a valid command.",
+		"author.id", inv.Author.ID,
+		"author.tag", inv.Author.Tag(),
+		"command", command.Command,
+		"body", command.Body)
+
+	h.stats.Commands++
+
+	if h.draining && commandSpecs[command.Command].Posts {
+		sendRejection(h.session, inv, inv.text(msgDraining))
+		return
+	}
+
+	if h.bot.Runtime.Paused && commandSpecs[command.Command].Posts {
+		sendRejection(h.session, inv, inv.textWith(msgPaused, replyData{Reason: h.bot.Runtime.PauseReason}))
+		return
+	}
+
+	switch command.Command {
+	case "announce":
+		h.announce(inv, command)
+	case "edit":
+		h.edit(inv, command)
+	case "poll":
+		h.poll(inv, command)
+	case "countdown":
+		h.startCountdown(inv, command)
+	case "snooze":
+		h.snooze(inv, command)
+	case "retract":
+		h.retract(inv, command)
+	case "get":
+		h.getNumbered(inv, command)
+	case "search":
+		h.search(inv, command)
+	case "stage":
+		h.stage(inv, command)
+	case "template":
+		h.template(inv, command)
+	case "snippet":
+		h.snippet(inv, command)
+	case "promote":
+		h.promote(inv, command)
+	case "subscribe":
+		h.subscribe(inv, true)
+	case "unsubscribe":
+		h.subscribe(inv, false)
+	case "pause":
+		h.pause(inv, command)
+	case "resume":
+		h.resume(inv)
+	case "doctor":
+		h.doctor(inv)
+	case "block":
+		h.block(inv, command, true)
+	case "unblock":
+		h.block(inv, command, false)
+	case "lock":
+		h.lock(inv, command, true)
+	case "unlock":
+		h.lock(inv, command, false)
+	case "stats":
+		h.showStats(inv, command)
+	case "status":
+		h.status(inv)
+	case "errors":
+		h.showErrors(inv)
+	case "export-me":
+		h.exportMe(inv)
+	case "forget-me":
+		h.forgetMe(inv, command)
+	}
+}
+
+func (h *commandHandler) announce(inv *invocation, command *parsedCommand) {
+	// For announcing a new message, ensure that the global rate limit is
+	// respected.
+	if remaining := h.bot.cooldownRemaining(); remaining > 0 && !h.bot.cooldownExempt(inv.member()) {
+		sendRejection(h.session, inv, inv.textWith(msgCooldown, replyData{Remaining: remaining.Round(time.Second)}))
+		return
+	}
+
+	pending, ok := h.prepareAnnouncement(inv, command)
+	if !ok {
+		return
+	}
+	opts := pending.Options
+
+	channelIDs := opts.Channels
+	if len(channelIDs) == 0 {
+		// Only bot accounts can send components, so users can only pick
+		// channels through the picker if the bot is one. The REPL can't
+		// show the picker at all.
+		if len(h.bot.ExtraChannelIDs) > 0 && h.bot.AppID.IsValid() && inv.Console == nil {
+			h.askChannels(inv, pending)
+			return
+		}
+		channelIDs = []discord.ChannelID{h.bot.TargetChannelID}
+	}
+
+	h.postAnnouncement(inv, pending, channelIDs)
+}
+
+// prepareAnnouncement checks the announce command and turns it into a pending
+// announcement. If the command is refused, the author is told why and false
+// is returned.
+func (h *commandHandler) prepareAnnouncement(inv *invocation, command *parsedCommand) (*pendingAnnouncement, bool) {
+	opts, err := parseAnnounceOptions(command.Options)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidOptions, replyData{Error: err}))
+		return nil, false
+	}
+
+	if opts.TTS && !h.bot.ttsAllowed(inv.member()) {
+		sendRejection(h.session, inv, inv.text(msgTTSNotAllowed))
+		return nil, false
+	}
+
+	body, ok := h.verifySignature(inv, command.Options, command.Body)
+	if !ok {
+		return nil, false
+	}
+
+	body, notes, ok := h.rewriteBody(inv, opts, body)
+	if !ok {
+		return nil, false
+	}
+
+	attachments := command.Attachments
+	if opts.ReuploadMedia {
+		var media []discord.Attachment
+		body, media = cutMediaLinks(body, len(attachments))
+		attachments = append(attachments, media...)
+	}
+
+	rendered, err := h.bot.renderAnnouncement(opts, body)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidEmbed, replyData{Error: err}))
+		return nil, false
+	}
+
+	if !h.checkLint(inv, opts, rendered) {
+		return nil, false
+	}
+
+	if id, ok := h.bot.foreignChannel(opts.Channels); ok {
+		sendRejection(h.session, inv, inv.textWith(msgChannelNotAllowed, replyData{Channel: id.Mention()}))
+		return nil, false
+	}
+
+	pending := &pendingAnnouncement{
+		AuthorID:    inv.Author.ID,
+		Body:        body,
+		Options:     opts,
+		Created:     time.Now(),
+		Notes:       notes,
+		Attachments: attachments,
+	}
+	if inv.Message != nil {
+		pending.Command = &messageRef{ChannelID: inv.ChannelID, MessageID: inv.Message.ID}
+	}
+
+	return pending, true
+}
+
+// rewriteBody turns the body as the author wrote it into the body to post,
+// and screens it. It returns the body along with notes on what screening
+// changed in it. If the body is refused, the author is told why and false is
+// returned.
+//
+// Signed bodies are posted exactly as they were signed, so nothing rewrites
+// them, and they are refused if screening would change them.
+func (h *commandHandler) rewriteBody(inv *invocation, opts announceOptions, body string) (string, []string, bool) {
+	if h.bot.signsAnnouncements() {
+		screened, notes, ok := h.screenBody(inv, opts, body)
+		if !ok {
+			return "", nil, false
+		}
+		if screened != body {
+			sendRejection(h.session, inv, inv.text(msgSignedBodyChanged))
+			return "", nil, false
+		}
+		return body, notes, true
+	}
+
+	// Snippets, mentions and processors go first, so that what they add is
+	// screened like the rest of the body.
+	body, notes, ok := h.screenBody(inv, opts, h.processBody(h.resolveMentions(h.expandSnippets(body))))
+	if !ok {
+		return "", nil, false
+	}
+	body = h.resolveEmojiShortcodes(body)
+	body, err := h.bot.convertTimes(body, time.Now())
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidTime, replyData{Error: err}))
+		return "", nil, false
+	}
+	return body, notes, true
+}
+
+func (h *commandHandler) edit(inv *invocation, command *parsedCommand) {
+	opts, err := parseAnnounceOptions(command.Options)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidOptions, replyData{Error: err}))
+		return
+	}
+
+	// Announcements can be edited by number, rather than only the author's
+	// latest one.
+	if command.Args != "" {
+		h.editNumbered(inv, command, opts)
+		return
+	}
+
+	// Look up the last message sent by the author.
+	lastSent, ok, err := h.lastSentAuthors.Load(inv.Author.ID)
+	if err != nil {
+		slog.Error(
+			"Bots has failed to look up the last message sent by the author.",
+			"author_id", inv.Author.ID,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	if !ok {
+		sendRejection(h.session, inv, inv.text(msgLastSentNotFound))
+		return
+	}
+
+	h.editAnnouncementBody(inv, lastSent, command.Options, opts, command.Body, nil)
+}
+
+// editAnnouncementBody replaces the body of the announcement whose primary
+// message is lastSent, along with all of its copies. options is the front
+// matter that opts was parsed from, if any. done is called once the edit has
+// been made, and may be nil. If the edit is refused or fails, the author is
+// told why instead.
+func (h *commandHandler) editAnnouncementBody(inv *invocation, lastSent discord.MessageID, options string, opts announceOptions, body string, done func()) {
+	// Edits are signed like new announcements, or a hijacked account could
+	// just edit an old one.
+	body, ok := h.verifySignature(inv, options, body)
+	if !ok {
+		return
+	}
+
+	// Find out where the announcement went. Announcements from before the
+	// bot kept records were always sent to the target channel.
+	messages := []messageRef{{ChannelID: h.bot.TargetChannelID, MessageID: lastSent}}
+	record, recorded, err := h.announcements.Load(lastSent)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to look up the announcement record.",
+			"message_id", lastSent,
+			"err", err)
+	} else if recorded {
+		if !h.checkEditWindow(inv, record) {
+			return
+		}
+		messages[0].ChannelID = record.ChannelID
+		messages = append(messages, record.Copies...)
+		if record.Staged != nil {
+			messages = append(messages, *record.Staged)
+		}
+
+		// What the announcement was scheduled to do carries over, unless the
+		// edit says otherwise.
+		if !setsOption(options, "delete-at") {
+			opts.DeleteAt = record.Options.DeleteAt
+		}
+		if !setsOption(options, "sticky") {
+			opts.Sticky = record.Options.Sticky
+		}
+		if !setsOption(options, "bump-after") {
+			opts.BumpAfter = record.Options.BumpAfter
+		}
+	}
+	announced := len(messages)
+	if record.Staged != nil {
+		announced--
+	}
+	messages = append(messages, h.stickyCopies(lastSent)...)
+
+	body, notes, ok := h.rewriteBody(inv, opts, body)
+	if !ok {
+		return
+	}
+
+	if !h.checkEditApproval(inv, opts, body, record) {
+		return
+	}
+
+	var footer string
+	if h.bot.EditFooter {
+		footer = localize(h.bot.locale(), msgEditedFooter, replyData{
+			Author: inv.Author.Mention(),
+			When:   timestampMarkup(time.Now(), timestampFull),
+		})
+	}
+
+	rendered, err := h.bot.renderAnnouncement(opts, withFooter(body, h.bot.numberLine(record.Number), footer))
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidEmbed, replyData{Error: err}))
+		return
+	}
+
+	if !h.checkLint(inv, opts, rendered) {
+		return
+	}
+
+	finish := func() {
+		if recorded {
+			// Records from before the body was kept only have what was posted.
+			before := record.Body
+			if before == "" && len(record.Revisions) > 0 {
+				before = record.Revisions[len(record.Revisions)-1].Content
+			}
+			h.auditEdit(inv, messages[0], before, body)
+
+			record.Body = body
+			record.Options = opts
+			record.Footer = footer
+			record.Revisions = append(record.Revisions, announcementRevision{
+				Time:    time.Now(),
+				Content: rendered.Content,
+			})
+			if err := h.announcements.Store(lastSent, record); err != nil {
+				slog.Warn(
+					"Bot has failed to record the new revision of the announcement.",
+					"message_id", lastSent,
+					"err", err)
+			}
+			h.indexAnnouncement(lastSent, record)
+			h.scheduleDeletion(lastSent, opts.DeleteAt)
+			h.rescheduleBump(lastSent, record)
+		}
+
+		h.setSticky(lastSent, messages[:announced], opts.Sticky)
+
+		// Translated copies can't share the rendered message, so they are
+		// translated again.
+		h.editTranslations(lastSent, record.Translations, body, opts)
+
+		if len(notes) > 0 {
+			sendReply(h.session, inv, inv.textWith(msgSanitized, replyData{Report: formatBulletList(notes)}))
+		}
+
+		// Messages in DMs can't be deleted by the bot.
+		if inv.Message != nil && inv.Message.GuildID.IsValid() {
+			h.deleteCommand(messageRef{ChannelID: inv.ChannelID, MessageID: inv.Message.ID}, opts)
+		}
+
+		if done != nil {
+			done()
+		}
+	}
+
+	var editNext func(i int)
+	editNext = func(i int) {
+		if i == len(messages) {
+			finish()
+			return
+		}
+
+		msg := messages[i]
+		h.retry("edit announcement", inv, func() error {
+			return h.editAnnouncement(msg, rendered)
+		}, func(err error) {
+			if err != nil {
+				slog.Error(
+					"Bot has failed to edit the last announcement message.",
+					"channel_id", msg.ChannelID,
+					"message_id", msg.MessageID,
+					"ref", inv.errorRef(),
+					"err", err)
+
+				replyInternalError(h.session, inv)
+				return
+			}
+			editNext(i + 1)
+		})
+	}
+	editNext(0)
+}
+
+// deleteCommand deletes the command message if the bot is configured to do so.
+func (h *commandHandler) deleteCommand(command messageRef, opts announceOptions) {
+	shouldDelete := h.bot.DeleteCommands
+	if opts.DeleteCommand != nil {
+		shouldDelete = *opts.DeleteCommand
+	}
+	if !shouldDelete {
+		return
+	}
+
+	h.botDeletions[command.MessageID] = struct{}{}
+	if err := h.session.DeleteMessage(command.ChannelID, command.MessageID, "command has been carried out"); err != nil {
+		delete(h.botDeletions, command.MessageID)
+		slog.Warn(
+			"Bot has failed to delete the command message.",
+			"channel_id", command.ChannelID,
+			"message_id", command.MessageID,
+			"err", err)
+	}
+}
+
+// acknowledgeEmoji is the reaction used to acknowledge commands in quiet mode.
+const acknowledgeEmoji = discord.APIEmoji("✅")
+
+// acknowledge replies to a successful command. In quiet mode, the command is
+// reacted to instead, or for interactions, replied to only for the invoker.
+func (h *commandHandler) acknowledge(inv *invocation, content string) {
+	if !h.bot.QuietMode {
+		sendReply(h.session, inv, content)
+		return
+	}
+
+	if inv.Interaction != nil {
+		// Interactions must always be responded to.
+		deliverReply(h.session, inv, content, nil, true)
+		return
+	}
+
+	if inv.Console != nil {
+		sendReply(h.session, inv, content)
+		return
+	}
+
+	if err := h.session.React(inv.ChannelID, inv.Message.ID, acknowledgeEmoji); err != nil {
+		slog.Warn(
+			"Bot has failed to react to the command. It will reply instead.",
+			"channel_id", inv.ChannelID,
+			"message_id", inv.Message.ID,
+			"err", err)
+
+		sendReply(h.session, inv, content)
+	}
+}
+
+func replyInternalError(session *ningen.State, inv *invocation) {
+	inv.outcome = outcomeError
+	sendRejection(session, inv, inv.textWith(msgInternalError, replyData{Ref: inv.errorRef()}))
+}
+
+func sendReply(session *ningen.State, inv *invocation, content string) {
+	deliverReply(session, inv, content, nil, false)
+}
+
+// sendRejection is like sendReply, except it's used for replies that reject
+// the command, such as cooldown notices and errors. If the command came from
+// an interaction, the reply is only visible to the invoker.
+func sendRejection(session *ningen.State, inv *invocation, content string) {
+	if inv.outcome == "" {
+		inv.outcome = outcomeRejected
+	}
+	deliverReply(session, inv, content, nil, true)
+}
+
+// sendComponents is like sendReply, except the reply also carries the given
+// components.
+func sendComponents(session *ningen.State, inv *invocation, content string, components discord.ContainerComponents) {
+	deliverReply(session, inv, content, components, false)
+}
+
+func deliverReply(session *ningen.State, inv *invocation, content string, components discord.ContainerComponents, ephemeral bool) {
+	if inv.Console != nil {
+		fmt.Fprintln(inv.Console, content)
+		return
+	}
+
+	content = inv.Author.Mention() + ", " + content
+
+	data := api.InteractionResponseData{
+		Content: option.NewNullableString(content),
+	}
+	if len(components) > 0 {
+		data.Components = &components
+	}
+	if ephemeral {
+		data.Flags = discord.EphemeralMessage
+	}
+
+	var err error
+	switch {
+	case inv.Interaction != nil && !inv.responded:
+		err = session.RespondInteraction(inv.Interaction.ID, inv.Interaction.Token, api.InteractionResponse{
+			Type: api.MessageInteractionWithSource,
+			Data: &data,
+		})
+		if err == nil {
+			inv.responded = true
+		}
+	case inv.Interaction != nil:
+		_, err = session.FollowUpInteraction(inv.Interaction.AppID, inv.Interaction.Token, data)
+	default:
+		var reply *discord.Message
+		reply, err = session.SendMessageComplex(inv.ChannelID, api.SendMessageData{
+			Content:    content,
+			Components: components,
+			Reference:  &discord.MessageReference{MessageID: inv.Message.ID},
+		})
+		if err == nil {
+			inv.replies = append(inv.replies, reply.ID)
+		}
+	}
+	if err != nil {
+		slog.Error(
+			"Bot has failed to deliver a reply.",
+			"channel_id", inv.ChannelID,
+			"author_id", inv.Author.ID,
+			"err", err)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// configFileEnv is the environment variable that points to the config file.
+// If unset, the built-in settings are used as-is.
+const configFileEnv = "CONFIG_FILE"
+
+// loadConfig loads the config file named by $CONFIG_FILE, if any, into the
+// global settings and validates them.
+func loadConfig() error {
+	s, err := readConfig()
+	if err != nil {
+		return err
+	}
+	settings = s
+	return nil
+}
+
+// readConfig reads the config file named by $CONFIG_FILE, if any, over the
+// built-in settings and validates the result. The global settings are left
+// alone.
+func readConfig() (botSettings, error) {
+	s := builtinSettings
+	if path := os.Getenv(configFileEnv); path != "" {
+		var err error
+		s, err = loadSettings(path)
+		if err != nil {
+			return botSettings{}, err
+		}
+	}
+
+	if err := errors.Join(s.validate()...); err != nil {
+		return botSettings{}, err
+	}
+
+	s.bannedPatterns = compileBannedPhrases(s.BannedPhrases)
+	return s, nil
+}
+
+// loadSettings reads the JSON config file at path over the built-in settings.
+// Fields that the file doesn't mention keep their built-in values. Unknown
+// fields are rejected. Each field is parsed on its own, so that every mistake
+// is reported along with the field it is in.
+func loadSettings(path string) (botSettings, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return botSettings{}, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return botSettings{}, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	s := builtinSettings
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	for _, name := range names {
+		field, _ := json.Marshal(map[string]json.RawMessage{name: fields[name]})
+
+		// The file replaces each field that it mentions. Clearing the field
+		// first keeps the decoder from writing into the slices and maps of
+		// the built-in settings, which a reload starts over from. Names are
+		// matched regardless of case, as the decoder does.
+		v := reflect.ValueOf(&s).Elem().FieldByNameFunc(func(field string) bool {
+			return strings.EqualFold(field, name)
+		})
+		if v.CanSet() {
+			v.SetZero()
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(field))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return botSettings{}, fmt.Errorf("cannot parse %s:\n%w", path, errors.Join(errs...))
+	}
+
+	return s, nil
+}
+
+// UnmarshalJSON unmarshals the settings, taking durations as strings such as
+// "4h" instead of nanoseconds, and permissions as lists of names.
+func (s *botSettings) UnmarshalJSON(b []byte) error {
+	type rawSettings botSettings
+	aux := struct {
+		*rawSettings
+		MinAnnounceTimeGap *configDuration
+		StateRetention     *configDuration
+		SlowmodeWindow     *configDuration
+		EditWindow         *configDuration
+		OutageAlertAfter   *configDuration
+		WatchdogTimeout    *configDuration
+		AllowedPermissions *configPermissions
+	}{
+		rawSettings:        (*rawSettings)(s),
+		MinAnnounceTimeGap: (*configDuration)(&s.MinAnnounceTimeGap),
+		StateRetention:     (*configDuration)(&s.StateRetention),
+		SlowmodeWindow:     (*configDuration)(&s.SlowmodeWindow),
+		EditWindow:         (*configDuration)(&s.EditWindow),
+		OutageAlertAfter:   (*configDuration)(&s.OutageAlertAfter),
+		WatchdogTimeout:    (*configDuration)(&s.WatchdogTimeout),
+		AllowedPermissions: (*configPermissions)(&s.AllowedPermissions),
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(&aux)
+}
+
+// configDuration is a time.Duration that is written as a string in the config
+// file.
+type configDuration time.Duration
+
+func (d *configDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New(`durations must be written as strings, such as "4h"`)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = configDuration(v)
+	return nil
+}
+
+// validate checks that the settings are consistent. Every problem found is
+// returned.
+func (s botSettings) validate() []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	checkID := func(field string, id discord.Snowflake) {
+		if !id.IsValid() {
+			fail("%s: is not a valid ID", field)
+			return
+		}
+		if id.Time().After(time.Now()) {
+			fail("%s: %d is not a valid ID, since it is from the future", field, id)
+		}
+	}
+
+	if s.TargetChannelID.IsValid() {
+		checkID("TargetChannelID", discord.Snowflake(s.TargetChannelID))
+	} else {
+		fail("TargetChannelID: must be set")
+	}
+
+	for i, id := range s.ExtraChannelIDs {
+		field := fmt.Sprintf("ExtraChannelIDs[%d]", i)
+		checkID(field, discord.Snowflake(id))
+		if id == s.TargetChannelID {
+			fail("%s: %d is already the target channel", field, id)
+		}
+		if slices.Index(s.ExtraChannelIDs, id) != i {
+			fail("%s: %d is listed more than once", field, id)
+		}
+	}
+
+	for i, id := range s.CommandChannelIDs {
+		field := fmt.Sprintf("CommandChannelIDs[%d]", i)
+		checkID(field, discord.Snowflake(id))
+		if slices.Index(s.CommandChannelIDs, id) != i {
+			fail("%s: %d is listed more than once", field, id)
+		}
+	}
+
+	if s.StagingChannelID.IsValid() {
+		checkID("StagingChannelID", discord.Snowflake(s.StagingChannelID))
+		if s.StagingChannelID == s.TargetChannelID || slices.Contains(s.ExtraChannelIDs, s.StagingChannelID) {
+			fail("StagingChannelID: %d is also an announcement channel", s.StagingChannelID)
+		}
+	}
+
+	if s.DigestChannelID.IsValid() {
+		checkID("DigestChannelID", discord.Snowflake(s.DigestChannelID))
+		if s.DigestChannelID == s.TargetChannelID || slices.Contains(s.ExtraChannelIDs, s.DigestChannelID) {
+			fail("DigestChannelID: %d is also an announcement channel", s.DigestChannelID)
+		}
+	}
+
+	if s.StageChannelID.IsValid() {
+		checkID("StageChannelID", discord.Snowflake(s.StageChannelID))
+	}
+
+	switch s.Translation.Provider {
+	case "", "deepl":
+	case "libretranslate":
+		if s.Translation.URL == "" {
+			fail("Translation.URL: must be set for LibreTranslate")
+		}
+	default:
+		fail("Translation.Provider: %q is not a known provider", s.Translation.Provider)
+	}
+	for lang, id := range s.Translation.Channels {
+		checkID(fmt.Sprintf("Translation.Channels[%q]", lang), discord.Snowflake(id))
+	}
+	if s.Translation.Provider != "" && len(s.Translation.Channels) == 0 {
+		fail("Translation.Channels: must be set when a translation provider is used")
+	}
+
+	for i, p := range s.Processors {
+		switch p.Name {
+		case "tidy":
+		case "tldr":
+			if p.URL == "" || p.Model == "" {
+				fail("Processors[%d]: tldr requires a URL and a Model", i)
+			}
+		default:
+			fail("Processors[%d]: %q is not a known processor", i, p.Name)
+		}
+	}
+
+	for i, key := range s.SigningKeys {
+		if _, err := parseMinisignKey(key); err != nil {
+			fail("SigningKeys[%d]: %v", i, err)
+		}
+	}
+
+	for i, id := range s.ApproverRoleIDs {
+		checkID(fmt.Sprintf("ApproverRoleIDs[%d]", i), discord.Snowflake(id))
+	}
+	if len(s.ApproverRoleIDs) > 0 && !s.StagingChannelID.IsValid() {
+		fail("ApproverRoleIDs: needs StagingChannelID, since announcements are approved in it")
+	}
+
+	if s.AuditChannelID.IsValid() {
+		checkID("AuditChannelID", discord.Snowflake(s.AuditChannelID))
+	}
+
+	if len(s.AllowedRoleIDs) == 0 && s.AllowedPermissions == 0 {
+		fail("AllowedRoleIDs: must have at least one role unless AllowedPermissions is set, or nobody can use the bot")
+	}
+	for i, id := range s.AllowedRoleIDs {
+		checkID(fmt.Sprintf("AllowedRoleIDs[%d]", i), discord.Snowflake(id))
+	}
+	for i, id := range s.BlockedUserIDs {
+		checkID(fmt.Sprintf("BlockedUserIDs[%d]", i), discord.Snowflake(id))
+	}
+	for i, id := range s.AdminRoleIDs {
+		checkID(fmt.Sprintf("AdminRoleIDs[%d]", i), discord.Snowflake(id))
+	}
+
+	for name, roleIDs := range s.CommandRoleIDs {
+		if _, ok := commandSpecs[name]; !ok {
+			fail("CommandRoleIDs: %q is not a known command", name)
+			continue
+		}
+		if len(roleIDs) == 0 {
+			fail("CommandRoleIDs[%q]: must have at least one role, or nobody can use the command", name)
+		}
+		for i, id := range roleIDs {
+			checkID(fmt.Sprintf("CommandRoleIDs[%q][%d]", name, i), discord.Snowflake(id))
+		}
+	}
+
+	if s.AnnounceRoleID.IsValid() {
+		checkID("AnnounceRoleID", discord.Snowflake(s.AnnounceRoleID))
+	} else if s.SubscribePrompt {
+		fail("SubscribePrompt: requires AnnounceRoleID to be set")
+	}
+
+	for i, id := range s.CooldownExemptRoleIDs {
+		checkID(fmt.Sprintf("CooldownExemptRoleIDs[%d]", i), discord.Snowflake(id))
+	}
+
+	for i, id := range s.TTSRoleIDs {
+		checkID(fmt.Sprintf("TTSRoleIDs[%d]", i), discord.Snowflake(id))
+	}
+
+	if s.MinAnnounceTimeGap < 0 {
+		fail("MinAnnounceTimeGap: must not be negative")
+	}
+
+	if s.EditWindow < 0 {
+		fail("EditWindow: must not be negative")
+	}
+
+	if s.SlowmodeWindow < 0 {
+		fail("SlowmodeWindow: must not be negative")
+	}
+
+	if s.StateRetention < 0 {
+		fail("StateRetention: must not be negative")
+	} else if s.StateRetention > 0 && s.StateRetention < minStateRetention {
+		fail("StateRetention: must be at least %v", minStateRetention)
+	}
+
+	if s.OutageAlertAfter < 0 {
+		fail("OutageAlertAfter: must not be negative")
+	}
+
+	if s.WatchdogTimeout < 0 {
+		fail("WatchdogTimeout: must not be negative")
+	} else if s.WatchdogTimeout > 0 && s.WatchdogTimeout < minWatchdogTimeout {
+		fail("WatchdogTimeout: must be at least %v", minWatchdogTimeout)
+	}
+
+	if s.Presence.Status != "" && !slices.Contains(presenceStatuses, s.Presence.Status) {
+		fail("Presence.Status: %q is not a known status", s.Presence.Status)
+	}
+	if _, ok := activityTypes[s.Presence.ActivityType]; s.Presence.ActivityType != "" && !ok {
+		fail("Presence.ActivityType: %q is not a known activity type", s.Presence.ActivityType)
+	}
+	for i, text := range s.Presence.Activities {
+		if _, err := renderActivity(text, presenceData{}); err != nil {
+			fail("Presence.Activities[%d]: %v", i, err)
+		}
+	}
+	if s.Presence.RotateEvery < 0 {
+		fail("Presence.RotateEvery: must not be negative")
+	} else if s.Presence.RotateEvery > 0 && s.Presence.RotateEvery < minPresenceInterval {
+		fail("Presence.RotateEvery: must be at least %v", minPresenceInterval)
+	}
+
+	if s.Identify.Capabilities < 0 {
+		fail("Identify.Capabilities: must not be negative")
+	}
+	if s.Identify.Browser == "" || s.Identify.Device == "" {
+		fail("Identify: Browser and Device must not be empty")
+	}
+	for i, name := range s.Identify.Intents {
+		if _, ok := gatewayIntents[name]; !ok {
+			fail("Identify.Intents[%d]: %q is not a known intent", i, name)
+		}
+	}
+
+	if s.FloodLimit < 0 {
+		fail("FloodLimit: must not be negative")
+	}
+
+	if s.MaxMentions < 0 {
+		fail("MaxMentions: must not be negative")
+	}
+
+	if s.Locale != "" {
+		base, _, _ := strings.Cut(s.Locale, "-")
+		_, ok1 := messageCatalog[s.Locale]
+		_, ok2 := messageCatalog[base]
+		if !ok1 && !ok2 {
+			fail("Locale: %q has no messages", s.Locale)
+		}
+	}
+
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			fail("Timezone: %v", err)
+		}
+	}
+
+	for i, phrase := range s.BannedPhrases {
+		if _, err := compileBannedPhrase(phrase); err != nil {
+			fail("BannedPhrases[%d]: %v", i, err)
+		}
+	}
+
+	for i, domain := range s.AllowedLinkDomains {
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			fail("AllowedLinkDomains[%d]: %q is not a domain", i, domain)
+		}
+	}
+	if s.DefangLinks && len(s.AllowedLinkDomains) == 0 {
+		fail("DefangLinks: requires AllowedLinkDomains to be set")
+	}
+
+	for key, text := range s.ReplyTemplates {
+		if _, ok := messageCatalog[defaultLocale][key]; !ok {
+			fail("ReplyTemplates: %q is not a known message", key)
+			continue
+		}
+		if _, err := renderReplyTemplate(text, replyData{}); err != nil {
+			fail("ReplyTemplates[%q]: %v", key, err)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// controlSocketDirectory is the name of the directory in the state
+	// directory that holds the control socket. Only the bot's own user may
+	// enter it, so the socket can't be reached by anyone else, whatever the
+	// umask.
+	controlSocketDirectory = "control"
+	// controlSocketFile is the name of the Unix socket in the control socket
+	// directory that the bot accepts control commands on.
+	controlSocketFile = "control.sock"
+)
+
+// drainCheckInterval is how often a draining bot checks whether it is done.
+const drainCheckInterval = time.Second
+
+// errDrained is returned by the event loop once the bot has been drained.
+var errDrained = errors.New("bot has been drained")
+
+// controlRequest is a command line received on the control socket. The event
+// loop handles it and sends the reply back.
+type controlRequest struct {
+	Line  string
+	Reply chan<- string
+}
+
+// controlCommands describes each command that the control socket accepts.
+var controlCommands = map[string]string{
+	"pause":  "pause announcements, optionally with a reason",
+	"resume": "resume announcements",
+	"reload": "reload the config file",
+	"status": "print whether the bot is healthy",
+	"drain":  "stop taking announcements and exit once nothing is left to send",
+	"help":   "print this help",
+}
+
+// serveControl accepts control commands on the Unix socket in the directory
+// dir until ctx is done. Each line that a client sends is a command, and each
+// reply ends with a blank line. For example, with systemd:
+//
+//	ExecReload=/bin/sh -c 'echo reload | nc -U $STATE_DIRECTORY/control/control.sock'
+func serveControl(ctx context.Context, dir string, requests chan<- controlRequest) error {
+	// The directory is restricted before the socket is made, since the socket
+	// is reachable as soon as it exists. MkdirAll leaves the permissions of
+	// an existing directory alone.
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("cannot create the control socket directory: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return fmt.Errorf("cannot restrict the control socket directory: %w", err)
+	}
+
+	// Only the instance that holds the state lock gets this far, so a socket
+	// left at the path is from an instance that didn't shut down cleanly.
+	path := filepath.Join(dir, controlSocketFile)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot remove the stale control socket: %w", err)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("cannot listen on the control socket: %w", err)
+	}
+	defer os.Remove(path)
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	slog.Info(
+		"Bot is now accepting control commands.",
+		"socket", path)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("cannot accept on the control socket: %w", err)
+		}
+		go handleControlConn(ctx, conn, requests)
+	}
+}
+
+func handleControlConn(ctx context.Context, conn net.Conn, requests chan<- controlRequest) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		reply := make(chan string, 1)
+		select {
+		case requests <- controlRequest{Line: line, Reply: reply}:
+		case <-ctx.Done():
+			return
+		}
+
+		var text string
+		select {
+		case text = <-reply:
+		case <-ctx.Done():
+			return
+		}
+
+		if _, err := fmt.Fprintf(conn, "%s\n\n", strings.TrimRight(text, "\n")); err != nil {
+			return
+		}
+	}
+}
+
+// handleControl handles a control command and returns its reply. It must be
+// called from the event loop.
+func (h *commandHandler) handleControl(line string) string {
+	command, args, _ := strings.Cut(line, " ")
+	command = strings.ToLower(command)
+	args = strings.TrimSpace(args)
+
+	slog.Info(
+		"Bot has received a control command.",
+		"command", command)
+
+	switch command {
+	case "help":
+		var b strings.Builder
+		for _, name := range sortedKeys(controlCommands) {
+			fmt.Fprintf(&b, "%s: %s\n", name, controlCommands[name])
+		}
+		return b.String()
+
+	case "pause":
+		h.setPaused(true, "the control socket", args)
+		return "announcements are now paused"
+
+	case "resume":
+		h.setPaused(false, "", "")
+		return "announcements are no longer paused"
+
+	case "reload":
+		if err := h.reloadConfig(); err != nil {
+			slog.Error(
+				"Bot has failed to reload its config. It keeps the old one.",
+				"err", err)
+			return "error: " + err.Error()
+		}
+		return "the config has been reloaded"
+
+	case "status":
+		return h.controlStatus()
+
+	case "drain":
+		h.draining = true
+		slog.Info("Bot is draining. It will exit once nothing is left to send.")
+		return "the bot is draining"
+
+	default:
+		return fmt.Sprintf("error: unknown command %q, try help", command)
+	}
+}
+
+// reloadConfig reads the config file again and applies it. Settings that the
+// bot can't switch while it is running are refused.
+func (h *commandHandler) reloadConfig() error {
+	s, err := readConfig()
+	if err != nil {
+		return err
+	}
+
+	if s.TargetChannelID != h.bot.TargetChannelID {
+		return errors.New("TargetChannelID can only be changed with a restart")
+	}
+
+	translator, err := newTranslator(s.Translation)
+	if err != nil {
+		return err
+	}
+
+	processors, err := newProcessors(s.Processors)
+	if err != nil {
+		return err
+	}
+
+	settings = s
+	h.bot.botSettings = s
+	h.translator = translator
+	h.processors = processors
+
+	slog.Info("Bot has reloaded its config.")
+	return nil
+}
+
+// controlStatus returns the status of the bot as plain text, one key and
+// value per line.
+func (h *commandHandler) controlStatus() string {
+	status := h.gateway.status()
+
+	var unsent int
+	h.outbox.Keys()(func(string) bool {
+		unsent++
+		return true
+	})
+
+	lines := []string{
+		fmt.Sprintf("gateway_alive: %t", status.Alive),
+		fmt.Sprintf("heartbeat_latency: %s", status.Latency.Round(time.Millisecond)),
+		fmt.Sprintf("last_event: %s", formatControlTime(status.LastEvent)),
+		fmt.Sprintf("uptime: %s", time.Since(h.stats.Started).Round(time.Second)),
+		fmt.Sprintf("commands: %d", h.stats.Commands),
+		fmt.Sprintf("announcements: %d", h.stats.Announcements),
+		fmt.Sprintf("paused: %t", h.bot.Runtime.Paused),
+		fmt.Sprintf("draining: %t", h.draining),
+		fmt.Sprintf("pending: %d", len(h.pending)),
+		fmt.Sprintf("unsent: %d", unsent),
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatControlTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// drained returns true if a draining bot has nothing left to send, so that it
+// can exit.
+func (h *commandHandler) drained() bool {
+	if h.backgroundTasks > 0 {
+		return false
+	}
+
+	for _, p := range h.pending {
+		if time.Since(p.Created) <= pendingAnnouncementTTL {
+			return false
+		}
+	}
+
+	empty := true
+	h.outbox.Keys()(func(string) bool {
+		empty = false
+		return false
+	})
+	return empty
+}
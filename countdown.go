@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const (
+	// countdownInterval is how often the countdowns are updated. Each message
+	// is only edited when its text changes.
+	countdownInterval = time.Minute
+	// maxCountdownLength is how far into the future a countdown may count.
+	maxCountdownLength = 365 * 24 * time.Hour
+)
+
+// countdown is a message that counts down to a point in time. It is keyed by
+// the ID of the message.
+type countdown struct {
+	ChannelID discord.ChannelID
+	AuthorID  discord.UserID
+	// Topic is what is being counted down to, such as "Release v2".
+	Topic string
+	// Final is what the message is replaced with once the time has come. If
+	// empty, a default message is used.
+	Final  string
+	Target time.Time
+	// Shown is the text that the message was last edited to, so that it is
+	// only edited when the text changes.
+	Shown string
+}
+
+// parseCountdownTarget parses the time to count down to. It is either a time
+// such as "2024-06-01 18:00" in UTC or "2024-06-01T18:00:00+02:00", or a
+// duration from now such as "3d", "12h" or "90m".
+func parseCountdownTarget(v string, now time.Time) (time.Time, error) {
+	v = strings.TrimSpace(v)
+
+	var target time.Time
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		target = t
+	} else if t, err := time.Parse("2006-01-02 15:04", v); err == nil {
+		target = t
+	} else if d, err := parseLongDuration(v); err == nil {
+		target = now.Add(d)
+	} else {
+		return time.Time{}, fmt.Errorf("%q is not a time or a duration", v)
+	}
+
+	switch {
+	case !target.After(now.Add(countdownInterval)):
+		return time.Time{}, errors.New("the time must be in the future")
+	case target.After(now.Add(maxCountdownLength)):
+		return time.Time{}, fmt.Errorf("the time must be within %d days", maxCountdownLength/(24*time.Hour))
+	}
+	return target, nil
+}
+
+// text renders the countdown as it should read at the given time.
+func (c countdown) text(locale string, now time.Time) string {
+	remaining := c.Target.Sub(now)
+	if remaining <= 0 {
+		if c.Final != "" {
+			return c.Final
+		}
+		return localize(locale, msgCountdownDone, replyData{Topic: c.Topic})
+	}
+
+	// Round up, so that the countdown never reads zero before it's done.
+	minutes := int((remaining + time.Minute - 1) / time.Minute)
+	days, hours, minutes := minutes/(24*60), minutes/60%24, minutes%60
+
+	var parts []string
+	unit := func(key messageKey, n int) {
+		parts = append(parts, localize(locale, key, replyData{Count: n}))
+	}
+	switch {
+	case days > 0:
+		unit(msgCountdownDays, days)
+		if hours > 0 {
+			unit(msgCountdownHours, hours)
+		}
+	case hours > 0:
+		unit(msgCountdownHours, hours)
+		if minutes > 0 {
+			unit(msgCountdownMinutes, minutes)
+		}
+	default:
+		unit(msgCountdownMinutes, minutes)
+	}
+
+	return localize(locale, msgCountdown, replyData{
+		Topic: c.Topic,
+		Left:  strings.Join(parts, " "),
+		When:  timestampMarkup(c.Target, timestampFull),
+	})
+}
+
+// startCountdown posts a countdown into the target channel.
+func (h *commandHandler) startCountdown(inv *invocation, command *parsedCommand) {
+	// Countdowns share the announcement cooldown.
+	if remaining := h.bot.cooldownRemaining(); remaining > 0 && !h.bot.cooldownExempt(inv.member()) {
+		sendRejection(h.session, inv, inv.textWith(msgCooldown, replyData{Remaining: remaining.Round(time.Second)}))
+		return
+	}
+
+	now := time.Now()
+	target, err := parseCountdownTarget(command.Args, now)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidCountdown, replyData{Error: err}))
+		return
+	}
+
+	body, ok := h.screenSignedBody(inv, command.Options, command.Body)
+	if !ok {
+		return
+	}
+
+	// The first line is the topic, and anything after it is the final
+	// message.
+	topic, final, _ := strings.Cut(body, "\n")
+	c := countdown{
+		ChannelID: h.bot.TargetChannelID,
+		AuthorID:  inv.Author.ID,
+		Topic:     strings.TrimSpace(topic),
+		Final:     strings.TrimSpace(final),
+		Target:    target,
+	}
+	c.Shown = c.text(h.bot.locale(), now)
+
+	// Exemptions from the cooldown are only noted once the countdown is sure to
+	// be sent. The cooldown holds while it is being sent.
+	if !h.checkCooldown(inv) {
+		return
+	}
+
+	var msg *discord.Message
+	h.bot.Announcing++
+	h.retry("send countdown", inv, func() (err error) {
+		msg, err = h.session.SendMessageComplex(c.ChannelID, api.SendMessageData{
+			Content: c.Shown,
+			// Countdowns are edited a lot, so they should never ping anyone.
+			AllowedMentions: &api.AllowedMentions{},
+		})
+		return err
+	}, func(err error) {
+		h.bot.Announcing--
+		if err != nil {
+			slog.Error(
+				"Bot has failed to send the countdown.",
+				"channel_id", c.ChannelID,
+				"ref", inv.errorRef(),
+				"err", err)
+
+			replyInternalError(h.session, inv)
+			return
+		}
+
+		if err := h.countdowns.Store(msg.ID, c); err != nil {
+			slog.Error(
+				"Bot has failed to store the countdown. It will stop counting on restart.",
+				"message_id", msg.ID,
+				"err", err)
+		}
+
+		h.bot.LastAnnouncedTime = now
+
+		ref := messageRef{ChannelID: msg.ChannelID, MessageID: msg.ID}
+		h.acknowledge(inv, inv.textWith(msgCountdownPosted, replyData{Link: messageLink(h.bot.TargetGuildID, ref)}))
+
+		if inv.Message != nil {
+			h.deleteCommand(messageRef{ChannelID: inv.ChannelID, MessageID: inv.Message.ID}, announceOptions{})
+		}
+	})
+}
+
+// updateCountdowns edits every countdown whose text has changed, and
+// finishes those whose time has come.
+func (h *commandHandler) updateCountdowns() {
+	now := time.Now()
+
+	type item struct {
+		id discord.MessageID
+		c  countdown
+	}
+
+	var items []item
+	h.countdowns.All()(func(id discord.MessageID, c countdown) bool {
+		items = append(items, item{id, c})
+		return true
+	})
+
+	for _, item := range items {
+		id, c := item.id, item.c
+		done := !now.Before(c.Target)
+
+		text := c.text(h.bot.locale(), now)
+		if text == c.Shown && !done {
+			continue
+		}
+		if text != c.Shown {
+			_, err := h.session.EditMessageComplex(c.ChannelID, id, api.EditMessageData{
+				Content:         option.NewNullableString(text),
+				AllowedMentions: &api.AllowedMentions{},
+			})
+			if err != nil {
+				var httpErr *httputil.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.Status != http.StatusNotFound {
+					slog.Warn(
+						"Bot has failed to update a countdown. It will try again later.",
+						"message_id", id,
+						"err", err)
+					continue
+				}
+				// The message was deleted, so there is nothing left to count.
+				done = true
+			}
+			c.Shown = text
+		}
+
+		if done {
+			if err := h.countdowns.Delete(id); err != nil {
+				slog.Warn(
+					"Bot has failed to forget a finished countdown.",
+					"message_id", id,
+					"err", err)
+			}
+			continue
+		}
+
+		if err := h.countdowns.Store(id, c); err != nil {
+			slog.Warn(
+				"Bot has failed to store the progress of a countdown.",
+				"message_id", id,
+				"err", err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// debugTokenEnv is the environment variable that holds the bearer token that
+// the debug endpoint requires. If it is empty, the debug endpoint is disabled.
+const debugTokenEnv = "DEBUG_TOKEN"
+
+// maxRecentEvents is how many of the most recently processed events are kept
+// for the debug endpoint.
+const maxRecentEvents = 50
+
+// debugTimeout is how long the debug endpoint waits for the event loop to take
+// a snapshot.
+const debugTimeout = 5 * time.Second
+
+// processedEvent is an event that the event loop has processed.
+type processedEvent struct {
+	Time time.Time
+	Type string
+}
+
+// noteEvent remembers that the event loop has processed the event, keeping
+// only the most recent maxRecentEvents.
+func (h *commandHandler) noteEvent(ev gateway.Event) {
+	if len(h.recentEvents) == maxRecentEvents {
+		h.recentEvents = append(h.recentEvents[:0], h.recentEvents[1:]...)
+	}
+	h.recentEvents = append(h.recentEvents, processedEvent{
+		Time: time.Now(),
+		Type: string(ev.EventType()),
+	})
+}
+
+// debugSnapshot is a snapshot of the internal state of the bot.
+type debugSnapshot struct {
+	Bot               botState
+	Stats             sessionStats
+	CooldownRemaining string
+	IgnoredUntil      map[discord.UserID]time.Time
+	Pending           map[string]*pendingAnnouncement
+	Outbox            map[string]outboxEntry
+	RecentEvents      []processedEvent
+}
+
+// debugSnapshot takes a snapshot of the internal state of the bot. It must be
+// called from the event loop.
+func (h *commandHandler) debugSnapshot() debugSnapshot {
+	outbox := make(map[string]outboxEntry)
+	h.outbox.All()(func(id string, entry outboxEntry) bool {
+		outbox[id] = entry
+		return true
+	})
+
+	var cooldown string
+	if remaining := h.bot.cooldownRemaining(); remaining > 0 {
+		cooldown = remaining.Round(time.Second).String()
+	}
+
+	return debugSnapshot{
+		Bot:               *h.bot,
+		Stats:             h.stats,
+		CooldownRemaining: cooldown,
+		IgnoredUntil:      h.flood.ignoredUntil,
+		Pending:           h.pending,
+		Outbox:            outbox,
+		RecentEvents:      h.recentEvents,
+	}
+}
+
+// debugRequest asks the event loop for a snapshot, which it encodes right
+// away, so that nothing in it is read outside of the event loop.
+type debugRequest chan<- []byte
+
+// debugHandler serves the debug endpoint. Snapshots are taken by the event
+// loop, which receives each request on requests.
+type debugHandler struct {
+	token    string
+	requests chan debugRequest
+}
+
+// newDebugHandler creates the debug handler. It returns nil if no debug token
+// is set.
+func newDebugHandler() *debugHandler {
+	token := os.Getenv(debugTokenEnv)
+	if token == "" {
+		return nil
+	}
+	return &debugHandler{
+		token:    token,
+		requests: make(chan debugRequest),
+	}
+}
+
+func (d *debugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+d.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), debugTimeout)
+	defer cancel()
+
+	reply := make(chan []byte, 1)
+	select {
+	case d.requests <- reply:
+	case <-ctx.Done():
+		http.Error(w, "the event loop is busy", http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case b := <-reply:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	case <-ctx.Done():
+		http.Error(w, "the event loop is busy", http.StatusServiceUnavailable)
+	}
+}
+
+// serveDebug answers the debug request with a snapshot. It must be called
+// from the event loop.
+func (h *commandHandler) serveDebug(reply debugRequest) {
+	b, err := json.MarshalIndent(h.debugSnapshot(), "", "\t")
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	reply <- b
+}
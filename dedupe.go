@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// dedupeBucket is the window that identical announcements by the same author
+// are considered duplicates within.
+const dedupeBucket = time.Minute
+
+// dedupeKey derives the idempotency key of an announcement from its author,
+// its body and the time bucket that it falls into.
+func dedupeKey(authorID discord.UserID, body string, t time.Time) string {
+	h := sha256.New()
+	h.Write([]byte(authorID.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(body))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(t.Truncate(dedupeBucket).Unix(), 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claimDedupeKey stores the idempotency key of the announcement before it is
+// sent. It returns false if the same announcement was already claimed in this
+// or the previous time bucket, in which case it must not be sent again.
+//
+// The returned key should be released using releaseDedupeKey if the
+// announcement ends up not being sent at all.
+func (h *commandHandler) claimDedupeKey(authorID discord.UserID, body string) (string, bool) {
+	now := time.Now()
+
+	previous := dedupeKey(authorID, body, now.Add(-dedupeBucket))
+	if _, ok, err := h.dedupeKeys.Load(previous); err == nil && ok {
+		return "", false
+	}
+
+	key := dedupeKey(authorID, body, now)
+	_, loaded, err := h.dedupeKeys.LoadOrStore(key, now)
+	if err != nil {
+		// Err on the side of sending the announcement rather than silently
+		// dropping it.
+		slog.Warn(
+			"Bot has failed to store the idempotency key of an announcement.",
+			"author_id", authorID,
+			"err", err)
+		return key, true
+	}
+
+	return key, !loaded
+}
+
+// releaseDedupeKey forgets the idempotency key so that the announcement may be
+// retried.
+func (h *commandHandler) releaseDedupeKey(key string) {
+	if err := h.dedupeKeys.Delete(key); err != nil {
+		slog.Warn(
+			"Bot has failed to release the idempotency key of an announcement.",
+			"err", err)
+	}
+}
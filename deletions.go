@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+)
+
+// deletionCheckInterval is how often the bot looks for announcements whose
+// "delete-at" time has come.
+const deletionCheckInterval = time.Minute
+
+// scheduleDeletion schedules the announcement whose primary message is id to
+// be deleted at the given time. A zero time cancels the deletion.
+func (h *commandHandler) scheduleDeletion(id discord.MessageID, at time.Time) {
+	var err error
+	if at.IsZero() {
+		err = h.deletions.Delete(id)
+	} else {
+		err = h.deletions.Store(id, at)
+	}
+	if err != nil {
+		slog.Error(
+			"Bot has failed to schedule the deletion of an announcement.",
+			"message_id", id,
+			"delete_at", at,
+			"err", err)
+	}
+}
+
+// deleteDueAnnouncements deletes the announcements whose "delete-at" time has
+// come. Those that fail to delete are tried again later.
+func (h *commandHandler) deleteDueAnnouncements() {
+	now := time.Now()
+
+	var due []discord.MessageID
+	h.deletions.All()(func(id discord.MessageID, at time.Time) bool {
+		if !now.Before(at) {
+			due = append(due, id)
+		}
+		return true
+	})
+
+	for _, id := range due {
+		record, ok, err := h.announcements.Load(id)
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to look up an announcement that is due to be deleted. It will try again later.",
+				"message_id", id,
+				"err", err)
+			continue
+		}
+
+		// Without a record, the bot can't tell where the announcement went,
+		// so there is nothing left to delete.
+		if ok {
+			if err := h.deleteAnnouncement(id, record); err != nil {
+				slog.Warn(
+					"Bot has failed to delete an announcement that is due. It will try again later.",
+					"message_id", id,
+					"err", err)
+				continue
+			}
+
+			slog.Info(
+				"Bot has deleted an announcement as scheduled.",
+				"author_id", record.AuthorID,
+				"message_id", id)
+		}
+
+		h.unindexAnnouncement(id)
+		h.setSticky(id, nil, false)
+		h.scheduleBump(id, time.Time{})
+		h.scheduleDeletion(id, time.Time{})
+	}
+}
+
+// deleteAnnouncement deletes every message of the announcement. Messages that
+// are already gone are skipped.
+func (h *commandHandler) deleteAnnouncement(id discord.MessageID, record announcementRecord) error {
+	messages := []messageRef{{ChannelID: record.ChannelID, MessageID: id}}
+	messages = append(messages, record.Copies...)
+	for _, ref := range record.Translations {
+		messages = append(messages, ref)
+	}
+
+	var errs []error
+	for _, ref := range messages {
+		var err error
+		if ref.isForumPost() {
+			// Forum posts share their ID with their starter message, and the
+			// whole post goes.
+			err = h.session.DeleteChannel(ref.ChannelID, "the announcement was scheduled to be deleted")
+		} else {
+			err = h.session.DeleteMessage(ref.ChannelID, ref.MessageID, "the announcement was scheduled to be deleted")
+		}
+
+		var httpErr *httputil.HTTPError
+		if err != nil && !(errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snooze postpones a scheduled announcement, or the scheduled deletion of an
+// announcement, given as
+//
+//	snooze <number or message> <duration>
+//
+// such as "snooze 42 2h", where 42 is the number that the bot replied with
+// when the announcement was scheduled. Only its author and admins may snooze
+// it.
+func (h *commandHandler) snooze(inv *invocation, command *parsedCommand) {
+	target, by, _ := strings.Cut(strings.TrimSpace(command.Args), " ")
+
+	d, err := parseLongDuration(strings.TrimSpace(by))
+	if err != nil || d <= 0 {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidSnooze, replyData{
+			Error: fmt.Errorf("%q is not a duration, such as 2h or 3d", strings.TrimSpace(by)),
+		}))
+		return
+	}
+
+	if n, err := strconv.Atoi(strings.TrimPrefix(target, "#")); err == nil {
+		s, ok, err := h.scheduled.Load(n)
+		if err != nil {
+			slog.Error(
+				"Bot has failed to look up the scheduled announcement.",
+				"number", n,
+				"ref", inv.errorRef(),
+				"err", err)
+
+			replyInternalError(h.session, inv)
+			return
+		}
+		if ok {
+			h.snoozeScheduled(inv, n, s, d)
+			return
+		}
+	}
+
+	id, err := parseMessageID(target)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidMessage, replyData{Error: err}))
+		return
+	}
+
+	at, ok, err := h.deletions.Load(id)
+	if err != nil {
+		slog.Error(
+			"Bot has failed to look up the scheduled deletion.",
+			"message_id", id,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	record, recorded, err := h.announcements.Load(id)
+	if err != nil || !ok || !recorded {
+		sendRejection(h.session, inv, inv.text(msgNotScheduled))
+		return
+	}
+
+	if record.AuthorID != inv.Author.ID {
+		if member := inv.member(); member != nil {
+			perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, member)
+			if err != nil || !isAdmin(*h.bot, member, perms) {
+				sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+				return
+			}
+		}
+	}
+
+	if !h.checkEditWindow(inv, record) {
+		return
+	}
+
+	// Snoozing an overdue deletion counts from now.
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	at = at.Add(d)
+
+	// The record keeps the time too, so that the bot knows that it deleted
+	// the announcement itself.
+	record.Options.DeleteAt = at
+	if err := h.announcements.Store(id, record); err != nil {
+		slog.Error(
+			"Bot has failed to record the snoozed deletion.",
+			"message_id", id,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	h.scheduleDeletion(id, at)
+	h.acknowledge(inv, inv.textWith(msgSnoozed, replyData{
+		When: timestampMarkup(at, timestampFull) + " (" + timestampMarkup(at, timestampRelative) + ")",
+	}))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// unifiedDiff returns the unified diff of the lines of before and after, or
+// an empty string if they are the same.
+func unifiedDiff(before, after string) string {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]. Bodies are short enough for this to be cheap.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table into a list of edits, each line prefixed like in the
+	// diff's output.
+	type edit struct {
+		op   byte
+		line string
+		// ai and bi are the indices of the line in a and b, or where it
+		// would be.
+		ai, bi int
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// Find the next change, and the end of the hunk around it, which
+		// takes in changes that are close enough to share context.
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+
+		last := first
+		for k := first; k < len(edits) && k-last <= 2*diffContext+1; k++ {
+			if edits[k].op != ' ' {
+				last = k
+			}
+		}
+
+		from := max(first-diffContext, start)
+		to := min(last+diffContext+1, len(edits))
+
+		var aLines, bLines int
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				aLines++
+			}
+			if e.op != '-' {
+				bLines++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(edits[from].ai, aLines), hunkRange(edits[from].bi, bLines))
+		for _, e := range edits[from:to] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			out.WriteByte('\n')
+		}
+
+		start = to
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the start and length of a hunk of a unified diff. Lines
+// are numbered from 1, and empty hunks start at the line before them.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// auditEdit posts the changes that an edit made to the announcement into the
+// audit channel, so that moderators can review them at a glance. Diffs that
+// don't fit in a message are attached as a file.
+func (h *commandHandler) auditEdit(inv *invocation, ref messageRef, before, after string) {
+	diff := unifiedDiff(before, after)
+	if diff == "" {
+		return
+	}
+
+	data := replyData{
+		Author: inv.Author.Mention(),
+		Link:   messageLink(h.bot.TargetGuildID, ref),
+		Report: strings.TrimSuffix(diff, "\n"),
+	}
+
+	content := localize(h.bot.locale(), msgEditDiff, data)
+	if utf8.RuneCountInString(content) <= maxContentLength && !strings.Contains(diff, "```") {
+		h.sendAudit(msgEditDiff, data)
+		return
+	}
+
+	h.sendAudit(msgEditDiffAttached, data, sendpart.File{
+		Name:   fmt.Sprintf("edit-%s.diff", ref.MessageID),
+		Reader: strings.NewReader(diff),
+	})
+}
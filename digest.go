@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	// digestPeriod is how far back each digest looks, and how often one is
+	// posted.
+	digestPeriod = 7 * 24 * time.Hour
+	// digestCheckInterval is how often the bot checks whether a digest is
+	// due.
+	digestCheckInterval = time.Hour
+	// maxDigestEntries is the number of announcements listed in a digest.
+	// The rest are only counted, so that the digest fits in one message.
+	maxDigestEntries = 25
+)
+
+// digestEntry is an announcement listed in the digest.
+type digestEntry struct {
+	Time  time.Time
+	Title string
+	Link  string
+}
+
+// postDigestIfDue posts the digest of the past week's announcements into the
+// DigestChannelID, if a week has passed since the last one.
+func (h *commandHandler) postDigestIfDue() {
+	if !h.bot.DigestChannelID.IsValid() {
+		return
+	}
+
+	now := time.Now()
+	if !h.bot.Runtime.LastDigest.IsZero() && now.Sub(h.bot.Runtime.LastDigest) < digestPeriod {
+		return
+	}
+
+	entries := h.digestEntries(now.Add(-digestPeriod))
+
+	// Nothing was announced, so there is nothing to post. The next digest is
+	// still a week away, so that quiet weeks don't keep the bot checking.
+	if len(entries) > 0 {
+		if err := h.postDigest(entries); err != nil {
+			slog.Error(
+				"Bot has failed to post the weekly digest. It will try again later.",
+				"channel_id", h.bot.DigestChannelID,
+				"err", err)
+			return
+		}
+
+		slog.Info(
+			"Bot has posted the weekly digest.",
+			"channel_id", h.bot.DigestChannelID,
+			"announcements", len(entries))
+	}
+
+	h.bot.Runtime.LastDigest = now
+	h.saveRuntime()
+}
+
+// digestEntries returns the announcements posted since the given time, oldest
+// first.
+func (h *commandHandler) digestEntries(since time.Time) []digestEntry {
+	var entries []digestEntry
+	h.announcements.All()(func(id discord.MessageID, record announcementRecord) bool {
+		if record.Time.Before(since) {
+			return true
+		}
+
+		var content string
+		if len(record.Revisions) > 0 {
+			content = record.Revisions[len(record.Revisions)-1].Content
+		}
+
+		entries = append(entries, digestEntry{
+			Time:  record.Time,
+			Title: forumPostName(announcementMessage{Content: content}, record.Options),
+			Link:  messageLink(h.bot.TargetGuildID, messageRef{ChannelID: record.ChannelID, MessageID: id}),
+		})
+		return true
+	})
+
+	slices.SortFunc(entries, func(a, b digestEntry) int { return a.Time.Compare(b.Time) })
+	return entries
+}
+
+// postDigest posts the digest of the given announcements.
+func (h *commandHandler) postDigest(entries []digestEntry) error {
+	lines := make([]string, 0, min(len(entries), maxDigestEntries))
+	for _, entry := range entries[:min(len(entries), maxDigestEntries)] {
+		lines = append(lines, fmt.Sprintf("%s — %s (<t:%d:d>)", entry.Title, entry.Link, entry.Time.Unix()))
+	}
+
+	report := formatBulletList(lines)
+	if more := len(entries) - len(lines); more > 0 {
+		report += "\n" + localize(h.bot.locale(), msgDigestMore, replyData{Count: more})
+	}
+
+	_, err := h.session.SendMessageComplex(h.bot.DigestChannelID, api.SendMessageData{
+		Content: localize(h.bot.locale(), msgDigest, replyData{Report: report}),
+		// Titles may contain mentions, which shouldn't ping anyone again.
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	return err
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/ningen/v3"
+)
+
+// doctorCheck is the result of one check made by the doctor.
+type doctorCheck struct {
+	Name string
+	// Err is why the check failed, or nil if it passed.
+	Err error
+}
+
+// channelPermission is a permission that the bot needs in the channels that
+// it announces in.
+type channelPermission struct {
+	Permission discord.Permissions
+	Name       string
+}
+
+// announcePermissions are the permissions that the bot needs in the channels
+// that it announces in.
+var announcePermissions = []channelPermission{
+	{discord.PermissionViewChannel, "View Channel"},
+	{discord.PermissionSendMessages, "Send Messages"},
+	{discord.PermissionEmbedLinks, "Embed Links"},
+	{discord.PermissionManageMessages, "Manage Messages"},
+}
+
+// startupPermissions are the permissions that the bot checks for in the
+// target channel when it starts. Manage Messages is needed for pinning.
+var startupPermissions = []channelPermission{
+	{discord.PermissionViewChannel, "View Channel"},
+	{discord.PermissionSendMessages, "Send Messages"},
+	{discord.PermissionEmbedLinks, "Embed Links"},
+	{discord.PermissionAttachFiles, "Attach Files"},
+	{discord.PermissionManageMessages, "Manage Messages"},
+}
+
+// checkStartupPermissions warns if the bot lacks any of startupPermissions in
+// the target channel, so that it is noticed before the next announcement
+// fails.
+func checkStartupPermissions(session *ningen.State, bot botState) {
+	missing, err := missingPermissions(session, bot, bot.TargetChannelID, startupPermissions)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to check its permissions in the target channel.",
+			"channel_id", bot.TargetChannelID,
+			"err", err)
+		return
+	}
+
+	if len(missing) > 0 {
+		slog.Error(
+			"Bot is missing permissions in the target channel. Announcements may fail until they are granted.",
+			"channel_id", bot.TargetChannelID,
+			"missing", missing)
+	}
+}
+
+// missingPermissions returns the names of the permissions that the bot lacks
+// in the channel.
+func missingPermissions(session *ningen.State, bot botState, channelID discord.ChannelID, required []channelPermission) ([]string, error) {
+	perms, err := session.Permissions(channelID, bot.SelfID)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, p := range required {
+		if !perms.Has(p.Permission) {
+			missing = append(missing, p.Name)
+		}
+	}
+	return missing, nil
+}
+
+// runDoctor checks whether the bot can actually do its job in the target
+// guild.
+func runDoctor(session *ningen.State, bot botState) []doctorCheck {
+	var checks []doctorCheck
+
+	channelIDs := bot.announceChannels()
+	for _, channelID := range bot.Translation.Channels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	if bot.StagingChannelID.IsValid() {
+		channelIDs = append(channelIDs, bot.StagingChannelID)
+	}
+	if bot.DigestChannelID.IsValid() {
+		channelIDs = append(channelIDs, bot.DigestChannelID)
+	}
+
+	for _, channelID := range channelIDs {
+		name := channelID.String()
+		if ch, err := session.Channel(channelID); err == nil {
+			name = "#" + ch.Name
+		}
+
+		check := doctorCheck{Name: "permissions in " + name}
+		missing, err := missingPermissions(session, bot, channelID, announcePermissions)
+		switch {
+		case err != nil:
+			check.Err = err
+		case len(missing) > 0:
+			check.Err = fmt.Errorf("missing %s", strings.Join(missing, ", "))
+		}
+		checks = append(checks, check)
+	}
+
+	roles, err := session.Roles(bot.TargetGuildID)
+	if err != nil {
+		checks = append(checks, doctorCheck{Name: "roles", Err: err})
+	} else {
+		checkRoles := func(field string, roleIDs []discord.RoleID) {
+			check := doctorCheck{Name: field}
+
+			var gone []string
+			for _, id := range roleIDs {
+				if !hasRole(roles, id) {
+					gone = append(gone, id.String())
+				}
+			}
+			if len(gone) > 0 {
+				check.Err = fmt.Errorf("roles %s no longer exist", strings.Join(gone, ", "))
+			}
+
+			checks = append(checks, check)
+		}
+
+		checkRoles("AllowedRoleIDs", bot.AllowedRoleIDs)
+		if len(bot.AdminRoleIDs) > 0 {
+			checkRoles("AdminRoleIDs", bot.AdminRoleIDs)
+		}
+		if bot.AnnounceRoleID.IsValid() {
+			checkRoles("AnnounceRoleID", []discord.RoleID{bot.AnnounceRoleID})
+		}
+	}
+
+	// Bot accounts can only see who holds which roles if they have the
+	// Server Members intent. User accounts read the member list instead, which
+	// they always can.
+	if bot.AppID.IsValid() {
+		check := doctorCheck{Name: "member list"}
+		if bot.AppFlags&(discord.AppFlagGatewayGuildMembers|discord.AppFlagGatewayGuildMembersLimited) == 0 {
+			check.Err = errors.New("the Server Members intent is not enabled")
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func hasRole(roles []discord.Role, id discord.RoleID) bool {
+	for _, role := range roles {
+		if role.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDoctorReport formats the checks as one line each.
+func formatDoctorReport(checks []doctorCheck) string {
+	var b strings.Builder
+	for _, check := range checks {
+		if check.Err == nil {
+			fmt.Fprintf(&b, "✅ %s\n", check.Name)
+		} else {
+			fmt.Fprintf(&b, "❌ %s: %v\n", check.Name, check.Err)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (h *commandHandler) doctor(inv *invocation) {
+	report := formatDoctorReport(runDoctor(h.session, *h.bot))
+	sendReply(h.session, inv, inv.textWith(msgDoctor, replyData{Report: report}))
+}
+
+// doctorTimeout is how long the doctor subcommand waits for the bot to connect
+// and find its target channel.
+const doctorTimeout = 30 * time.Second
+
+func doctorSubcommand(ctx context.Context, args []string) int {
+	token := os.Getenv("DISCORD_TOKEN")
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "the doctor requires $DISCORD_TOKEN to be set")
+		return 1
+	}
+
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "the config is invalid:", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	session := newSession(token).WithContext(ctx)
+
+	readyCh := make(chan *gateway.ReadyEvent, 1)
+	session.AddHandler(func(ev *gateway.ReadyEvent) {
+		select {
+		case readyCh <- ev:
+		default:
+		}
+	})
+
+	guildCh := make(chan struct{}, 1)
+	session.AddHandler(func(*gateway.GuildCreateEvent) {
+		select {
+		case guildCh <- struct{}{}:
+		default:
+		}
+	})
+
+	if err := session.Open(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "cannot connect to Discord:", err)
+		return 1
+	}
+	defer session.Close()
+
+	bot := botState{botSettings: settings}
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr, "cannot connect to Discord:", ctx.Err())
+		return 1
+	case ev := <-readyCh:
+		bot.SelfID = ev.User.ID
+		if ev.User.Bot {
+			bot.AppID = ev.Application.ID
+			bot.AppFlags = ev.Application.Flags
+		}
+	}
+
+	for {
+		ch, err := session.Cabinet.Channel(bot.TargetChannelID)
+		if err == nil {
+			bot.TargetGuildID = ch.GuildID
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr, "cannot find the target channel:", err)
+			return 1
+		case <-guildCh:
+		}
+	}
+
+	checks := runDoctor(session, bot)
+	fmt.Println(formatDoctorReport(checks))
+
+	for _, check := range checks {
+		if check.Err != nil {
+			return 1
+		}
+	}
+	return 0
+}
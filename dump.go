@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// dumpState logs the current state of the bot, so that a wedged instance can
+// be diagnosed without attaching a debugger.
+func (h *commandHandler) dumpState() {
+	var unsent int
+	h.outbox.Keys()(func(string) bool {
+		unsent++
+		return true
+	})
+
+	slog.Info(
+		"Bot is dumping its state as requested.",
+		"self_id", h.bot.SelfID,
+		"app_id", h.bot.AppID,
+		"target_guild_id", h.bot.TargetGuildID,
+		"target_channel_id", h.bot.TargetChannelID,
+		"extra_channel_ids", h.bot.ExtraChannelIDs,
+		"last_announced", h.bot.LastAnnouncedTime,
+		"cooldown_remaining", h.bot.cooldownRemaining().Round(time.Second),
+		"paused", h.bot.Runtime.Paused,
+		"pending", len(h.pending),
+		"unsent_outbox", unsent,
+		"commands", h.stats.Commands,
+		"announcements", h.stats.Announcements,
+		"uptime", time.Since(h.stats.Started).Round(time.Second),
+		"goroutines", runtime.NumGoroutine())
+
+	// Each database lives in its own directory within the state directory.
+	entries, err := os.ReadDir(stateDirectory)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to list the state directory.",
+			"err", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == controlSocketDirectory {
+			continue
+		}
+
+		var files int
+		var size int64
+		filepath.WalkDir(filepath.Join(stateDirectory, entry.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				files++
+				size += info.Size()
+			}
+			return nil
+		})
+
+		slog.Info(
+			"Bot has a database in its state directory.",
+			"database", entry.Name(),
+			"files", files,
+			"bytes", size)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifyDump returns a nil channel on platforms without SIGUSR1, so state
+// dumps are never requested.
+func notifyDump() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+const (
+	// editEmoji is the reaction that authors put on their own announcement
+	// to edit it over DM.
+	editEmoji = "✏️"
+	// editReplyTimeout is how long the bot waits for the new body after it
+	// has DMed the author.
+	editReplyTimeout = 15 * time.Minute
+	// maxInlineBodyLength is the longest body that is DMed as text. Longer
+	// bodies are attached as a file.
+	maxInlineBodyLength = 1500
+)
+
+// editRequest is an author who has reacted to edit their announcement and is
+// yet to reply with the new body.
+type editRequest struct {
+	// Target is the primary message of the announcement.
+	Target discord.MessageID
+	// ChannelID is the DM channel that the new body is expected in.
+	ChannelID discord.ChannelID
+	// Options are used to render the new body if the reply has no front
+	// matter of its own.
+	Options announceOptions
+	Started time.Time
+}
+
+// handleEditReaction starts the edit flow if the author reacted to their own
+// announcement with editEmoji. The reaction is removed either way, so that
+// it can be used again.
+func (h *commandHandler) handleEditReaction(ev *gateway.MessageReactionAddEvent) {
+	if ev.UserID == h.bot.SelfID || ev.Emoji.Name != editEmoji || ev.Emoji.IsCustom() {
+		return
+	}
+	if ev.GuildID != h.bot.TargetGuildID || ev.Member == nil {
+		return
+	}
+
+	target, record, ok := h.findAnnouncement(messageRef{ChannelID: ev.ChannelID, MessageID: ev.MessageID})
+	if !ok || record.AuthorID != ev.UserID {
+		return
+	}
+
+	if err := h.session.DeleteUserReaction(ev.ChannelID, ev.MessageID, ev.UserID, discord.APIEmoji(editEmoji)); err != nil {
+		slog.Debug(
+			"Bot has failed to remove the edit reaction.",
+			"channel_id", ev.ChannelID,
+			"message_id", ev.MessageID,
+			"err", err)
+	}
+
+	if h.bot.isBlocked(ev.UserID) {
+		return
+	}
+	perms, err := memberPermissions(h.session, *h.bot, ev.UserID, ev.Member)
+	if err != nil || !canUse(*h.bot, "edit", ev.Member, perms) {
+		return
+	}
+
+	// There is no point in asking for a body that can't be applied.
+	if h.bot.EditWindow > 0 && time.Since(record.Time) > h.bot.EditWindow && !isAdmin(*h.bot, ev.Member, perms) {
+		return
+	}
+
+	// Records from before the body was kept only have what was posted.
+	body := record.Body
+	if body == "" && len(record.Revisions) > 0 {
+		body = record.Revisions[len(record.Revisions)-1].Content
+	}
+
+	text := localize(h.bot.locale(), msgEditPrompt, replyData{
+		Link:      messageLink(h.bot.TargetGuildID, messageRef{ChannelID: record.ChannelID, MessageID: target}),
+		Remaining: editReplyTimeout,
+	})
+
+	data := api.SendMessageData{AllowedMentions: &api.AllowedMentions{}}
+	if body != "" && len(body) <= maxInlineBodyLength && !strings.Contains(body, "```") {
+		data.Content = text + "\n```\n" + body + "\n```"
+	} else {
+		data.Content = text
+		if body != "" {
+			data.Files = []sendpart.File{{
+				Name:   fmt.Sprintf("announcement-%s.md", target),
+				Reader: strings.NewReader(body),
+			}}
+		}
+	}
+
+	dm, err := h.session.CreatePrivateChannel(ev.UserID)
+	if err == nil {
+		_, err = h.session.SendMessageComplex(dm.ID, data)
+	}
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to DM the author the body of their announcement.",
+			"author_id", ev.UserID,
+			"message_id", target,
+			"err", err)
+		return
+	}
+
+	h.editRequests[ev.UserID] = editRequest{
+		Target:    target,
+		ChannelID: dm.ID,
+		Options:   record.Options,
+		Started:   time.Now(),
+	}
+}
+
+// handleEditReply applies the new body that the author DMed the bot after
+// reacting with editEmoji. It returns false if the message isn't such a reply.
+func (h *commandHandler) handleEditReply(ev *gateway.MessageCreateEvent) bool {
+	if ev.GuildID.IsValid() {
+		return false
+	}
+
+	req, ok := h.editRequests[ev.Author.ID]
+	if !ok || req.ChannelID != ev.ChannelID {
+		return false
+	}
+	delete(h.editRequests, ev.Author.ID)
+
+	if time.Since(req.Started) > editReplyTimeout {
+		return false
+	}
+
+	inv := newMessageInvocation(ev, h.bot.locale())
+	defer h.trackReplies(inv)
+
+	if strings.EqualFold(strings.TrimSpace(ev.Content), "cancel") {
+		sendReply(h.session, inv, inv.text(msgEditCancelled))
+		return true
+	}
+
+	// These are checked again, since a lot may have changed while the bot
+	// was waiting. The edit window is checked again when the edit is made.
+	switch {
+	case h.bot.isBlocked(ev.Author.ID):
+		sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+		return true
+	case h.draining:
+		sendRejection(h.session, inv, inv.text(msgDraining))
+		return true
+	case h.bot.Runtime.Paused:
+		sendRejection(h.session, inv, inv.textWith(msgPaused, replyData{Reason: h.bot.Runtime.PauseReason}))
+		return true
+	}
+
+	// The author may have lost the roles that let them edit.
+	member := h.invokerMember(inv)
+	if member == nil {
+		sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+		return true
+	}
+	perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, member)
+	if err != nil || !canUse(*h.bot, "edit", member, perms) {
+		sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+		return true
+	}
+
+	opts := req.Options
+	options, body := cutFrontMatter(ev.Content)
+	if options != "" {
+		if opts, err = parseAnnounceOptions(options); err != nil {
+			sendRejection(h.session, inv, inv.textWith(msgInvalidOptions, replyData{Error: err}))
+			return true
+		}
+	}
+
+	h.editAnnouncementBody(inv, req.Target, options, opts, body, func() {
+		h.acknowledge(inv, inv.text(msgEditApplied))
+	})
+	return true
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// checkEditWindow refuses changes to announcements that were posted longer
+// than EditWindow ago, unless the invoker is an admin, so that official posts
+// keep their history. It returns false if the change was refused, in which
+// case the invoker has been told why.
+func (h *commandHandler) checkEditWindow(inv *invocation, record announcementRecord) bool {
+	if h.bot.EditWindow == 0 || time.Since(record.Time) <= h.bot.EditWindow {
+		return true
+	}
+
+	// The console is run by whoever runs the bot.
+	if inv.Console != nil {
+		return true
+	}
+
+	if member := h.invokerMember(inv); member != nil {
+		perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, member)
+		if err == nil && isAdmin(*h.bot, member, perms) {
+			return true
+		}
+	}
+
+	sendRejection(h.session, inv, inv.textWith(msgEditWindowClosed, replyData{Duration: h.bot.EditWindow}))
+	return false
+}
+
+// invokerMember returns the invoker as a member of the target guild, or nil
+// if they can't be found. Replies in the DM edit flow don't come with the
+// member, so it is fetched for them.
+func (h *commandHandler) invokerMember(inv *invocation) *discord.Member {
+	if member := inv.member(); member != nil || inv.Console != nil {
+		return member
+	}
+
+	member, err := h.session.Member(h.bot.TargetGuildID, inv.Author.ID)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to get the member to check their roles.",
+			"user_id", inv.Author.ID,
+			"err", err)
+		return nil
+	}
+	return member
+}
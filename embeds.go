@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// embedFence opens an embed block in the body. The block is closed by a line
+// containing only "```".
+const embedFence = "```embed"
+
+// maxEmbeds is the maximum number of embeds that a message may have.
+const maxEmbeds = 10
+
+// maxEmbedsLength is the maximum total length of all embeds in a message.
+const maxEmbedsLength = 6000
+
+// cutEmbeds cuts all embed blocks out of the body and parses them. An embed
+// block is written using keyed lines like so:
+//
+//	```embed
+//	title: Release v1.0
+//	url: https://example.com/releases/v1.0
+//	color: #5865F2
+//	description: This release brings
+//	  a lot of new things.
+//	field: Downloads | https://example.com/downloads
+//	field: Version | v1.0 | inline
+//	image: https://example.com/banner.png
+//	thumbnail: https://example.com/logo.png
+//	footer: Thanks for using our project!
+//	```
+//
+// Lines that don't begin with a known key continue the value of the
+// description or the last field. The parsed embeds are validated against
+// Discord's limits.
+func cutEmbeds(body string) (string, []discord.Embed, error) {
+	var embeds []discord.Embed
+	var rest []string
+
+	lines := strings.Split(body, "\n")
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != embedFence {
+			rest = append(rest, lines[i])
+			continue
+		}
+
+		end := -1
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == "```" {
+				end = j
+				break
+			}
+		}
+		if end == -1 {
+			return "", nil, fmt.Errorf("embed %d is never closed", len(embeds)+1)
+		}
+
+		embed, err := parseEmbed(lines[i+1 : end])
+		if err != nil {
+			return "", nil, fmt.Errorf("embed %d: %w", len(embeds)+1, err)
+		}
+
+		embeds = append(embeds, embed)
+		i = end
+	}
+
+	if len(embeds) > maxEmbeds {
+		return "", nil, fmt.Errorf("there are %d embeds, but only %d are allowed", len(embeds), maxEmbeds)
+	}
+
+	var total int
+	for _, embed := range embeds {
+		total += embed.Length()
+	}
+	if total > maxEmbedsLength {
+		return "", nil, fmt.Errorf("the embeds are %d characters long in total, but only %d are allowed", total, maxEmbedsLength)
+	}
+
+	return strings.TrimSpace(strings.Join(rest, "\n")), embeds, nil
+}
+
+func parseEmbed(lines []string) (discord.Embed, error) {
+	embed := discord.Embed{Type: discord.NormalEmbed}
+
+	// continued points to the value that continuation lines are appended to.
+	var continued *string
+
+	for _, line := range lines {
+		k, v, ok := strings.Cut(line, ":")
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+
+		if !ok || !isEmbedKey(k) {
+			if continued == nil {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				return embed, fmt.Errorf("line %q has no key", line)
+			}
+			*continued += "\n" + strings.TrimSpace(line)
+			continue
+		}
+
+		continued = nil
+
+		switch k {
+		case "title":
+			embed.Title = v
+		case "url":
+			embed.URL = v
+		case "description":
+			embed.Description = v
+			continued = &embed.Description
+		case "color":
+			c, err := strconv.ParseUint(strings.TrimPrefix(v, "#"), 16, 24)
+			if err != nil {
+				return embed, fmt.Errorf("color %q is not a hex color", v)
+			}
+			embed.Color = discord.Color(c)
+		case "image":
+			embed.Image = &discord.EmbedImage{URL: v}
+		case "thumbnail":
+			embed.Thumbnail = &discord.EmbedThumbnail{URL: v}
+		case "footer":
+			embed.Footer = &discord.EmbedFooter{Text: v}
+		case "field":
+			parts := strings.Split(v, "|")
+			if len(parts) < 2 || len(parts) > 3 {
+				return embed, errors.New("fields must be written as: name | value | inline")
+			}
+			field := discord.EmbedField{
+				Name:  strings.TrimSpace(parts[0]),
+				Value: strings.TrimSpace(parts[1]),
+			}
+			if len(parts) == 3 {
+				if strings.TrimSpace(parts[2]) != "inline" {
+					return embed, fmt.Errorf("field %q has an unknown flag", field.Name)
+				}
+				field.Inline = true
+			}
+			embed.Fields = append(embed.Fields, field)
+			continued = &embed.Fields[len(embed.Fields)-1].Value
+		}
+	}
+
+	if embed.Title == "" && embed.Description == "" && len(embed.Fields) == 0 && embed.Image == nil {
+		return embed, errors.New("embed is empty")
+	}
+
+	if err := embed.Validate(); err != nil {
+		return embed, err
+	}
+
+	return embed, nil
+}
+
+func isEmbedKey(k string) bool {
+	switch k {
+	case "title", "url", "description", "color", "image", "thumbnail", "footer", "field":
+		return true
+	default:
+		return false
+	}
+}
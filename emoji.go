@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+// shortcodePattern matches emoji shortcodes such as ":shipit:". The character
+// before the shortcode is captured so that the names inside custom emoji
+// markup, such as "<:shipit:123>", aren't matched.
+var shortcodePattern = regexp.MustCompile(`(^|[^\w<]):(\w{2,32}):`)
+
+// resolveEmojiShortcodes replaces the shortcodes of the target guild's custom
+// emoji in the body with their markup, so that authors don't need to know
+// their IDs. Shortcodes that aren't custom emoji are left alone.
+func (h *commandHandler) resolveEmojiShortcodes(body string) string {
+	if !shortcodePattern.MatchString(body) {
+		return body
+	}
+
+	emojis, err := h.session.Cabinet.Emojis(h.bot.TargetGuildID)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to get the emoji of the target guild. Shortcodes will be left as-is.",
+			"guild_id", h.bot.TargetGuildID,
+			"err", err)
+		return body
+	}
+
+	markup := make(map[string]string, len(emojis))
+	for _, emoji := range emojis {
+		markup[emoji.Name] = emoji.String()
+	}
+
+	return shortcodePattern.ReplaceAllStringFunc(body, func(match string) string {
+		m := shortcodePattern.FindStringSubmatch(match)
+		if s, ok := markup[m[2]]; ok {
+			return m[1] + s
+		}
+		return match
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/state/store/defaultstore"
+	"github.com/diamondburned/arikawa/v3/utils/handler"
+	"github.com/diamondburned/ningen/v3"
+)
+
+const (
+	apiURLEnv     = "DISCORD_API_URL"
+	gatewayURLEnv = "DISCORD_GATEWAY_URL"
+)
+
+// customGatewayURL is the gateway to connect to instead of the one that the
+// REST API hands out. It is set by applyEndpoints.
+var customGatewayURL string
+
+// applyEndpoints points the bot at the REST API and gateway given using
+// $DISCORD_API_URL and $DISCORD_GATEWAY_URL, such as a test server that
+// speaks Discord's API.
+//
+// The REST client builds its URLs from constants, so requests are rewritten
+// on their way out instead. Like applyProxy, it must be called before any
+// request is made.
+func applyEndpoints() error {
+	if v := os.Getenv(apiURLEnv); v != "" {
+		u, err := parseEndpoint(apiURLEnv, v, "http", "https")
+		if err != nil {
+			return err
+		}
+
+		http.DefaultTransport = &endpointTransport{
+			from: api.Endpoint,
+			to:   strings.TrimSuffix(u.String(), "/") + "/",
+			next: http.DefaultTransport,
+		}
+	}
+
+	if v := os.Getenv(gatewayURLEnv); v != "" {
+		u, err := parseEndpoint(gatewayURLEnv, v, "ws", "wss")
+		if err != nil {
+			return err
+		}
+		customGatewayURL = gateway.AddGatewayParams(u.String())
+	}
+
+	return nil
+}
+
+func parseEndpoint(env, v string, schemes ...string) (*url.URL, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $%s: %w", env, err)
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme && u.Host != "" {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid $%s %q: must be like %s://host/path", env, v, schemes[len(schemes)-1])
+}
+
+// endpointTransport sends requests meant for one base URL to another.
+// Requests to anywhere else are left alone.
+type endpointTransport struct {
+	from string
+	to   string
+	next http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rest, ok := strings.CutPrefix(r.URL.String(), t.from)
+	if !ok {
+		return t.next.RoundTrip(r)
+	}
+
+	u, err := url.Parse(t.to + rest)
+	if err != nil {
+		return nil, err
+	}
+
+	r = r.Clone(r.Context())
+	r.URL = u
+	r.Host = u.Host
+	return t.next.RoundTrip(r)
+}
+
+// newCustomGatewayState creates a state that connects to customGatewayURL
+// instead of asking the REST API where the gateway is.
+func newCustomGatewayState(id gateway.Identifier) *ningen.State {
+	g := gateway.NewCustomWithIdentifier(customGatewayURL, id, nil)
+	s := session.NewWithGateway(g, handler.New())
+	return ningen.FromState(state.NewFromSession(s, defaultstore.New()))
+}
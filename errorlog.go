@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxRecentErrors is how many of the most recent errors are kept in memory.
+const maxRecentErrors = 50
+
+// maxShownErrors is how many errors the errors command shows, so that its
+// reply fits in a message.
+const maxShownErrors = 10
+
+// maxShownErrorLength is how much of each error the errors command shows.
+const maxShownErrorLength = 150
+
+// errorRecord is an error that the bot has logged.
+type errorRecord struct {
+	Time time.Time
+	// Operation is the log message, which says what the bot was doing.
+	Operation string
+	// Error is the text of the logged error, if any.
+	Error string
+	// Ref is the correlation ID of the error, if it has one.
+	Ref string
+}
+
+// errorRing keeps the most recent errors. Errors are logged from any
+// goroutine, so it is guarded by a mutex.
+type errorRing struct {
+	mu      sync.Mutex
+	records []errorRecord
+}
+
+// recentErrors holds the errors that the bot has logged since it started.
+var recentErrors = &errorRing{}
+
+func (r *errorRing) add(record errorRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.records) == maxRecentErrors {
+		r.records = append(r.records[:0], r.records[1:]...)
+	}
+	r.records = append(r.records, record)
+}
+
+// latest returns up to n of the most recent errors, newest first.
+func (r *errorRing) latest(n int) []errorRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n = min(n, len(r.records))
+	latest := make([]errorRecord, n)
+	for i := range latest {
+		latest[i] = r.records[len(r.records)-1-i]
+	}
+	return latest
+}
+
+// errorRecorder is a slog.Handler that remembers every error that passes
+// through it in an errorRing before handing it on.
+type errorRecorder struct {
+	slog.Handler
+	ring  *errorRing
+	attrs []slog.Attr
+}
+
+func newErrorRecorder(h slog.Handler, ring *errorRing) *errorRecorder {
+	return &errorRecorder{Handler: h, ring: ring}
+}
+
+func (h *errorRecorder) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		record := errorRecord{
+			Time:      r.Time,
+			Operation: r.Message,
+		}
+
+		collect := func(attr slog.Attr) bool {
+			switch attr.Key {
+			case "err":
+				record.Error = attr.Value.String()
+			case "ref":
+				record.Ref = attr.Value.String()
+			}
+			return true
+		}
+		for _, attr := range h.attrs {
+			collect(attr)
+		}
+		r.Attrs(collect)
+
+		h.ring.add(record)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *errorRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorRecorder{
+		Handler: h.Handler.WithAttrs(attrs),
+		ring:    h.ring,
+		attrs:   append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+	}
+}
+
+func (h *errorRecorder) WithGroup(name string) slog.Handler {
+	return &errorRecorder{
+		Handler: h.Handler.WithGroup(name),
+		ring:    h.ring,
+		attrs:   h.attrs,
+	}
+}
+
+// formatErrors formats the errors as one line each.
+func formatErrors(records []errorRecord) string {
+	lines := make([]string, len(records))
+	for i, record := range records {
+		line := fmt.Sprintf("%s %s", timestampMarkup(record.Time, timestampRelative), record.Operation)
+		if record.Error != "" {
+			// Backticks in the error would end the code span early.
+			text := strings.ReplaceAll(record.Error, "`", "'")
+			line += " `" + truncateText(text, maxShownErrorLength) + "`"
+		}
+		if record.Ref != "" {
+			line += " (ref " + record.Ref + ")"
+		}
+		lines[i] = line
+	}
+	return formatBulletList(lines)
+}
+
+// truncateText cuts the text down to at most n runes, marking the cut with an
+// ellipsis.
+func truncateText(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:n-1]) + "…"
+}
+
+func (h *commandHandler) showErrors(inv *invocation) {
+	records := recentErrors.latest(maxShownErrors)
+	if len(records) == 0 {
+		sendReply(h.session, inv, inv.text(msgNoErrors))
+		return
+	}
+
+	sendReply(h.session, inv, inv.textWith(msgErrors, replyData{Report: formatErrors(records)}))
+}
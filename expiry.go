@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// expiryCheckInterval is how often the bot looks for state entries that are
+// older than StateRetention.
+const expiryCheckInterval = time.Hour
+
+// minStateRetention is the shortest StateRetention that may be configured.
+// Anything shorter would forget announcements while they may still be edited.
+const minStateRetention = 24 * time.Hour
+
+// expireState deletes the last-sent and dedupe entries that are older than
+// StateRetention, and forgets flood attempts and edit requests that no longer
+// count. If StateRetention is zero, persisted entries never expire.
+func (h *commandHandler) expireState() {
+	now := time.Now()
+	h.flood.expire(now)
+	maps.DeleteFunc(h.editRequests, func(_ discord.UserID, req editRequest) bool {
+		return now.Sub(req.Started) > editReplyTimeout
+	})
+
+	if h.bot.StateRetention <= 0 {
+		return
+	}
+	cutoff := now.Add(-h.bot.StateRetention)
+
+	var authors []discord.UserID
+	h.lastSentAuthors.All()(func(authorID discord.UserID, id discord.MessageID) bool {
+		if id.Time().Before(cutoff) {
+			authors = append(authors, authorID)
+		}
+		return true
+	})
+
+	var keys []string
+	h.dedupeKeys.All()(func(key string, claimed time.Time) bool {
+		if claimed.Before(cutoff) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+
+	if len(authors) == 0 && len(keys) == 0 {
+		return
+	}
+
+	for _, authorID := range authors {
+		if err := h.lastSentAuthors.Delete(authorID); err != nil {
+			slog.Warn(
+				"Bot has failed to expire the last announcement of an author.",
+				"author_id", authorID,
+				"err", err)
+		}
+	}
+
+	for _, key := range keys {
+		if err := h.dedupeKeys.Delete(key); err != nil {
+			slog.Warn(
+				"Bot has failed to expire an idempotency key.",
+				"err", err)
+		}
+	}
+
+	slog.Info(
+		"Bot has expired state entries older than the retention.",
+		"retention", h.bot.StateRetention,
+		"last_sent_authors", len(authors),
+		"dedupe_keys", len(keys))
+}
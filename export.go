@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+// userExport is everything that the bot stores about a user, as sent to
+// them by the export-me command.
+type userExport struct {
+	UserID   discord.UserID
+	Exported time.Time
+	// LastAnnouncement is the announcement that the edit command edits, if
+	// the bot remembers one.
+	LastAnnouncement *discord.MessageID `json:",omitempty"`
+	Announcements    []exportedAnnouncement
+	// Outbox are the user's announcements that are yet to be fully sent.
+	Outbox []outboxEntry
+	// Staged are the user's announcements that are waiting for review.
+	Staged []stagedAnnouncement
+	// Scheduled are the user's announcements that are waiting for their
+	// "send-at" time.
+	Scheduled []scheduledAnnouncement
+	// Countdowns are the user's countdowns that are still counting.
+	Countdowns []countdown
+	// Templates are the templates that the user saved last, by name.
+	Templates map[string]announcementTemplate
+	// Snippets are the snippets that the user set last, by name.
+	Snippets map[string]snippet
+	Quota    exportedQuota
+	// Blocked is true if the user may not use the bot.
+	Blocked bool
+}
+
+// exportedAnnouncement is an announcement record in a user export.
+type exportedAnnouncement struct {
+	MessageID discord.MessageID
+	announcementRecord
+	Link string
+}
+
+// exportedQuota is how much of their quota the user has used up.
+type exportedQuota struct {
+	// CooldownEnds is when anyone may announce again. It is zero if the
+	// cooldown is over.
+	CooldownEnds time.Time `json:",omitempty"`
+	// RecentCommands is the number of commands that the user has sent in the
+	// past minute, out of FloodLimit.
+	RecentCommands int
+	FloodLimit     int
+	// IgnoredUntil is when the bot stops ignoring the user for flooding. It
+	// is zero if they aren't ignored.
+	IgnoredUntil time.Time `json:",omitempty"`
+}
+
+// exportUser collects everything that the bot stores about the user.
+func (h *commandHandler) exportUser(userID discord.UserID) userExport {
+	now := time.Now()
+	export := userExport{
+		UserID:        userID,
+		Exported:      now,
+		Announcements: []exportedAnnouncement{},
+		Outbox:        []outboxEntry{},
+		Staged:        []stagedAnnouncement{},
+		Countdowns:    []countdown{},
+		Templates:     map[string]announcementTemplate{},
+		Snippets:      map[string]snippet{},
+		Blocked:       h.bot.isBlocked(userID),
+	}
+
+	if id, ok, err := h.lastSentAuthors.Load(userID); err == nil && ok {
+		export.LastAnnouncement = &id
+	}
+
+	h.announcements.All()(func(id discord.MessageID, record announcementRecord) bool {
+		if record.AuthorID == userID {
+			export.Announcements = append(export.Announcements, exportedAnnouncement{
+				MessageID:          id,
+				announcementRecord: record,
+				Link:               messageLink(h.bot.TargetGuildID, messageRef{ChannelID: record.ChannelID, MessageID: id}),
+			})
+		}
+		return true
+	})
+	slices.SortFunc(export.Announcements, func(a, b exportedAnnouncement) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	h.outbox.All()(func(_ string, entry outboxEntry) bool {
+		if entry.AuthorID == userID {
+			export.Outbox = append(export.Outbox, entry)
+		}
+		return true
+	})
+
+	h.staged.All()(func(_ discord.MessageID, staged stagedAnnouncement) bool {
+		if staged.AuthorID == userID {
+			export.Staged = append(export.Staged, staged)
+		}
+		return true
+	})
+
+	h.scheduled.All()(func(_ int, s scheduledAnnouncement) bool {
+		if s.AuthorID == userID {
+			export.Scheduled = append(export.Scheduled, s)
+		}
+		return true
+	})
+
+	h.countdowns.All()(func(_ discord.MessageID, c countdown) bool {
+		if c.AuthorID == userID {
+			export.Countdowns = append(export.Countdowns, c)
+		}
+		return true
+	})
+
+	h.templates.All()(func(name string, t announcementTemplate) bool {
+		if t.AuthorID == userID {
+			export.Templates[name] = t
+		}
+		return true
+	})
+
+	h.snippets.All()(func(name string, s snippet) bool {
+		if s.AuthorID == userID {
+			export.Snippets[name] = s
+		}
+		return true
+	})
+
+	if remaining := h.bot.cooldownRemaining(); remaining > 0 {
+		export.Quota.CooldownEnds = now.Add(remaining)
+	}
+	export.Quota.FloodLimit = h.bot.FloodLimit
+	for _, t := range h.flood.attempts[userID] {
+		if now.Sub(t) < floodWindow {
+			export.Quota.RecentCommands++
+		}
+	}
+	if until, ok := h.flood.ignoredUntil[userID]; ok && now.Before(until) {
+		export.Quota.IgnoredUntil = until
+	}
+
+	return export
+}
+
+// exportMe DMs the author a JSON file of everything that the bot stores about
+// them. Commands from the REPL have the export printed instead.
+func (h *commandHandler) exportMe(inv *invocation) {
+	export := h.exportUser(inv.Author.ID)
+
+	if inv.Console != nil {
+		printJSON(inv.Console, export)
+		return
+	}
+
+	b, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		slog.Error(
+			"Bot has failed to encode a user's data export.",
+			"author_id", inv.Author.ID,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	dm, err := h.session.CreatePrivateChannel(inv.Author.ID)
+	if err == nil {
+		_, err = h.session.SendMessageComplex(dm.ID, api.SendMessageData{
+			Content: inv.text(msgExportDM),
+			Files: []sendpart.File{{
+				Name:   fmt.Sprintf("message-for-me-%s.json", inv.Author.ID),
+				Reader: bytes.NewReader(b),
+			}},
+		})
+	}
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to DM the author their data export.",
+			"author_id", inv.Author.ID,
+			"err", err)
+
+		sendRejection(h.session, inv, inv.text(msgExportNotSent))
+		return
+	}
+
+	h.acknowledge(inv, inv.text(msgExported))
+}
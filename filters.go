@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// bannedPhraseMatch returns the first part of the body that matches any of
+// the BannedPhrases, or false if none do.
+func (b botState) bannedPhraseMatch(body string) (string, bool) {
+	for _, re := range b.bannedPatterns {
+		if match := re.FindString(body); match != "" {
+			return match, true
+		}
+	}
+	return "", false
+}
+
+// compileBannedPhrases compiles the banned phrases of a config, once it has
+// been validated.
+func compileBannedPhrases(phrases []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(phrases))
+	for _, phrase := range phrases {
+		re, err := compileBannedPhrase(phrase)
+		if err != nil {
+			// This is caught when validating the config.
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// compileBannedPhrase compiles a banned phrase. Phrases are case-insensitive
+// regular expressions.
+func compileBannedPhrase(phrase string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + phrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// linkPattern matches the links in a body.
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>()\[\]"']+`)
+
+// isAllowedLink returns true if the link points to one of the
+// AllowedLinkDomains or their subdomains. Every link is allowed if there are
+// no AllowedLinkDomains.
+func (b botState) isAllowedLink(link string) bool {
+	if len(b.AllowedLinkDomains) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range b.AllowedLinkDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// disallowedLinks returns the links in the body that are not allowed.
+func (b botState) disallowedLinks(body string) []string {
+	var links []string
+	for _, link := range linkPattern.FindAllString(body, -1) {
+		if !b.isAllowedLink(link) {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// defangLinks rewrites the disallowed links in the body so that they can't
+// be clicked, such as "hxxps://example[.]com/path".
+func (b botState) defangLinks(body string) string {
+	return linkPattern.ReplaceAllStringFunc(body, func(link string) string {
+		if b.isAllowedLink(link) {
+			return link
+		}
+
+		scheme, rest, _ := strings.Cut(link, "://")
+		host, path, _ := strings.Cut(rest, "/")
+		if path != "" {
+			path = "/" + path
+		}
+
+		scheme = strings.Replace(strings.ToLower(scheme), "tt", "xx", 1)
+		host = strings.ReplaceAll(host, ".", "[.]")
+		return scheme + "://" + host + path
+	})
+}
+
+var (
+	// mentionPattern matches user and role mentions.
+	mentionPattern = regexp.MustCompile(`<@[!&]?\d+>`)
+	// massMentionPattern matches mentions that ping everyone.
+	massMentionPattern = regexp.MustCompile(`@(everyone|here)\b`)
+)
+
+// mentionProblem returns why the body has too many mentions, or false if it
+// doesn't. Any mention of everyone counts as too many.
+func (b botState) mentionProblem(body string) (string, bool) {
+	if match := massMentionPattern.FindString(body); match != "" {
+		return match, true
+	}
+
+	if b.MaxMentions > 0 {
+		if n := len(mentionPattern.FindAllString(body, -1)); n > b.MaxMentions {
+			return fmt.Sprintf("%d mentions", n), true
+		}
+	}
+
+	return "", false
+}
+
+// screenBody checks the announcement body against the content filters before
+// it is posted, returning the body to post and notes on what was changed in
+// it. If the body is refused, the author is told why and false is returned.
+func (h *commandHandler) screenBody(inv *invocation, opts announceOptions, body string) (string, []string, bool) {
+	if !h.screenTitle(inv, opts.Title) {
+		return "", nil, false
+	}
+
+	// Sanitize first, so that the other filters can't be dodged using
+	// invisible or look-alike characters.
+	body, notes := sanitizeBody(body)
+
+	if match, ok := h.bot.bannedPhraseMatch(body); ok {
+		sendRejection(h.session, inv, inv.textWith(msgBannedPhrase, replyData{Match: match}))
+		return "", nil, false
+	}
+
+	// Mass mentions are costly mistakes, so the author must confirm them.
+	if match, ok := h.bot.mentionProblem(body); ok && !opts.MassMention {
+		sendRejection(h.session, inv, inv.textWith(msgTooManyMentions, replyData{Match: match}))
+		return "", nil, false
+	}
+
+	if links := h.bot.disallowedLinks(body); len(links) > 0 {
+		if !h.bot.DefangLinks {
+			sendRejection(h.session, inv, inv.textWith(msgDisallowedLink, replyData{Match: links[0]}))
+			return "", nil, false
+		}
+		body = h.bot.defangLinks(body)
+	}
+
+	return body, notes, true
+}
+
+// screenTitle checks the title of the announcement against the content
+// filters. The title is only checked, not changed, so links that aren't
+// allowed are refused even if DefangLinks is set.
+func (h *commandHandler) screenTitle(inv *invocation, title string) bool {
+	if title == "" {
+		return true
+	}
+
+	title, _ = sanitizeBody(title)
+
+	if match, ok := h.bot.bannedPhraseMatch(title); ok {
+		sendRejection(h.session, inv, inv.textWith(msgBannedPhrase, replyData{Match: match}))
+		return false
+	}
+
+	if links := h.bot.disallowedLinks(title); len(links) > 0 {
+		sendRejection(h.session, inv, inv.textWith(msgDisallowedLink, replyData{Match: links[0]}))
+		return false
+	}
+
+	return true
+}
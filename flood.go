@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	// floodWindow is the window that command attempts are counted in.
+	floodWindow = time.Minute
+	// floodIgnoreTime is how long a flooding user is ignored for.
+	floodIgnoreTime = 10 * time.Minute
+)
+
+// floodGuard tracks command attempts per user, so that users who send too
+// many can be ignored for a while.
+type floodGuard struct {
+	attempts     map[discord.UserID][]time.Time
+	ignoredUntil map[discord.UserID]time.Time
+}
+
+func newFloodGuard() floodGuard {
+	return floodGuard{
+		attempts:     make(map[discord.UserID][]time.Time),
+		ignoredUntil: make(map[discord.UserID]time.Time),
+	}
+}
+
+// attempt records a command attempt by the user. It returns false if the user
+// should be ignored, and tripped is true if this attempt is the one that got
+// them ignored.
+func (g *floodGuard) attempt(userID discord.UserID, limit int, now time.Time) (ok, tripped bool) {
+	if until, ignored := g.ignoredUntil[userID]; ignored {
+		if now.Before(until) {
+			return false, false
+		}
+		delete(g.ignoredUntil, userID)
+	}
+
+	recent := g.attempts[userID][:0]
+	for _, t := range g.attempts[userID] {
+		if now.Sub(t) < floodWindow {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+
+	if len(recent) > limit {
+		delete(g.attempts, userID)
+		g.ignoredUntil[userID] = now.Add(floodIgnoreTime)
+		return false, true
+	}
+
+	g.attempts[userID] = recent
+	return true, false
+}
+
+// expire forgets the attempts that no longer count and the users who are no
+// longer ignored, so that users who stopped sending commands don't linger.
+func (g *floodGuard) expire(now time.Time) {
+	for userID, attempts := range g.attempts {
+		if len(attempts) == 0 || now.Sub(attempts[len(attempts)-1]) >= floodWindow {
+			delete(g.attempts, userID)
+		}
+	}
+	for userID, until := range g.ignoredUntil {
+		if !now.Before(until) {
+			delete(g.ignoredUntil, userID)
+		}
+	}
+}
+
+// admitCommand returns true if the invocation should be handled. Users who
+// send more than FloodLimit commands within a minute are warned once and then
+// ignored for a while.
+func (h *commandHandler) admitCommand(inv *invocation) bool {
+	if h.bot.FloodLimit <= 0 {
+		return true
+	}
+
+	ok, tripped := h.flood.attempt(inv.Author.ID, h.bot.FloodLimit, time.Now())
+	if ok {
+		return true
+	}
+
+	if tripped {
+		slog.Warn(
+			"Bot is ignoring a user who is sending too many commands.",
+			"author_id", inv.Author.ID,
+			"author.tag", inv.Author.Tag(),
+			"ignore_time", floodIgnoreTime)
+
+		sendRejection(h.session, inv, inv.textWith(msgFlooding, replyData{Remaining: floodIgnoreTime}))
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"libdb.so/persist"
+)
+
+// forgetConfirmTime is how long a user has to confirm the forget-me command.
+const forgetConfirmTime = 5 * time.Minute
+
+// pseudonymLimit bounds the IDs that are handed out as pseudonyms. Real
+// Discord IDs encode their creation time, which puts them far above it, so
+// the two never collide.
+const pseudonymLimit = 1 << 22
+
+// newPseudonym returns a random ID to replace a forgotten user's ID with.
+func newPseudonym() discord.UserID {
+	n, err := rand.Int(rand.Reader, big.NewInt(pseudonymLimit-1))
+	if err != nil {
+		panic(err)
+	}
+	return discord.UserID(n.Int64() + 1)
+}
+
+// isPseudonym returns true if the ID was given to a user who has been
+// forgotten.
+func isPseudonym(id discord.UserID) bool {
+	return id.IsValid() && id < pseudonymLimit
+}
+
+// forgetMe deletes what the bot remembers about the author. The first
+// invocation only explains what happens, and the author must repeat it with
+// the "confirm" argument within forgetConfirmTime to go through with it.
+//
+// The author's announcement records are kept for the audit, but their ID in
+// them is replaced with a pseudonym that is shared by all of their records.
+// Blocks are kept, so that forgetting can't be used to get around them.
+func (h *commandHandler) forgetMe(inv *invocation, command *parsedCommand) {
+	userID := inv.Author.ID
+
+	requested, ok := h.forgetRequests[userID]
+	if !ok || time.Since(requested) > forgetConfirmTime || !strings.EqualFold(command.Args, "confirm") {
+		h.forgetRequests[userID] = time.Now()
+		sendReply(h.session, inv, inv.textWith(msgForgetMeConfirm, replyData{Remaining: forgetConfirmTime}))
+		return
+	}
+	delete(h.forgetRequests, userID)
+
+	pseudonym := newPseudonym()
+	var failed bool
+
+	if err := h.lastSentAuthors.Delete(userID); err != nil {
+		slog.Error(
+			"Bot has failed to forget the last announcement of a user.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	records, err := pseudonymizeAuthor(h.announcements, userID, pseudonym,
+		func(record *announcementRecord) *discord.UserID { return &record.AuthorID })
+	if err != nil {
+		slog.Error(
+			"Bot has failed to pseudonymize the user's announcement records.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	entries, err := pseudonymizeAuthor(h.outbox, userID, pseudonym,
+		func(entry *outboxEntry) *discord.UserID { return &entry.AuthorID })
+	if err != nil {
+		slog.Error(
+			"Bot has failed to pseudonymize the user's outbox entries.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	staged, err := pseudonymizeAuthor(h.staged, userID, pseudonym,
+		func(staged *stagedAnnouncement) *discord.UserID { return &staged.AuthorID })
+	if err != nil {
+		slog.Error(
+			"Bot has failed to pseudonymize the user's staged announcements.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	scheduled, err := pseudonymizeAuthor(h.scheduled, userID, pseudonym,
+		func(s *scheduledAnnouncement) *discord.UserID { return &s.AuthorID })
+	if err != nil {
+		slog.Error(
+			"Bot has failed to pseudonymize the user's scheduled announcements.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	countdowns, err := pseudonymizeAuthor(h.countdowns, userID, pseudonym,
+		func(c *countdown) *discord.UserID { return &c.AuthorID })
+	if err != nil {
+		slog.Error(
+			"Bot has failed to pseudonymize the user's countdowns.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	templates, err := pseudonymizeAuthor(h.templates, userID, pseudonym,
+		func(t *announcementTemplate) *discord.UserID { return &t.AuthorID })
+	if err != nil {
+		slog.Error(
+			"Bot has failed to pseudonymize the user's templates.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	snippets, err := pseudonymizeAuthor(h.snippets, userID, pseudonym,
+		func(s *snippet) *discord.UserID { return &s.AuthorID })
+	if err != nil {
+		slog.Error(
+			"Bot has failed to pseudonymize the user's snippets.",
+			"ref", inv.errorRef(),
+			"err", err)
+		failed = true
+	}
+
+	for id, pending := range h.pending {
+		if pending.AuthorID == userID {
+			delete(h.pending, id)
+		}
+	}
+
+	delete(h.flood.attempts, userID)
+	delete(h.flood.ignoredUntil, userID)
+
+	if h.bot.Runtime.PausedBy == inv.Author.Mention() {
+		h.bot.Runtime.PausedBy = pseudonym.Mention()
+		h.saveRuntime()
+	}
+
+	slog.Info(
+		"Bot has forgotten a user at their request.",
+		"pseudonym", pseudonym,
+		"announcements", records,
+		"outbox_entries", entries,
+		"staged", staged,
+		"scheduled", scheduled,
+		"countdowns", countdowns,
+		"templates", templates,
+		"snippets", snippets)
+
+	if failed {
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	sendReply(h.session, inv, inv.text(msgForgotten))
+}
+
+// pseudonymizeAuthor replaces the user's ID with the pseudonym in every value
+// of the map whose author is the user. author returns where the value keeps
+// its author. The number of values changed is returned.
+func pseudonymizeAuthor[K comparable, V any](m persist.Map[K, V], userID, pseudonym discord.UserID, author func(*V) *discord.UserID) (int, error) {
+	var keys []K
+	m.All()(func(k K, v V) bool {
+		if *author(&v) == userID {
+			keys = append(keys, k)
+		}
+		return true
+	})
+
+	var errs []error
+	for _, k := range keys {
+		v, ok, err := m.Load(k)
+		if err == nil && ok {
+			*author(&v) = pseudonym
+			err = m.Store(k, v)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return len(keys), errors.Join(errs...)
+}
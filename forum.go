@@ -0,0 +1,119 @@
+package main
+
+import (
+	"log/slog"
+	"mime/multipart"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+// maxThreadNameLength is the maximum length of a thread's name.
+const maxThreadNameLength = 100
+
+// forumPostData is the body of a request that creates a forum post. The API
+// client doesn't know about forum posts, so this is sent as-is.
+type forumPostData struct {
+	Name        string          `json:"name"`
+	AppliedTags []discord.TagID `json:"applied_tags,omitempty"`
+	Message     struct {
+		Content         string               `json:"content,omitempty"`
+		Embeds          []discord.Embed      `json:"embeds,omitempty"`
+		Flags           discord.MessageFlags `json:"flags,omitempty"`
+		AllowedMentions *api.AllowedMentions `json:"allowed_mentions"`
+	} `json:"message"`
+
+	Files []sendpart.File `json:"-"`
+}
+
+func (data forumPostData) NeedsMultipart() bool {
+	return len(data.Files) > 0
+}
+
+func (data forumPostData) WriteMultipart(body *multipart.Writer) error {
+	return sendpart.Write(body, data, data.Files)
+}
+
+// forumPostName returns the name of the forum post for the announcement. It is
+// the title if there is one, or the first line of the body otherwise.
+func forumPostName(msg announcementMessage, opts announceOptions) string {
+	name := opts.Title
+	if name == "" {
+		for _, line := range strings.Split(msg.Content, "\n") {
+			// Skip the AnnounceRoleID ping.
+			if strings.HasPrefix(line, "<@&") && strings.HasSuffix(line, ">") {
+				continue
+			}
+			name = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if name != "" {
+				break
+			}
+		}
+	}
+	if name == "" && len(msg.Embeds) > 0 {
+		name = msg.Embeds[0].Title
+	}
+	if name == "" {
+		name = "Announcement"
+	}
+
+	if utf8.RuneCountInString(name) > maxThreadNameLength {
+		name = string([]rune(name)[:maxThreadNameLength-1]) + "…"
+	}
+	return name
+}
+
+// forumTags returns the IDs of the ForumTags that the forum has.
+func (s announcementSender) forumTags(forum *discord.Channel) []discord.TagID {
+	var tagIDs []discord.TagID
+	for _, name := range s.bot.ForumTags {
+		found := false
+		for _, tag := range forum.AvailableTags {
+			if strings.EqualFold(tag.Name, name) {
+				tagIDs = append(tagIDs, tag.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			slog.Warn(
+				"Bot could not find a configured tag in the forum. It will not be applied.",
+				"channel_id", forum.ID,
+				"tag", name)
+		}
+	}
+	return tagIDs
+}
+
+// sendForumPost creates a new post in the forum for the announcement. The
+// returned message is the post's starter message, whose ID is the same as the
+// post's.
+func (s announcementSender) sendForumPost(forum *discord.Channel, msg announcementMessage, opts announceOptions) (*discord.Message, error) {
+	var data forumPostData
+	data.Name = forumPostName(msg, opts)
+	data.AppliedTags = s.forumTags(forum)
+	data.Message.Content = msg.Content
+	data.Message.Embeds = msg.Embeds
+	data.Message.Flags = msg.Flags
+	data.Message.AllowedMentions = msg.Mentions
+	if data.Message.AllowedMentions == nil {
+		data.Message.AllowedMentions = &api.AllowedMentions{}
+	}
+
+	data.Files = uploadFiles(msg.Files)
+
+	var thread discord.Channel
+	err := sendpart.POST(s.session.Client.Client, data, &thread, api.EndpointChannels+forum.ID.String()+"/threads")
+	if err != nil {
+		return nil, err
+	}
+
+	return &discord.Message{
+		ID:        discord.MessageID(thread.ID),
+		ChannelID: thread.ID,
+		GuildID:   thread.GuildID,
+	}, nil
+}
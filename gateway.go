@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/ningen/v3"
+)
+
+// gatewayMonitor keeps track of the health of the gateway connection, so that
+// a bot that is connected but not receiving events can be told apart from a
+// healthy one.
+type gatewayMonitor struct {
+	session *ningen.State
+	// lastEvent is the time that the last event was received, in Unix
+	// nanoseconds. It is written by the gateway's goroutine.
+	lastEvent atomic.Int64
+}
+
+// gatewayStatus is a snapshot of the health of the gateway connection.
+type gatewayStatus struct {
+	// Alive is true if the gateway is connected or is reconnecting.
+	Alive bool
+	// SentBeat and EchoBeat are the times that the last heartbeat was sent
+	// and acknowledged.
+	SentBeat time.Time
+	EchoBeat time.Time
+	// Latency is the round-trip time of the last acknowledged heartbeat.
+	Latency time.Duration
+	// LastEvent is the time that the last event was received.
+	LastEvent time.Time
+}
+
+func newGatewayMonitor(session *ningen.State) *gatewayMonitor {
+	m := &gatewayMonitor{session: session}
+	session.AddSyncHandler(func(ev gateway.Event) {
+		m.lastEvent.Store(time.Now().UnixNano())
+	})
+	return m
+}
+
+// status returns the current health of the gateway connection.
+func (m *gatewayMonitor) status() gatewayStatus {
+	var status gatewayStatus
+	if nanos := m.lastEvent.Load(); nanos != 0 {
+		status.LastEvent = time.Unix(0, nanos)
+	}
+
+	status.Alive = m.session.GatewayIsAlive()
+	if g := m.session.Gateway(); g != nil {
+		status.SentBeat = g.SentBeat()
+		status.EchoBeat = g.EchoBeat()
+		// While a heartbeat is waiting to be acknowledged, the latency of
+		// the last one is unknown.
+		if !status.EchoBeat.Before(status.SentBeat) {
+			status.Latency = status.EchoBeat.Sub(status.SentBeat)
+		}
+	}
+
+	return status
+}
+
+// formatStatus formats the status of the bot as one line each.
+func (h *commandHandler) formatStatus() string {
+	status := h.gateway.status()
+
+	connection := "connected"
+	if !status.Alive {
+		connection = "disconnected"
+	}
+
+	heartbeat := "none yet"
+	if !status.SentBeat.IsZero() {
+		heartbeat = fmt.Sprintf("%s, sent %s",
+			status.Latency.Round(time.Millisecond),
+			timestampMarkup(status.SentBeat, timestampRelative))
+	}
+
+	lastEvent := "none yet"
+	if !status.LastEvent.IsZero() {
+		lastEvent = timestampMarkup(status.LastEvent, timestampRelative)
+	}
+
+	lines := []string{
+		"gateway: " + connection,
+		"heartbeat: " + heartbeat,
+		"last event: " + lastEvent,
+		"up since: " + timestampMarkup(h.stats.Started, timestampRelative),
+		fmt.Sprintf("handled: %d commands, %d announcements", h.stats.Commands, h.stats.Announcements),
+	}
+	if h.bot.Runtime.Paused {
+		lines = append(lines, "announcements are paused")
+	}
+
+	return formatBulletList(lines)
+}
+
+func (h *commandHandler) status(inv *invocation) {
+	sendReply(h.session, inv, inv.textWith(msgStatus, replyData{Report: h.formatStatus()}))
+}
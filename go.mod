@@ -5,7 +5,9 @@ go 1.22.0
 require (
 	github.com/diamondburned/arikawa/v3 v3.3.5
 	github.com/diamondburned/ningen/v3 v3.0.0
+	golang.org/x/crypto v0.17.0
 	golang.org/x/sync v0.1.0
+	golang.org/x/text v0.14.0
 	libdb.so/persist v0.0.0-20231219023831-5321494d3834
 )
 
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// heartbeatURLEnv is the environment variable that holds the URL that the
+// bot pings while it is healthy, such as a healthchecks.io check. If the pings
+// stop, the monitor knows that something is wrong.
+const heartbeatURLEnv = "HEARTBEAT_URL"
+
+// heartbeatInterval is how often the heartbeat URL is pinged.
+const heartbeatInterval = time.Minute
+
+// heartbeatProbe is sent to the event loop to check that it is still
+// handling events. The loop replies on it once it gets to it.
+type heartbeatProbe chan<- struct{}
+
+// sendHeartbeats pings the URL every heartbeatInterval, but only while the
+// gateway is healthy and the event loop answers probes, so that a bot that is
+// connected but stuck stops pinging too.
+func sendHeartbeats(ctx context.Context, url string, monitor *gatewayMonitor, probes chan<- heartbeatProbe) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if reason := checkHealth(ctx, monitor, probes); reason != "" {
+			slog.Warn(
+				"Bot is unhealthy, so it is skipping its heartbeat.",
+				"reason", reason)
+			continue
+		}
+
+		if err := pingHeartbeat(ctx, url); err != nil {
+			slog.Warn(
+				"Bot has failed to ping its heartbeat URL.",
+				"err", err)
+		}
+	}
+}
+
+// checkHealth returns why the bot is unhealthy, or an empty string if it is
+// healthy.
+func checkHealth(ctx context.Context, monitor *gatewayMonitor, probes chan<- heartbeatProbe) string {
+	status := monitor.status()
+	if !status.Alive {
+		return "the gateway is disconnected"
+	}
+	// Discord asks for heartbeats less than a minute apart, so anything older
+	// than two intervals means that the gateway has gone quiet.
+	if time.Since(status.lastSignOfLife()) > 2*heartbeatInterval {
+		return "the gateway has gone quiet"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, heartbeatInterval/2)
+	defer cancel()
+
+	// The reply is buffered so that a late answer doesn't block the loop.
+	reply := make(chan struct{}, 1)
+	select {
+	case probes <- reply:
+	case <-ctx.Done():
+		return "the event loop is not taking probes"
+	}
+	select {
+	case <-reply:
+	case <-ctx.Done():
+		return "the event loop has not answered the probe"
+	}
+
+	return ""
+}
+
+func pingHeartbeat(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// identifySettings configures how the bot identifies itself to the gateway.
+// The defaults match what Discord expects at the time of writing, so they
+// only need changing when Discord changes what it expects.
+type identifySettings struct {
+	// Capabilities are the client capability flags sent when identifying.
+	// They only matter for user accounts. The default of 253 was found by
+	// reverse-engineering Discord's own client.
+	Capabilities int
+	// Browser and Device are the client names reported to Discord.
+	Browser string
+	Device  string
+	// Intents are the gateway intents to ask for when running as a bot, such
+	// as ["GUILDS", "GUILD_MESSAGES", "MESSAGE_CONTENT"]. They are named as in
+	// Discord's documentation. If empty, the intents are worked out from the
+	// events that the bot handles.
+	Intents []string
+}
+
+// gatewayIntents maps the names used in Intents to their intents.
+var gatewayIntents = map[string]gateway.Intents{
+	"GUILDS":                    gateway.IntentGuilds,
+	"GUILD_MEMBERS":             gateway.IntentGuildMembers,
+	"GUILD_MODERATION":          gateway.IntentGuildModeration,
+	"GUILD_EMOJIS_AND_STICKERS": gateway.IntentGuildEmojis,
+	"GUILD_INTEGRATIONS":        gateway.IntentGuildIntegrations,
+	"GUILD_WEBHOOKS":            gateway.IntentGuildWebhooks,
+	"GUILD_INVITES":             gateway.IntentGuildInvites,
+	"GUILD_VOICE_STATES":        gateway.IntentGuildVoiceStates,
+	"GUILD_PRESENCES":           gateway.IntentGuildPresences,
+	"GUILD_MESSAGES":            gateway.IntentGuildMessages,
+	"GUILD_MESSAGE_REACTIONS":   gateway.IntentGuildMessageReactions,
+	"GUILD_MESSAGE_TYPING":      gateway.IntentGuildMessageTyping,
+	"DIRECT_MESSAGES":           gateway.IntentDirectMessages,
+	"DIRECT_MESSAGE_REACTIONS":  gateway.IntentDirectMessageReactions,
+	"DIRECT_MESSAGE_TYPING":     gateway.IntentDirectMessageTyping,
+	"MESSAGE_CONTENT":           gateway.IntentMessageContent,
+	"GUILD_SCHEDULED_EVENTS":    gateway.IntentGuildScheduledEvents,
+}
+
+// intents returns the configured intents combined, and false if none are
+// configured. Unknown names are rejected when the settings are loaded.
+func (s identifySettings) intents() (gateway.Intents, bool) {
+	var intents gateway.Intents
+	for _, name := range s.Intents {
+		intents |= gatewayIntents[name]
+	}
+	return intents, len(s.Intents) > 0
+}
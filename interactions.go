@@ -0,0 +1,568 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/ningen/v3"
+)
+
+// slashCommands is the list of slash commands that the bot registers in the
+// target guild. Slash commands are only available when the bot is running as
+// a bot account.
+var slashCommands = []api.CreateCommandData{
+	{
+		Name:        "announce",
+		Description: "Compose a new announcement.",
+	},
+	{
+		Name:        "poll",
+		Description: "Post a poll.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "question",
+				Description: "The question to ask.",
+				Required:    true,
+			},
+			&discord.StringOption{
+				OptionName:  "answers",
+				Description: "The answers to pick from, separated by |.",
+				Required:    true,
+			},
+			&discord.StringOption{
+				OptionName:  "duration",
+				Description: "How long the poll stays open, such as 12h or 3d.",
+			},
+			&discord.BooleanOption{
+				OptionName:  "multiselect",
+				Description: "Whether more than one answer may be picked.",
+			},
+		},
+	},
+	{
+		Name:        "stage",
+		Description: "Open the stage and announce it.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "topic",
+				Description: "What the stage is about.",
+				Required:    true,
+				MaxLength:   option.NewInt(maxStageTopicLength),
+			},
+			&discord.StringOption{
+				OptionName:  "body",
+				Description: "The announcement to post along with the stage link.",
+			},
+		},
+	},
+	{
+		Name:        "template",
+		Description: "Announce using a saved template, or list or delete templates.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "action",
+				Description: "What to do. Templates are saved using the message command.",
+				Required:    true,
+				Choices: []discord.StringChoice{
+					{Name: "Use", Value: "use"},
+					{Name: "List", Value: "list"},
+					{Name: "Delete", Value: "delete"},
+				},
+			},
+			&discord.StringOption{
+				OptionName:  "name",
+				Description: "The name of the template.",
+			},
+			&discord.StringOption{
+				OptionName:  "values",
+				Description: "The values to fill in, such as version: 1.2 | date: friday.",
+			},
+		},
+	},
+	{
+		Name:        "snippet",
+		Description: "Set, list or delete the snippets that announcements may use.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "action",
+				Description: "What to do.",
+				Required:    true,
+				Choices: []discord.StringChoice{
+					{Name: "Set", Value: "set"},
+					{Name: "List", Value: "list"},
+					{Name: "Delete", Value: "delete"},
+				},
+			},
+			&discord.StringOption{
+				OptionName:  "name",
+				Description: "The name of the snippet, such as footer for !footer.",
+			},
+			&discord.StringOption{
+				OptionName:  "text",
+				Description: "The text that the snippet expands to.",
+			},
+		},
+	},
+	{
+		Name:        "snooze",
+		Description: "Postpone a scheduled announcement or its deletion.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "message",
+				Description: "The number of the scheduled announcement, or the link to the announcement.",
+				Required:    true,
+			},
+			&discord.StringOption{
+				OptionName:  "by",
+				Description: "How long to postpone it by, such as 2h or 3d.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "get",
+		Description: "Link to an announcement by its number.",
+		Options: []discord.CommandOption{
+			&discord.IntegerOption{
+				OptionName:  "number",
+				Description: "The number of the announcement.",
+				Required:    true,
+				Min:         option.NewInt(1),
+			},
+		},
+	},
+	{
+		Name:        "search",
+		Description: "Search past announcements.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "query",
+				Description: "Words to look for, with from:@user, after:2024-01-01 and before:2024-06-01 to narrow it down.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "retract",
+		Description: "Replace an announcement with a notice that it was retracted.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "message",
+				Description: "The link to the announcement.",
+				Required:    true,
+			},
+			&discord.StringOption{
+				OptionName:  "reason",
+				Description: "Why the announcement is retracted.",
+			},
+		},
+	},
+	{
+		Name:        "countdown",
+		Description: "Post a countdown that updates itself.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "when",
+				Description: "When to count down to, such as 2024-06-01 18:00 (UTC) or 3d.",
+				Required:    true,
+			},
+			&discord.StringOption{
+				OptionName:  "topic",
+				Description: "What is being counted down to.",
+				Required:    true,
+			},
+			&discord.StringOption{
+				OptionName:  "final",
+				Description: "What the countdown reads once the time has come.",
+			},
+		},
+	},
+	{
+		Name:        "promote",
+		Description: "Post an announcement that is waiting in the staging channel.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "message",
+				Description: "The link to the staged announcement. Defaults to the latest one.",
+			},
+		},
+	},
+	{
+		Name:        "subscribe",
+		Description: "Get pinged for future announcements.",
+	},
+	{
+		Name:        "unsubscribe",
+		Description: "Stop getting pinged for announcements.",
+	},
+	{
+		Name:        "pause",
+		Description: "Pause announcements.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "reason",
+				Description: "Why announcements are paused.",
+			},
+		},
+	},
+	{
+		Name:        "resume",
+		Description: "Resume announcements.",
+	},
+	{
+		Name:        "block",
+		Description: "Stop a user from using the bot.",
+		Options: []discord.CommandOption{
+			&discord.UserOption{
+				OptionName:  "user",
+				Description: "The user to block.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "unblock",
+		Description: "Let a blocked user use the bot again.",
+		Options: []discord.CommandOption{
+			&discord.UserOption{
+				OptionName:  "user",
+				Description: "The user to unblock.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "lock",
+		Description: "Stop everyone from sending messages in a channel.",
+		Options: []discord.CommandOption{
+			&discord.ChannelOption{
+				OptionName:  "channel",
+				Description: "The channel to lock. Defaults to the announcement channel.",
+			},
+			&discord.StringOption{
+				OptionName:  "reason",
+				Description: "Why the channel is locked, for the audit log.",
+			},
+		},
+	},
+	{
+		Name:        "unlock",
+		Description: "Let everyone send messages in a locked channel again.",
+		Options: []discord.CommandOption{
+			&discord.ChannelOption{
+				OptionName:  "channel",
+				Description: "The channel to unlock. Defaults to the announcement channel.",
+			},
+		},
+	},
+	{
+		Name:        "stats",
+		Description: "Show who has announced how often.",
+		Options: []discord.CommandOption{
+			&discord.StringOption{
+				OptionName:  "window",
+				Description: "How far back to look.",
+				Choices: []discord.StringChoice{
+					{Name: "Past week", Value: "week"},
+					{Name: "Past month", Value: "month"},
+					{Name: "All time", Value: "all"},
+				},
+			},
+		},
+	},
+	{
+		Name:        "status",
+		Description: "Show whether the bot is healthy.",
+	},
+	{
+		Name:        "errors",
+		Description: "Show the errors that the bot has run into recently.",
+	},
+	{
+		Name:        "export-me",
+		Description: "Get everything that the bot stores about you in a DM.",
+	},
+	{
+		Name:        "forget-me",
+		Description: "Make the bot forget everything that it remembers about you.",
+		Options: []discord.CommandOption{
+			&discord.BooleanOption{
+				OptionName:  "confirm",
+				Description: "Whether you have read what this does and want to go ahead.",
+			},
+		},
+	},
+	{
+		Name:        "doctor",
+		Description: "Check that the bot has what it needs.",
+	},
+}
+
+// announceModalID is the custom ID of the announcement composer modal.
+const announceModalID = "announce"
+
+// announceModal is the data of the announcement composer modal. Each field is
+// looked up using its custom ID.
+type announceModal struct {
+	Title   string `discord:"title?"`
+	Body    string `discord:"body"`
+	Options string `discord:"options?"`
+}
+
+// registerCommands registers the slash commands in the target guild.
+func registerCommands(session *ningen.State, bot botState) {
+	_, err := session.BulkOverwriteGuildCommands(bot.AppID, bot.TargetGuildID, slashCommands)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to register its slash commands. They will not be available.",
+			"guild_id", bot.TargetGuildID,
+			"err", err)
+		return
+	}
+
+	slog.Info(
+		"Bot has registered its slash commands.",
+		"guild_id", bot.TargetGuildID)
+}
+
+// handleInteraction handles an incoming interaction. If the interaction
+// results in a command, then it is returned alongside its invocation so that
+// the caller can handle it. Otherwise, a nil command is returned.
+func (h *commandHandler) handleInteraction(ev *gateway.InteractionCreateEvent) (*invocation, *parsedCommand) {
+	// Only serve interactions from the target guild.
+	if ev.GuildID != h.bot.TargetGuildID || ev.Member == nil {
+		return nil, nil
+	}
+
+	locale := h.bot.Locale
+	if locale == "" {
+		locale = ev.GuildLocale
+	}
+	inv := newInteractionInvocation(&ev.InteractionEvent, locale)
+
+	if h.bot.isBlocked(inv.Author.ID) {
+		sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+		return nil, nil
+	}
+
+	perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, ev.Member)
+	if err != nil {
+		slog.Warn(
+			"Bot was unable to calculate the permissions of the interaction's author.",
+			"author_id", inv.Author.ID,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return nil, nil
+	}
+
+	// Slash commands are checked like their message counterparts. Everything
+	// else is part of the announce flow.
+	command := "announce"
+	if data, ok := ev.Data.(*discord.CommandInteraction); ok {
+		command = data.Name
+	}
+	allowed := canUse(*h.bot, command, ev.Member, perms)
+
+	if !allowed {
+		sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+		return nil, nil
+	}
+
+	switch data := ev.Data.(type) {
+	case *discord.CommandInteraction:
+		switch data.Name {
+		case "subscribe", "unsubscribe", "resume", "doctor", "status", "errors", "export-me":
+			return inv, &parsedCommand{Command: data.Name}
+		case "pause":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("reason").String(),
+			}
+		case "poll":
+			body := data.Options.Find("question").String() + "\n" +
+				strings.ReplaceAll(data.Options.Find("answers").String(), "|", "\n")
+
+			var options []string
+			if v := data.Options.Find("duration").String(); v != "" {
+				options = append(options, "duration: "+v)
+			}
+			if v, err := data.Options.Find("multiselect").BoolValue(); err == nil {
+				options = append(options, fmt.Sprintf("multiselect: %t", v))
+			}
+
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Body:    body,
+				Options: strings.Join(options, "\n"),
+			}
+		case "stage":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("topic").String(),
+				Body:    data.Options.Find("body").String(),
+			}
+		case "template":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("action").String() + " " + data.Options.Find("name").String(),
+				Body:    strings.ReplaceAll(data.Options.Find("values").String(), "|", "\n"),
+			}
+		case "snippet":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("action").String() + " " + data.Options.Find("name").String(),
+				Body:    data.Options.Find("text").String(),
+			}
+		case "snooze":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("message").String() + " " + data.Options.Find("by").String(),
+			}
+		case "get":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("number").String(),
+			}
+		case "search":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("query").String(),
+			}
+		case "retract":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("message").String() + " " + data.Options.Find("reason").String(),
+			}
+		case "countdown":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("when").String(),
+				Body:    data.Options.Find("topic").String() + "\n" + data.Options.Find("final").String(),
+			}
+		case "forget-me":
+			var args string
+			if confirm, err := data.Options.Find("confirm").BoolValue(); err == nil && confirm {
+				args = "confirm"
+			}
+			return inv, &parsedCommand{Command: data.Name, Args: args}
+		case "promote":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("message").String(),
+			}
+		case "stats":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("window").String(),
+			}
+		case "block", "unblock":
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    data.Options.Find("user").String(),
+			}
+		case "lock", "unlock":
+			// The reason goes after the channel, as in the message command.
+			channel := h.bot.TargetChannelID.Mention()
+			if id, err := data.Options.Find("channel").SnowflakeValue(); err == nil && id.IsValid() {
+				channel = discord.ChannelID(id).Mention()
+			}
+			return inv, &parsedCommand{
+				Command: data.Name,
+				Args:    channel + " " + data.Options.Find("reason").String(),
+			}
+		case "announce":
+			h.respondInteraction(inv, api.InteractionResponse{
+				Type: api.ModalResponse,
+				Data: &api.InteractionResponseData{
+					CustomID: option.NewNullableString(announceModalID),
+					Title:    option.NewNullableString(inv.text(msgModalTitle)),
+					Components: discord.ComponentsPtr(
+						&discord.TextInputComponent{
+							CustomID:     "title",
+							Label:        inv.text(msgModalTitleLabel),
+							Style:        discord.TextInputShortStyle,
+							LengthLimits: [2]int{0, 256},
+						},
+						&discord.TextInputComponent{
+							CustomID:     "body",
+							Label:        inv.text(msgModalBodyLabel),
+							Style:        discord.TextInputParagraphStyle,
+							Required:     true,
+							LengthLimits: [2]int{1, 2000},
+						},
+						&discord.TextInputComponent{
+							CustomID:    "options",
+							Label:       inv.text(msgModalOptionsLabel),
+							Style:       discord.TextInputParagraphStyle,
+							Placeholder: inv.text(msgModalOptionsHint),
+						},
+					),
+				},
+			})
+		}
+
+	case *discord.StringSelectInteraction:
+		if id, ok := strings.CutPrefix(string(data.CustomID), channelPickerPrefix); ok {
+			h.pickChannels(inv, id, data.Values)
+		}
+
+	case *discord.ModalInteraction:
+		switch data.CustomID {
+		case announceModalID:
+			var modal announceModal
+			if err := data.Components.Unmarshal(&modal); err != nil {
+				slog.Warn(
+					"Bot was unable to parse the announcement modal.",
+					"ref", inv.errorRef(),
+					"err", err)
+
+				replyInternalError(h.session, inv)
+				return nil, nil
+			}
+
+			options := modal.Options
+			if modal.Title != "" {
+				options = "title: " + modal.Title + "\n" + options
+			}
+
+			return inv, &parsedCommand{
+				Command: "announce",
+				Body:    modal.Body,
+				Options: options,
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// deferInteraction tells Discord that the reply to the interaction of the
+// given invocation comes later, such as after a slow download. Commands that
+// aren't interactions are left alone.
+func (h *commandHandler) deferInteraction(inv *invocation) {
+	if inv.Interaction == nil || inv.responded {
+		return
+	}
+	h.respondInteraction(inv, api.InteractionResponse{Type: api.DeferredMessageInteractionWithSource})
+}
+
+// respondInteraction responds to the interaction of the given invocation with
+// a custom response.
+func (h *commandHandler) respondInteraction(inv *invocation, resp api.InteractionResponse) {
+	if err := h.session.RespondInteraction(inv.Interaction.ID, inv.Interaction.Token, resp); err != nil {
+		slog.Error(
+			"Bot has failed to respond to an interaction.",
+			"channel_id", inv.ChannelID,
+			"author_id", inv.Author.ID,
+			"err", err)
+		return
+	}
+	inv.responded = true
+}
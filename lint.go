@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxContentLength is the maximum length of a message's content.
+const maxContentLength = 2000
+
+// maskedLinkPattern matches masked links, such as "[text](https://...)". The
+// closing parenthesis is optional so that unclosed links are caught too.
+var maskedLinkPattern = regexp.MustCompile(`\[([^\]\n]*)\]\(([^)\s]*)(\))?`)
+
+// lintAnnouncement looks for formatting mistakes in the rendered announcement
+// that Discord would not complain about, but that would render badly.
+func lintAnnouncement(msg announcementMessage) []string {
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	var fences int
+	for _, line := range strings.Split(msg.Content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fences++
+		}
+	}
+	if fences%2 != 0 {
+		warn("a code block is never closed")
+	}
+
+	texts := []string{msg.Content}
+	for _, embed := range msg.Embeds {
+		texts = append(texts, embed.Description)
+		for _, field := range embed.Fields {
+			texts = append(texts, field.Value)
+		}
+	}
+
+	for _, text := range texts {
+		for _, m := range maskedLinkPattern.FindAllStringSubmatch(text, -1) {
+			switch {
+			case m[3] == "":
+				warn("the masked link to %q is never closed", m[1])
+			case !strings.HasPrefix(m[2], "https://") && !strings.HasPrefix(m[2], "http://"):
+				warn("the masked link %q does not point to a web address", m[1])
+			case m[1] == "":
+				warn("the masked link to %s has no text", m[2])
+			}
+		}
+	}
+
+	if n := utf8.RuneCountInString(msg.Content); n > maxContentLength {
+		warn("the message is %d characters long, but Discord allows only %d", n, maxContentLength)
+	}
+
+	return warnings
+}
+
+// checkLint warns the author about formatting mistakes in the announcement
+// and refuses it, unless the author has confirmed it using the
+// "ignore-warnings" option. It returns true if the announcement may go ahead.
+func (h *commandHandler) checkLint(inv *invocation, opts announceOptions, msg announcementMessage) bool {
+	warnings := lintAnnouncement(msg)
+	if len(warnings) == 0 || opts.IgnoreWarnings {
+		return true
+	}
+
+	sendRejection(h.session, inv, inv.textWith(msgLintWarnings, replyData{Report: formatBulletList(warnings)}))
+	return false
+}
+
+// formatBulletList formats the lines as a bulleted list.
+func formatBulletList(lines []string) string {
+	return "- " + strings.Join(lines, "\n- ")
+}
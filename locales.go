@@ -0,0 +1,543 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// messageKey identifies a user-facing message in the message catalog.
+type messageKey string
+
+const (
+	msgCooldown           messageKey = "cooldown"
+	msgInvalidOptions     messageKey = "invalid-options"
+	msgChannelNotAllowed  messageKey = "channel-not-allowed"
+	msgNotAuthorized      messageKey = "not-authorized"
+	msgInternalError      messageKey = "internal-error"
+	msgAnnounced          messageKey = "announced"
+	msgPartiallyAnnounced messageKey = "partially-announced"
+	msgLastSentNotFound   messageKey = "last-sent-not-found"
+	msgPickChannels       messageKey = "pick-channels"
+	msgPickerPlaceholder  messageKey = "picker-placeholder"
+	msgNoLongerPending    messageKey = "no-longer-pending"
+	msgNotPendingAuthor   messageKey = "not-pending-author"
+	msgInvalidPick        messageKey = "invalid-pick"
+	msgModalTitle         messageKey = "modal-title"
+	msgModalTitleLabel    messageKey = "modal-title-label"
+	msgModalBodyLabel     messageKey = "modal-body-label"
+	msgModalOptionsLabel  messageKey = "modal-options-label"
+	msgModalOptionsHint   messageKey = "modal-options-hint"
+	msgReceipt            messageKey = "receipt"
+	msgNoAnnounceRole     messageKey = "no-announce-role"
+	msgSubscribed         messageKey = "subscribed"
+	msgUnsubscribed       messageKey = "unsubscribed"
+	msgSubscribePrompt    messageKey = "subscribe-prompt"
+	msgInvalidEmbed       messageKey = "invalid-embed"
+	msgPaused             messageKey = "paused"
+	msgPausedNow          messageKey = "paused-now"
+	msgResumed            messageKey = "resumed"
+	msgDuplicate          messageKey = "duplicate"
+	msgDoctor             messageKey = "doctor"
+	msgRoleDeleted        messageKey = "role-deleted"
+	msgRoleRenamed        messageKey = "role-renamed"
+	msgCooldownExempted   messageKey = "cooldown-exempted"
+	msgInvalidUser        messageKey = "invalid-user"
+	msgCannotBlockSelf    messageKey = "cannot-block-self"
+	msgBlockedByConfig    messageKey = "blocked-by-config"
+	msgBlocked            messageKey = "blocked"
+	msgUnblocked          messageKey = "unblocked"
+	msgFlooding           messageKey = "flooding"
+	msgBannedPhrase       messageKey = "banned-phrase"
+	msgDisallowedLink     messageKey = "disallowed-link"
+	msgTooManyMentions    messageKey = "too-many-mentions"
+	msgLintWarnings       messageKey = "lint-warnings"
+	msgSanitized          messageKey = "sanitized"
+	msgInvalidPoll        messageKey = "invalid-poll"
+	msgPolled             messageKey = "polled"
+	msgNoStageChannel     messageKey = "no-stage-channel"
+	msgInvalidStageTopic  messageKey = "invalid-stage-topic"
+	msgStageLive          messageKey = "stage-live"
+	msgStats              messageKey = "stats"
+	msgNoStats            messageKey = "no-stats"
+	msgInvalidStatsWindow messageKey = "invalid-stats-window"
+	msgReachReport        messageKey = "reach-report"
+	msgStatus             messageKey = "status"
+	msgErrors             messageKey = "errors"
+	msgNoErrors           messageKey = "no-errors"
+	msgDraining           messageKey = "draining"
+	msgPendingUpdated     messageKey = "pending-updated"
+	msgRemovalAlert       messageKey = "removal-alert"
+	msgRemovalNotice      messageKey = "removal-notice"
+	msgOutOfBandEdit      messageKey = "out-of-band-edit"
+	msgOutage             messageKey = "outage"
+	msgOutageOver         messageKey = "outage-over"
+	msgStaged             messageKey = "staged"
+	msgNoStagingChannel   messageKey = "no-staging-channel"
+	msgInvalidMessage     messageKey = "invalid-message"
+	msgNotStaged          messageKey = "not-staged"
+	msgExportDM           messageKey = "export-dm"
+	msgExported           messageKey = "exported"
+	msgExportNotSent      messageKey = "export-not-sent"
+	msgForgetMeConfirm    messageKey = "forget-me-confirm"
+	msgForgotten          messageKey = "forgotten"
+	msgRateLimited        messageKey = "rate-limited"
+	msgEditPrompt         messageKey = "edit-prompt"
+	msgEditCancelled      messageKey = "edit-cancelled"
+	msgEditApplied        messageKey = "edit-applied"
+	msgDigest             messageKey = "digest"
+	msgDigestMore         messageKey = "digest-more"
+	msgCountdown          messageKey = "countdown"
+	msgCountdownDone      messageKey = "countdown-done"
+	msgCountdownDays      messageKey = "countdown-days"
+	msgCountdownHours     messageKey = "countdown-hours"
+	msgCountdownMinutes   messageKey = "countdown-minutes"
+	msgCountdownPosted    messageKey = "countdown-posted"
+	msgInvalidCountdown   messageKey = "invalid-countdown"
+	msgInvalidTime        messageKey = "invalid-time"
+	msgTemplates          messageKey = "templates"
+	msgNoTemplates        messageKey = "no-templates"
+	msgTemplateSaved      messageKey = "template-saved"
+	msgTemplateDeleted    messageKey = "template-deleted"
+	msgTemplateNotFound   messageKey = "template-not-found"
+	msgInvalidTemplate    messageKey = "invalid-template"
+	msgSnippets           messageKey = "snippets"
+	msgNoSnippets         messageKey = "no-snippets"
+	msgSnippetSaved       messageKey = "snippet-saved"
+	msgSnippetDeleted     messageKey = "snippet-deleted"
+	msgSnippetNotFound    messageKey = "snippet-not-found"
+	msgInvalidSnippet     messageKey = "invalid-snippet"
+	msgTTSNotAllowed      messageKey = "tts-not-allowed"
+	msgSnoozed            messageKey = "snoozed"
+	msgNotScheduled       messageKey = "not-scheduled"
+	msgInvalidSnooze      messageKey = "invalid-snooze"
+	msgScheduled          messageKey = "scheduled"
+	msgSendSnoozed        messageKey = "send-snoozed"
+	msgSnoozedPastDelete  messageKey = "snoozed-past-delete"
+	msgScheduledSent      messageKey = "scheduled-sent"
+	msgBump               messageKey = "bump"
+	msgInvalidChannel     messageKey = "invalid-channel"
+	msgLocked             messageKey = "locked"
+	msgUnlocked           messageKey = "unlocked"
+	msgNotApprover        messageKey = "not-approver"
+	msgCannotApproveOwn   messageKey = "cannot-approve-own"
+	msgAlreadyApproved    messageKey = "already-approved"
+	msgApprovalNoted      messageKey = "approval-noted"
+	msgApprovalAudit      messageKey = "approval-audit"
+	msgSignedOffAudit     messageKey = "signed-off-audit"
+	msgEditNeedsApproval  messageKey = "edit-needs-approval"
+	msgBadSignature       messageKey = "bad-signature"
+	msgSignatureAudit     messageKey = "signature-audit"
+	msgSignedBodyChanged  messageKey = "signed-body-changed"
+	msgEditWindowClosed   messageKey = "edit-window-closed"
+	msgEditedFooter       messageKey = "edited-footer"
+	msgEditDiff           messageKey = "edit-diff"
+	msgEditDiffAttached   messageKey = "edit-diff-attached"
+	msgNotAnnouncement    messageKey = "not-announcement"
+	msgRetractedNotice    messageKey = "retracted-notice"
+	msgRetracted          messageKey = "retracted"
+	msgAnnouncementNumber messageKey = "announcement-number"
+	msgInvalidNumber      messageKey = "invalid-number"
+	msgNumberNotFound     messageKey = "number-not-found"
+	msgNumbered           messageKey = "numbered"
+	msgInvalidSearch      messageKey = "invalid-search"
+	msgNoSearchResults    messageKey = "no-search-results"
+	msgSearchResults      messageKey = "search-results"
+	msgAuditAnchor        messageKey = "audit-anchor"
+	msgAttachmentFailed   messageKey = "attachment-failed"
+)
+
+// defaultLocale is the locale used when no other locale has the message.
+const defaultLocale = "en"
+
+// messageCatalog maps each locale to its messages. Messages are text/template
+// templates executed with replyData. Replies are prefixed with a mention of the
+// author, so they are written to follow one.
+var messageCatalog = map[string]map[messageKey]string{
+	"en": {
+		msgCooldown:           "please wait before sending another announcement.",
+		msgInvalidOptions:     "the announcement options are invalid: {{.Error}}.",
+		msgChannelNotAllowed:  "{{.Channel}} is not an announcement channel.",
+		msgNotAuthorized:      "you are not allowed to use this bot.",
+		msgInternalError:      "this bot has encountered an internal error. This error has been logged. (error ref: {{.Ref}})",
+		msgAnnounced:          "the announcement has been sent: {{.Link}}",
+		msgPartiallyAnnounced: "the announcement has been sent, but some channels could not be posted in. This error has been logged. {{.Link}}",
+		msgLastSentNotFound:   "this bot could not find the last announcement you sent.",
+		msgPickChannels:       "pick the channels to post this announcement in.",
+		msgPickerPlaceholder:  "Pick the channels to announce in",
+		msgNoLongerPending:    "this announcement is no longer pending.",
+		msgNotPendingAuthor:   "only the author of this announcement may pick its channels.",
+		msgInvalidPick:        "the picked channel is invalid.",
+		msgModalTitle:         "New Announcement",
+		msgModalTitleLabel:    "Title",
+		msgModalBodyLabel:     "Body",
+		msgModalOptionsLabel:  "Options",
+		msgModalOptionsHint:   "key: value, one per line",
+		msgReceipt:            "Your announcement has been posted: {{.Link}}\nYou may post another announcement {{.CooldownEnds}}.",
+		msgNoAnnounceRole:     "this bot has no notification role to give.",
+		msgSubscribed:         "you will now be pinged for announcements.",
+		msgUnsubscribed:       "you will no longer be pinged for announcements.",
+		msgSubscribePrompt:    "React with 🔔 to get pinged for future announcements.",
+		msgInvalidEmbed:       "the announcement has an invalid embed: {{.Error}}.",
+		msgPaused:             "announcements are paused: {{or .Reason \"no reason given\"}}",
+		msgPausedNow:          "announcements are now paused.",
+		msgResumed:            "announcements are no longer paused.",
+		msgDuplicate:          "this announcement has already been sent.",
+		msgDoctor:             "this is what the bot found:\n{{.Report}}",
+		msgRoleDeleted:        "⚠️ The role {{.Role}} has been deleted, but {{.Setting}} still refers to it. Update the bot's config.",
+		msgRoleRenamed:        "⚠️ The role {{.OldRole}} in {{.Setting}} has been renamed to {{.Role}}.",
+		msgCooldownExempted:   "{{.Author}} has announced {{.Remaining}} before the cooldown ended, since they are exempt from it.",
+		msgInvalidUser:        "the user is invalid: {{.Error}}.",
+		msgCannotBlockSelf:    "you cannot block yourself.",
+		msgBlockedByConfig:    "{{.Target}} is blocked by the bot's config, so they can only be unblocked there.",
+		msgBlocked:            "{{.Target}} can no longer use this bot.",
+		msgUnblocked:          "{{.Target}} can use this bot again.",
+		msgFlooding:           "you are sending too many commands. This bot will ignore you for {{.Remaining}}.",
+		msgBannedPhrase:       "the announcement contains \"{{.Match}}\", which is not allowed in announcements.",
+		msgDisallowedLink:     "the announcement links to <{{.Match}}>, which is not on an allowed domain.",
+		msgTooManyMentions:    "the announcement has `{{.Match}}`. If that is intended, send it again with the `mass-mention: true` option.",
+		msgLintWarnings:       "the announcement may not render as intended:\n{{.Report}}\nFix it, or send it again with the `ignore-warnings: true` option.",
+		msgSanitized:          "The bot has cleaned up the announcement:\n{{.Report}}",
+		msgInvalidPoll:        "the poll is invalid: {{.Error}}.",
+		msgPolled:             "the poll has been posted: {{.Link}}",
+		msgNoStageChannel:     "this bot has no stage channel to open.",
+		msgInvalidStageTopic:  "the stage needs a topic of at most 120 characters.",
+		msgStageLive:          "🎙️ **{{.Topic}}** is live now in {{.Channel}}!",
+		msgStats:              "these are the announcements {{if eq .Window \"week\"}}of the past week{{else if eq .Window \"month\"}}of the past month{{else}}of all time{{end}}:\n{{.Report}}",
+		msgNoStats:            "no announcements have been posted {{if eq .Window \"week\"}}in the past week{{else if eq .Window \"month\"}}in the past month{{else}}yet{{end}}.",
+		msgInvalidStatsWindow: "the window must be `week`, `month` or `all`.",
+		msgReachReport:        "📊 This is how far {{.Link}} reached in its first day:\n{{.Report}}",
+		msgStatus:             "this is how the bot is doing:\n{{.Report}}",
+		msgErrors:             "these are the most recent errors:\n{{.Report}}",
+		msgNoErrors:           "the bot has not run into any errors since it started.",
+		msgRemovalAlert:       "🗑️ {{if .Target}}{{.Target}} has deleted an announcement by {{.Author}}{{else}}An announcement by {{.Author}} has been deleted{{end}} in {{.Channel}}.",
+		msgOutage:             "🚨 The announcement bot has been unable to reach Discord for {{.Duration}}.",
+		msgOutageOver:         "✅ The announcement bot can reach Discord again.",
+		msgOutOfBandEdit:      "✏️ {{.Link}} has been edited outside of the bot. The new version has been recorded.",
+		msgRemovalNotice:      "Your announcement in {{.Channel}} has been deleted by a moderator.",
+		msgPendingUpdated:     "your announcement has been updated with your edit. Pick the channels to post it in above.",
+		msgStaged:             "your announcement is waiting for review: {{.Link}}",
+		msgNoStagingChannel:   "this bot has no staging channel, so there is nothing to promote.",
+		msgInvalidMessage:     "that is not a message link: {{.Error}}",
+		msgNotStaged:          "that announcement is not waiting for review.",
+		msgExportDM:           "This is everything that the bot stores about you.",
+		msgExported:           "the bot has sent you everything that it stores about you in a DM.",
+		msgExportNotSent:      "the bot could not DM you. Please allow DMs from server members and try again.",
+		msgForgetMeConfirm:    "this makes the bot forget everything that it remembers about you. Your announcements stay up, but they are no longer linked to you, so you can no longer edit them. Use the export-me command first if you want a copy. To go ahead, use the forget-me command again with `confirm` within {{.Remaining}}.",
+		msgForgotten:          "the bot has forgotten you.",
+		msgDraining:           "the bot is about to restart, so it is not taking new announcements. Try again in a bit.",
+		msgRateLimited:        "Discord is rate limiting the bot; retrying in {{.Duration}}.",
+		msgEditPrompt:         "Reply with the new body of your announcement {{.Link}} within {{.Remaining}} to edit it, or with `cancel` to leave it as it is. It currently reads:",
+		msgEditCancelled:      "your announcement has been left as it is.",
+		msgEditApplied:        "your announcement has been edited.",
+		msgDigest:             "📰 **Announcements of the past week**\n{{.Report}}",
+		msgDigestMore:         "…and {{.Count}} more.",
+		msgCountdown:          "⏳ **{{.Topic}}** in {{.Left}} ({{.When}})",
+		msgCountdownDone:      "🎉 **{{.Topic}}** is here!",
+		msgCountdownDays:      "{{.Count}} {{if eq .Count 1}}day{{else}}days{{end}}",
+		msgCountdownHours:     "{{.Count}} {{if eq .Count 1}}hour{{else}}hours{{end}}",
+		msgCountdownMinutes:   "{{.Count}} {{if eq .Count 1}}minute{{else}}minutes{{end}}",
+		msgCountdownPosted:    "your countdown has been posted: {{.Link}}",
+		msgInvalidCountdown:   "the countdown is invalid: {{.Error}}.",
+		msgInvalidTime:        "the announcement has an invalid time: {{.Error}}.",
+		msgTemplates:          "the saved templates and what they need filled in are:\n{{.Report}}",
+		msgNoTemplates:        "no templates have been saved yet.",
+		msgTemplateSaved:      "the template `{{.Name}}` has been saved.",
+		msgTemplateDeleted:    "the template `{{.Name}}` has been deleted.",
+		msgTemplateNotFound:   "there is no template called `{{.Name}}`.",
+		msgInvalidTemplate:    "the template command is invalid: {{.Error}}.",
+		msgSnippets:           "the snippets that announcements may use are:\n{{.Report}}",
+		msgNoSnippets:         "no snippets have been set yet.",
+		msgSnippetSaved:       "the snippet `!{{.Name}}` has been set.",
+		msgSnippetDeleted:     "the snippet `!{{.Name}}` has been deleted.",
+		msgSnippetNotFound:    "there is no snippet called `!{{.Name}}`.",
+		msgInvalidSnippet:     "the snippet command is invalid: {{.Error}}.",
+		msgTTSNotAllowed:      "you are not allowed to post text-to-speech announcements.",
+		msgSnoozed:            "the announcement will now be deleted {{.When}}.",
+		msgNotScheduled:       "that announcement isn't scheduled to be sent or deleted.",
+		msgInvalidSnooze:      "the snooze is invalid: {{.Error}}.",
+		msgScheduled:          "the announcement will be sent {{.When}}. Use `snooze {{.Count}} <duration>` to postpone it.",
+		msgSendSnoozed:        "the announcement will now be sent {{.When}}.",
+		msgSnoozedPastDelete:  "the announcement would be sent after it is due to be deleted.",
+		msgScheduledSent:      "Your scheduled announcement has been sent: {{.Link}}",
+		msgBump:               "📣 In case you missed it: {{.Link}}",
+		msgInvalidChannel:     "the channel is invalid: {{.Error}}.",
+		msgLocked:             "{{.Channel}} is now locked. Only those with their own permission can send messages there.",
+		msgUnlocked:           "{{.Channel}} is unlocked again.",
+		msgNotApprover:        "this announcement needs the sign-off of two approvers, and you are not one.",
+		msgCannotApproveOwn:   "you can't approve your own announcement.",
+		msgAlreadyApproved:    "you have already approved this announcement. It needs another approver.",
+		msgApprovalNoted:      "your approval is noted. The announcement needs {{.Count}} more before it is posted.",
+		msgApprovalAudit:      "{{.Author}} has approved the high-impact announcement {{.Link}}. It needs {{.Count}} more approval before it is posted.",
+		msgSignedOffAudit:     "{{.Author}} has given the last approval of the high-impact announcement {{.Link}}, so it is being posted.",
+		msgEditNeedsApproval:  "this edit would make the announcement critical or ping everyone, which needs the sign-off of approvers. Post it as a new announcement instead.",
+		msgBadSignature:       "this bot only posts signed announcements, so end the body with its minisign signature: {{.Error}}.",
+		msgSignatureAudit:     "{{.Author}} has tried to post an announcement without a valid signature: {{.Error}}.",
+		msgSignedBodyChanged:  "the content filters would change the signed body, so sign it as it should be posted instead.",
+		msgEditWindowClosed:   "that announcement was posted more than {{.Duration}} ago, so only admins may change it now.",
+		msgEditedFooter:       "-# Last edited by {{.Author}} {{.When}}",
+		msgEditDiff:           "{{.Author}} has edited {{.Link}}:\n```diff\n{{.Report}}\n```",
+		msgEditDiffAttached:   "{{.Author}} has edited {{.Link}}. The changes are attached.",
+		msgNotAnnouncement:    "that message isn't an announcement that this bot has a record of.",
+		msgRetractedNotice:    "*This announcement was retracted by {{.Author}}{{if .Reason}}: {{.Reason}}{{else}}.{{end}}*",
+		msgRetracted:          "the announcement has been retracted: {{.Link}}",
+		msgAnnouncementNumber: "-# Announcement #{{.Count}}",
+		msgInvalidNumber:      "that is not an announcement number: {{.Error}}.",
+		msgNumberNotFound:     "there is no announcement #{{.Count}}.",
+		msgNumbered:           "announcement #{{.Count}}: {{.Link}}",
+		msgInvalidSearch:      "that search is invalid: {{.Error}}.",
+		msgNoSearchResults:    "no announcements match that search.",
+		msgSearchResults:      "{{.Count}} announcements match{{if gt .Count 10}}, the latest 10 of which are{{end}}:\n{{.Report}}",
+		msgAuditAnchor:        "-# Audit log entry {{.Count}}: `{{.Hash}}`",
+		msgAttachmentFailed:   "the attachments could not be downloaded: {{.Error}}.",
+	},
+	"de": {
+		msgCooldown:           "bitte warte, bevor du eine weitere Ankündigung sendest.",
+		msgInvalidOptions:     "die Optionen der Ankündigung sind ungültig: {{.Error}}.",
+		msgChannelNotAllowed:  "{{.Channel}} ist kein Ankündigungskanal.",
+		msgNotAuthorized:      "du darfst diesen Bot nicht verwenden.",
+		msgInternalError:      "bei diesem Bot ist ein interner Fehler aufgetreten. Der Fehler wurde protokolliert. (Fehlerreferenz: {{.Ref}})",
+		msgAnnounced:          "die Ankündigung wurde gesendet: {{.Link}}",
+		msgPartiallyAnnounced: "die Ankündigung wurde gesendet, aber in einigen Kanälen konnte nicht gepostet werden. Der Fehler wurde protokolliert. {{.Link}}",
+		msgLastSentNotFound:   "dieser Bot konnte deine letzte Ankündigung nicht finden.",
+		msgPickChannels:       "wähle die Kanäle, in denen diese Ankündigung gepostet werden soll.",
+		msgPickerPlaceholder:  "Kanäle für die Ankündigung wählen",
+		msgNoLongerPending:    "diese Ankündigung steht nicht mehr aus.",
+		msgNotPendingAuthor:   "nur der Verfasser dieser Ankündigung darf ihre Kanäle wählen.",
+		msgInvalidPick:        "der gewählte Kanal ist ungültig.",
+		msgModalTitle:         "Neue Ankündigung",
+		msgModalTitleLabel:    "Titel",
+		msgModalBodyLabel:     "Text",
+		msgModalOptionsLabel:  "Optionen",
+		msgModalOptionsHint:   "schlüssel: wert, eine pro Zeile",
+		msgReceipt:            "Deine Ankündigung wurde gepostet: {{.Link}}\nDu kannst {{.CooldownEnds}} eine weitere Ankündigung posten.",
+		msgNoAnnounceRole:     "dieser Bot hat keine Benachrichtigungsrolle.",
+		msgSubscribed:         "du wirst nun bei Ankündigungen gepingt.",
+		msgUnsubscribed:       "du wirst nicht mehr bei Ankündigungen gepingt.",
+		msgSubscribePrompt:    "Reagiere mit 🔔, um bei zukünftigen Ankündigungen gepingt zu werden.",
+		msgInvalidEmbed:       "die Ankündigung hat ein ungültiges Embed: {{.Error}}.",
+		msgPaused:             "Ankündigungen sind pausiert: {{or .Reason \"kein Grund angegeben\"}}",
+		msgPausedNow:          "Ankündigungen sind nun pausiert.",
+		msgResumed:            "Ankündigungen sind nicht mehr pausiert.",
+		msgDuplicate:          "diese Ankündigung wurde bereits gesendet.",
+		msgDoctor:             "das hat der Bot herausgefunden:\n{{.Report}}",
+		msgRoleDeleted:        "⚠️ Die Rolle {{.Role}} wurde gelöscht, aber {{.Setting}} verweist noch auf sie. Passe die Konfiguration des Bots an.",
+		msgRoleRenamed:        "⚠️ Die Rolle {{.OldRole}} in {{.Setting}} wurde in {{.Role}} umbenannt.",
+		msgCooldownExempted:   "{{.Author}} hat {{.Remaining}} vor Ende der Wartezeit angekündigt, da die Wartezeit für sie nicht gilt.",
+		msgInvalidUser:        "der Benutzer ist ungültig: {{.Error}}.",
+		msgCannotBlockSelf:    "du kannst dich nicht selbst sperren.",
+		msgBlockedByConfig:    "{{.Target}} ist durch die Konfiguration des Bots gesperrt und kann nur dort entsperrt werden.",
+		msgBlocked:            "{{.Target}} kann diesen Bot nicht mehr verwenden.",
+		msgUnblocked:          "{{.Target}} kann diesen Bot wieder verwenden.",
+		msgFlooding:           "du sendest zu viele Befehle. Dieser Bot ignoriert dich für {{.Remaining}}.",
+		msgBannedPhrase:       "die Ankündigung enthält \"{{.Match}}\", was in Ankündigungen nicht erlaubt ist.",
+		msgDisallowedLink:     "die Ankündigung verlinkt auf <{{.Match}}>, das nicht auf einer erlaubten Domain liegt.",
+		msgTooManyMentions:    "die Ankündigung enthält `{{.Match}}`. Falls das gewollt ist, sende sie erneut mit der Option `mass-mention: true`.",
+		msgLintWarnings:       "die Ankündigung wird eventuell nicht wie gewollt dargestellt:\n{{.Report}}\nBehebe das, oder sende sie erneut mit der Option `ignore-warnings: true`.",
+		msgSanitized:          "Der Bot hat die Ankündigung bereinigt:\n{{.Report}}",
+		msgInvalidPoll:        "die Umfrage ist ungültig: {{.Error}}.",
+		msgPolled:             "die Umfrage wurde gepostet: {{.Link}}",
+		msgNoStageChannel:     "dieser Bot hat keinen Stage-Kanal zum Öffnen.",
+		msgInvalidStageTopic:  "die Stage braucht ein Thema mit höchstens 120 Zeichen.",
+		msgStageLive:          "🎙️ **{{.Topic}}** ist jetzt live in {{.Channel}}!",
+		msgStats:              "das sind die Ankündigungen {{if eq .Window \"week\"}}der letzten Woche{{else if eq .Window \"month\"}}des letzten Monats{{else}}aller Zeiten{{end}}:\n{{.Report}}",
+		msgNoStats:            "es wurden {{if eq .Window \"week\"}}in der letzten Woche{{else if eq .Window \"month\"}}im letzten Monat{{else}}bisher{{end}} keine Ankündigungen gepostet.",
+		msgInvalidStatsWindow: "der Zeitraum muss `week`, `month` oder `all` sein.",
+		msgReachReport:        "📊 Die Reichweite von {{.Link}} an ihrem ersten Tag:\n{{.Report}}",
+		msgStatus:             "so geht es dem Bot:\n{{.Report}}",
+		msgErrors:             "das sind die letzten Fehler:\n{{.Report}}",
+		msgNoErrors:           "der Bot hatte seit seinem Start keine Fehler.",
+		msgRemovalAlert:       "🗑️ {{if .Target}}{{.Target}} hat eine Ankündigung von {{.Author}}{{else}}Eine Ankündigung von {{.Author}} wurde{{end}} in {{.Channel}} gelöscht.",
+		msgOutage:             "🚨 Der Ankündigungsbot kann Discord seit {{.Duration}} nicht erreichen.",
+		msgOutageOver:         "✅ Der Ankündigungsbot kann Discord wieder erreichen.",
+		msgOutOfBandEdit:      "✏️ {{.Link}} wurde außerhalb des Bots bearbeitet. Die neue Version wurde festgehalten.",
+		msgRemovalNotice:      "Deine Ankündigung in {{.Channel}} wurde von einem Moderator gelöscht.",
+		msgPendingUpdated:     "deine Ankündigung wurde mit deiner Bearbeitung aktualisiert. Wähle oben die Kanäle aus, in denen sie gepostet werden soll.",
+		msgStaged:             "deine Ankündigung wartet auf Prüfung: {{.Link}}",
+		msgNoStagingChannel:   "dieser Bot hat keinen Staging-Kanal, also gibt es nichts freizugeben.",
+		msgInvalidMessage:     "das ist kein Nachrichtenlink: {{.Error}}",
+		msgNotStaged:          "diese Ankündigung wartet nicht auf Prüfung.",
+		msgExportDM:           "Das ist alles, was der Bot über dich speichert.",
+		msgExported:           "der Bot hat dir alles, was er über dich speichert, per DM geschickt.",
+		msgExportNotSent:      "der Bot konnte dir keine DM schicken. Bitte erlaube DMs von Servermitgliedern und versuche es erneut.",
+		msgForgetMeConfirm:    "damit vergisst der Bot alles, was er über dich weiß. Deine Ankündigungen bleiben stehen, sind aber nicht mehr mit dir verknüpft, sodass du sie nicht mehr bearbeiten kannst. Verwende zuerst den Befehl export-me, wenn du eine Kopie möchtest. Um fortzufahren, verwende den Befehl forget-me innerhalb von {{.Remaining}} erneut mit `confirm`.",
+		msgForgotten:          "der Bot hat dich vergessen.",
+		msgDraining:           "der Bot startet gleich neu und nimmt daher keine neuen Ankündigungen an. Versuche es gleich noch einmal.",
+		msgRateLimited:        "Discord bremst den Bot gerade aus; neuer Versuch in {{.Duration}}.",
+		msgEditPrompt:         "Antworte innerhalb von {{.Remaining}} mit dem neuen Text deiner Ankündigung {{.Link}}, um sie zu bearbeiten, oder mit `cancel`, um sie so zu lassen. Derzeit lautet sie:",
+		msgEditCancelled:      "deine Ankündigung bleibt, wie sie ist.",
+		msgEditApplied:        "deine Ankündigung wurde bearbeitet.",
+		msgDigest:             "📰 **Ankündigungen der vergangenen Woche**\n{{.Report}}",
+		msgDigestMore:         "…und {{.Count}} weitere.",
+		msgCountdown:          "⏳ **{{.Topic}}** in {{.Left}} ({{.When}})",
+		msgCountdownDone:      "🎉 **{{.Topic}}** ist da!",
+		msgCountdownDays:      "{{.Count}} {{if eq .Count 1}}Tag{{else}}Tagen{{end}}",
+		msgCountdownHours:     "{{.Count}} {{if eq .Count 1}}Stunde{{else}}Stunden{{end}}",
+		msgCountdownMinutes:   "{{.Count}} {{if eq .Count 1}}Minute{{else}}Minuten{{end}}",
+		msgCountdownPosted:    "dein Countdown wurde gepostet: {{.Link}}",
+		msgInvalidCountdown:   "der Countdown ist ungültig: {{.Error}}.",
+		msgInvalidTime:        "die Ankündigung hat eine ungültige Zeitangabe: {{.Error}}.",
+		msgTemplates:          "die gespeicherten Vorlagen und was sie zum Ausfüllen brauchen:\n{{.Report}}",
+		msgNoTemplates:        "es wurden noch keine Vorlagen gespeichert.",
+		msgTemplateSaved:      "die Vorlage `{{.Name}}` wurde gespeichert.",
+		msgTemplateDeleted:    "die Vorlage `{{.Name}}` wurde gelöscht.",
+		msgTemplateNotFound:   "es gibt keine Vorlage namens `{{.Name}}`.",
+		msgInvalidTemplate:    "der Vorlagen-Befehl ist ungültig: {{.Error}}.",
+		msgSnippets:           "die Textbausteine, die Ankündigungen nutzen können:\n{{.Report}}",
+		msgNoSnippets:         "es wurden noch keine Textbausteine festgelegt.",
+		msgSnippetSaved:       "der Textbaustein `!{{.Name}}` wurde festgelegt.",
+		msgSnippetDeleted:     "der Textbaustein `!{{.Name}}` wurde gelöscht.",
+		msgSnippetNotFound:    "es gibt keinen Textbaustein namens `!{{.Name}}`.",
+		msgInvalidSnippet:     "der Textbaustein-Befehl ist ungültig: {{.Error}}.",
+		msgTTSNotAllowed:      "du darfst keine Text-to-Speech-Ankündigungen posten.",
+		msgSnoozed:            "die Ankündigung wird jetzt {{.When}} gelöscht.",
+		msgNotScheduled:       "diese Ankündigung ist weder zum Senden noch zum Löschen geplant.",
+		msgInvalidSnooze:      "das Verschieben ist ungültig: {{.Error}}.",
+		msgScheduled:          "die Ankündigung wird {{.When}} gesendet. Mit `snooze {{.Count}} <Dauer>` lässt sie sich verschieben.",
+		msgSendSnoozed:        "die Ankündigung wird jetzt {{.When}} gesendet.",
+		msgSnoozedPastDelete:  "die Ankündigung würde erst nach ihrer geplanten Löschung gesendet.",
+		msgScheduledSent:      "Deine geplante Ankündigung wurde gesendet: {{.Link}}",
+		msgBump:               "📣 Falls du es verpasst hast: {{.Link}}",
+		msgInvalidChannel:     "der Kanal ist ungültig: {{.Error}}.",
+		msgLocked:             "{{.Channel}} ist nun gesperrt. Nur wer eine eigene Berechtigung hat, kann dort noch schreiben.",
+		msgUnlocked:           "{{.Channel}} ist wieder entsperrt.",
+		msgNotApprover:        "diese Ankündigung muss von zwei Freigebenden freigegeben werden, und du gehörst nicht dazu.",
+		msgCannotApproveOwn:   "du kannst deine eigene Ankündigung nicht freigeben.",
+		msgAlreadyApproved:    "du hast diese Ankündigung bereits freigegeben. Sie braucht noch eine weitere Freigabe.",
+		msgApprovalNoted:      "deine Freigabe wurde vermerkt. Die Ankündigung braucht noch {{.Count}} weitere, bevor sie gepostet wird.",
+		msgApprovalAudit:      "{{.Author}} hat die wichtige Ankündigung {{.Link}} freigegeben. Sie braucht noch {{.Count}} weitere Freigabe, bevor sie gepostet wird.",
+		msgSignedOffAudit:     "{{.Author}} hat die letzte Freigabe für die wichtige Ankündigung {{.Link}} erteilt, daher wird sie nun gepostet.",
+		msgEditNeedsApproval:  "diese Bearbeitung würde die Ankündigung wichtig machen oder alle pingen, was die Freigabe von Freigebenden braucht. Poste sie stattdessen als neue Ankündigung.",
+		msgBadSignature:       "dieser Bot postet nur signierte Ankündigungen, also beende den Text mit seiner minisign-Signatur: {{.Error}}.",
+		msgSignatureAudit:     "{{.Author}} hat versucht, eine Ankündigung ohne gültige Signatur zu posten: {{.Error}}.",
+		msgSignedBodyChanged:  "die Inhaltsfilter würden den signierten Text ändern, also signiere ihn so, wie er gepostet werden soll.",
+		msgEditWindowClosed:   "diese Ankündigung wurde vor mehr als {{.Duration}} gepostet, daher dürfen sie nur noch Admins ändern.",
+		msgEditedFooter:       "-# Zuletzt bearbeitet von {{.Author}} {{.When}}",
+		msgEditDiff:           "{{.Author}} hat {{.Link}} bearbeitet:\n```diff\n{{.Report}}\n```",
+		msgEditDiffAttached:   "{{.Author}} hat {{.Link}} bearbeitet. Die Änderungen sind angehängt.",
+		msgNotAnnouncement:    "diese Nachricht ist keine Ankündigung, über die dieser Bot Buch führt.",
+		msgRetractedNotice:    "*Diese Ankündigung wurde von {{.Author}} zurückgezogen{{if .Reason}}: {{.Reason}}{{else}}.{{end}}*",
+		msgRetracted:          "die Ankündigung wurde zurückgezogen: {{.Link}}",
+		msgAnnouncementNumber: "-# Ankündigung #{{.Count}}",
+		msgInvalidNumber:      "das ist keine Ankündigungsnummer: {{.Error}}.",
+		msgNumberNotFound:     "es gibt keine Ankündigung #{{.Count}}.",
+		msgNumbered:           "Ankündigung #{{.Count}}: {{.Link}}",
+		msgInvalidSearch:      "diese Suche ist ungültig: {{.Error}}.",
+		msgNoSearchResults:    "keine Ankündigungen passen zu dieser Suche.",
+		msgSearchResults:      "{{.Count}} Ankündigungen passen{{if gt .Count 10}}, davon die neuesten 10{{end}}:\n{{.Report}}",
+		msgAuditAnchor:        "-# Audit-Log-Eintrag {{.Count}}: `{{.Hash}}`",
+		msgAttachmentFailed:   "die Anhänge konnten nicht heruntergeladen werden: {{.Error}}.",
+	},
+}
+
+// replyData is the data that message templates are executed with.
+type replyData struct {
+	// Author is the mention of the author.
+	Author string
+	// Remaining is the time remaining until the cooldown is over.
+	Remaining time.Duration
+	// Link is the jump link to the announcement.
+	Link string
+	// CooldownEnds is the time that the cooldown ends, formatted as a relative
+	// Discord timestamp.
+	CooldownEnds string
+	// Channel is the mention of the channel concerned.
+	Channel string
+	// Error is the error concerned.
+	Error error
+	// Reason is the reason given for the state concerned.
+	Reason string
+	// Report is a multi-line report, such as the doctor's.
+	Report string
+	// Role is the name of the role concerned, and OldRole is its previous
+	// name.
+	Role    string
+	OldRole string
+	// Setting is the name of the setting concerned.
+	Setting string
+	// Name is the name of the thing concerned, such as a template.
+	Name string
+	// Target is the mention of the user that the command acts on.
+	Target string
+	// Match is the part of the body that a content filter matched.
+	Match string
+	// Topic is the topic of the stage concerned.
+	Topic string
+	// Window is the time window concerned, such as "week", "month" or "all".
+	Window string
+	// Ref is the correlation ID of the internal error concerned.
+	Ref string
+	// Hash is the hash concerned, such as that of the latest audit log
+	// entry.
+	Hash string
+	// Duration is how long the state concerned has lasted.
+	Duration time.Duration
+	// Count is the number of things concerned.
+	Count int
+	// When is the time concerned, formatted as a Discord timestamp, and Left
+	// is how long is left until then, written out.
+	When string
+	Left string
+}
+
+// localize renders the message with the given key in the given locale. A
+// template configured in ReplyTemplates takes precedence over the catalog. If
+// the locale doesn't have the message, then its base language is tried, and
+// then the default locale.
+func localize(locale string, key messageKey, data replyData) string {
+	if text, ok := settings.ReplyTemplates[key]; ok {
+		s, err := renderReplyTemplate(text, data)
+		if err == nil {
+			return s
+		}
+		slog.Warn(
+			"Bot has failed to render a configured reply template. It will use the default reply instead.",
+			"key", key,
+			"err", err)
+	}
+
+	base, _, _ := strings.Cut(locale, "-")
+	for _, locale := range []string{locale, base, defaultLocale} {
+		if text, ok := messageCatalog[locale][key]; ok {
+			s, err := renderReplyTemplate(text, data)
+			if err != nil {
+				slog.Error(
+					"Bot has a broken message in its catalog. This is a bug.",
+					"key", key,
+					"locale", locale,
+					"err", err)
+				break
+			}
+			return s
+		}
+	}
+
+	return string(key)
+}
+
+func renderReplyTemplate(text string, data replyData) (string, error) {
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// locale returns the locale that the bot should reply in. The configured
+// locale takes precedence over the guild's preferred locale.
+func (b botState) locale() string {
+	if b.Locale != "" {
+		return b.Locale
+	}
+	if b.GuildLocale != "" {
+		return b.GuildLocale
+	}
+	return defaultLocale
+}
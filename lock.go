@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// lock stops or lets @everyone send messages in the channel given in the
+// command's arguments, such as during an incident. The remaining arguments
+// are the reason that goes into the guild's audit log. If no channel is
+// given, the target channel is used.
+func (h *commandHandler) lock(inv *invocation, command *parsedCommand, lock bool) {
+	target, reason, _ := strings.Cut(strings.TrimSpace(command.Args), " ")
+
+	channelID := h.bot.TargetChannelID
+	if target != "" {
+		channelIDs, err := parseChannelMentions(target)
+		if err == nil && len(channelIDs) != 1 {
+			err = fmt.Errorf("%q is not a channel mention", target)
+		}
+		if err != nil {
+			sendRejection(h.session, inv, inv.textWith(msgInvalidChannel, replyData{Error: err}))
+			return
+		}
+		channelID = channelIDs[0]
+	}
+
+	ch, err := h.session.Channel(channelID)
+	if err != nil || ch.GuildID != h.bot.TargetGuildID {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidChannel, replyData{
+			Error: fmt.Errorf("%s is not a channel of this server", channelID.Mention()),
+		}))
+		return
+	}
+
+	// The @everyone role shares its ID with the guild.
+	everyone := discord.Snowflake(h.bot.TargetGuildID)
+
+	var overwrite discord.Overwrite
+	for _, o := range ch.Overwrites {
+		if o.ID == everyone && o.Type == discord.OverwriteRole {
+			overwrite = o
+			break
+		}
+	}
+
+	// Unlocking only lifts the denial, so that the channel goes back to what
+	// the category and roles allow rather than allowing everyone.
+	if lock {
+		overwrite.Allow &^= discord.PermissionSendMessages
+		overwrite.Deny |= discord.PermissionSendMessages
+	} else {
+		overwrite.Deny &^= discord.PermissionSendMessages
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "requested by " + inv.Author.Tag()
+	}
+
+	if overwrite.Allow == 0 && overwrite.Deny == 0 {
+		err = h.session.DeleteChannelPermission(channelID, everyone, api.AuditLogReason(reason))
+	} else {
+		err = h.session.EditChannelPermission(channelID, everyone, api.EditChannelPermissionData{
+			Type:           discord.OverwriteRole,
+			Allow:          overwrite.Allow,
+			Deny:           overwrite.Deny,
+			AuditLogReason: api.AuditLogReason(reason),
+		})
+	}
+	if err != nil {
+		slog.Error(
+			"Bot has failed to change the permissions of the channel.",
+			"channel_id", channelID,
+			"locked", lock,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	slog.Info(
+		"A channel has been locked or unlocked.",
+		"author_id", inv.Author.ID,
+		"channel_id", channelID,
+		"locked", lock,
+		"reason", reason)
+
+	data := replyData{Channel: channelID.Mention()}
+	if lock {
+		h.acknowledge(inv, inv.textWith(msgLocked, data))
+	} else {
+		h.acknowledge(inv, inv.textWith(msgUnlocked, data))
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -24,14 +25,39 @@ import (
 
 func init() {
 	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s [flags] [subcommand] [args...]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fmt.Fprintf(os.Stderr, "  %-28s %s\n", "--repl", flag.Lookup("repl").Usage)
+		fmt.Fprintf(os.Stderr, "  %-28s %s\n", "--proxy <url>", flag.Lookup("proxy").Usage)
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Subcommands:\n")
+		for _, name := range subcommandNames() {
+			cmd := subcommands[name]
+			fmt.Fprintf(os.Stderr, "  %-28s %s\n", strings.TrimSpace(name+" "+cmd.Usage), cmd.Description)
+		}
+		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Environment Variables:\n")
-		fmt.Fprintf(os.Stderr, "  $DISCORD_TOKEN    the bot token\n")
-		fmt.Fprintf(os.Stderr, "  $STATE_DIRECTORY  the directory to store the bot state\n")
+		fmt.Fprintf(os.Stderr, "  $DISCORD_TOKEN      the bot token\n")
+		fmt.Fprintf(os.Stderr, "  $DISCORD_TOKEN_FALLBACK\n")
+		fmt.Fprintf(os.Stderr, "                      the token to use if Discord refuses $DISCORD_TOKEN\n")
+		fmt.Fprintf(os.Stderr, "  $STATE_DIRECTORY    the directory to store the bot state\n")
+		fmt.Fprintf(os.Stderr, "  $CONFIG_FILE        the JSON file to read the settings from\n")
+		fmt.Fprintf(os.Stderr, "  $TRANSLATE_API_KEY  the API key of the translation provider\n")
+		fmt.Fprintf(os.Stderr, "  $PROCESSOR_API_KEY  the API key of the language model used by processors\n")
+		fmt.Fprintf(os.Stderr, "  $METRICS_ADDRESS    the address to serve metrics on, such as localhost:9100\n")
+		fmt.Fprintf(os.Stderr, "  $DEBUG_TOKEN        the bearer token that /debug/state requires\n")
+		fmt.Fprintf(os.Stderr, "  $ALERT_WEBHOOK_URL  the webhook to alert when the bot can't reach Discord\n")
+		fmt.Fprintf(os.Stderr, "  $HEARTBEAT_URL      the URL to ping every minute while the bot is healthy\n")
+		fmt.Fprintf(os.Stderr, "  $DISCORD_API_URL    the REST API to use instead of Discord's, such as a test server\n")
+		fmt.Fprintf(os.Stderr, "  $DISCORD_GATEWAY_URL\n")
+		fmt.Fprintf(os.Stderr, "                      the gateway to connect to instead of the one the REST API gives\n")
+		fmt.Fprintf(os.Stderr, "  $HTTPS_PROXY        the proxy to reach Discord through, unless --proxy is given\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Documentation:\n")
 		fmt.Fprintf(os.Stderr, "  https://libdb.so/message-for-me\n")
 	}
-	flag.Parse()
 }
 
 var (
@@ -39,6 +65,18 @@ var (
 )
 
 func main() {
+	flag.Parse()
+
+	if err := applyProxy(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := applyEndpoints(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	if env := os.Getenv("STATE_DIRECTORY"); env != "" {
 		stateDirectory = env
 	} else {
@@ -52,32 +90,101 @@ func main() {
 		stateDirectory = filepath.Join(userConfigDir, "message-for-me")
 	}
 
-	slog.Info(
-		"This bot will be using a state directory.",
-		"state_directory", stateDirectory)
+	name := defaultSubcommand
+	args := flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	cmd, ok := subcommands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown subcommand %q\n\n", name)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	os.Exit(run(ctx))
+	os.Exit(cmd.Run(ctx, args))
 }
 
 type botState struct {
 	botSettings
 	SelfID            discord.UserID
+	AppID             discord.AppID
 	TargetGuildID     discord.GuildID
+	GuildLocale       string
+	AppFlags          discord.ApplicationFlags
 	LastAnnouncedTime time.Time
+	// Announcing is how many announcements, polls and countdowns are being
+	// sent. The cooldown holds while any are, since it only starts once they
+	// have been sent.
+	Announcing int
+	Runtime    runtimeState
 }
 
 var errMalfunction = errors.New("bot is malfunctioning")
 
 func run(ctx context.Context) int {
+	// Remember the errors that the bot logs, so that admins can read them
+	// using the errors command.
+	slog.SetDefault(slog.New(newErrorRecorder(slog.NewTextHandler(os.Stderr, nil), recentErrors)))
+
 	token := os.Getenv("DISCORD_TOKEN")
 	if token == "" {
 		slog.Error("This bot requires $DISCORD_TOKEN to be set.")
 		return 1
 	}
 
+	slog.Info(
+		"This bot will be using a state directory.",
+		"state_directory", stateDirectory)
+
+	if err := loadConfig(); err != nil {
+		slog.Error(
+			"Bot could not load its config. Run the validate-config subcommand for details.",
+			"err", err)
+		return 1
+	}
+
+	// Only one instance may use the state directory at a time, so wait until
+	// any other instance is done with it.
+	if err := os.MkdirAll(stateDirectory, 0700); err != nil {
+		slog.Error(
+			"Bot could not create the state directory.",
+			"err", err)
+		return 1
+	}
+
+	release, err := acquireStateLock(ctx, filepath.Join(stateDirectory, stateLockFile))
+	if err != nil {
+		slog.Error(
+			"Bot could not lock the state directory.",
+			"err", err)
+		return 1
+	}
+	defer release()
+
+	slog.Info("This instance now holds the state directory. It will serve commands.")
+
+	// Upgrade the state before anything opens it.
+	applied, err := migrateStateDirectory(stateDirectory)
+	for _, migration := range applied {
+		slog.Info(
+			"Bot has migrated its state.",
+			"migration", migration)
+	}
+	if err != nil {
+		slog.Error(
+			"Bot could not migrate its state. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+
+	// stopped is done once the bot has been asked to shut down.
+	stopped := ctx
+
 	errg, ctx := errgroup.WithContext(ctx)
 	defer errg.Wait()
 
@@ -92,33 +199,358 @@ func run(ctx context.Context) int {
 			"err", err)
 		return 1
 	}
+	defer closeStore("last-sent-authors", lastSentAuthors)
 
-	gatewayID := gateway.DefaultIdentifier(token)
-	gatewayID.Capabilities = 253 // magic constant from reverse-engineering
-	gatewayID.Properties = gateway.IdentifyProperties{
-		OS:      runtime.GOOS,
-		Browser: "message-for-me",
-		Device:  "message-for-me",
+	// Keep track of every announcement that the bot has posted.
+	announcements, err := persist.NewMap[discord.MessageID, announcementRecord](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "announcements-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the announcements database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("announcements", announcements)
+
+	// Keep track of the subscribe prompt in each channel.
+	subscribePrompts, err := persist.NewMap[discord.ChannelID, discord.MessageID](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "subscribe-prompts-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the subscribe-prompts database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("subscribe-prompts", subscribePrompts)
+
+	// Keep track of the idempotency keys of recent announcements.
+	dedupeKeys, err := persist.NewMap[string, time.Time](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "dedupe-keys-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the dedupe-keys database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("dedupe-keys", dedupeKeys)
+
+	// Keep track of announcements that are yet to be sent.
+	outbox, err := persist.NewMap[string, outboxEntry](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "outbox-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the outbox database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("outbox", outbox)
+
+	// Keep track of announcements that wait in the staging channel.
+	staged, err := persist.NewMap[discord.MessageID, stagedAnnouncement](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "staged-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the staged database. It will not be able to function.",
+			"err", err)
+		return 1
 	}
-	gatewayID.Presence = &gateway.UpdatePresenceCommand{
-		// Mark that the bot is perpetually AFK so that it doesn't block any
-		// notifications from arriving.
-		Status: discord.IdleStatus,
-		AFK:    true,
+	defer closeStore("staged", staged)
+
+	countdowns, err := persist.NewMap[discord.MessageID, countdown](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "countdowns-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the countdowns database. It will not be able to function.",
+			"err", err)
+		return 1
 	}
+	defer closeStore("countdowns", countdowns)
 
-	session := ningen.
-		NewWithIdentifier(gatewayID).
-		WithContext(ctx)
+	templates, err := persist.NewMap[string, announcementTemplate](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "templates-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the templates database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("templates", templates)
+
+	snippets, err := persist.NewMap[string, snippet](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "snippets-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the snippets database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("snippets", snippets)
+
+	deletions, err := persist.NewMap[discord.MessageID, time.Time](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "deletions-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the deletions database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("deletions", deletions)
+
+	scheduled, err := persist.NewMap[int, scheduledAnnouncement](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "scheduled-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the scheduled announcements database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("scheduled", scheduled)
+
+	stickies, err := persist.NewMap[discord.ChannelID, stickyAnnouncement](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "stickies-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the stickies database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("stickies", stickies)
+
+	bumps, err := persist.NewMap[discord.MessageID, time.Time](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "bumps-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the bumps database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("bumps", bumps)
+
+	slowmodes, err := persist.NewMap[discord.ChannelID, slowmodeRestore](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "slowmodes-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the slowmodes database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("slowmodes", slowmodes)
+
+	auditLog, err := persist.NewMap[uint64, auditEntry](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "audit-log-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the audit log. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("audit log", auditLog)
+	auditSeq, auditHash := lastAuditEntry(auditLog)
+
+	// Keep track of the signatures that have been used.
+	signatures, err := persist.NewMap[string, time.Time](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "signatures-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the signatures database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("signatures", signatures)
+
+	// Keep the search index of past announcements.
+	searchTerms, err := persist.NewMap[string, searchPostings](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "search-terms-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the search-terms database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("search-terms", searchTerms)
+
+	searchDocuments, err := persist.NewMap[discord.MessageID, []string](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "search-documents-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the search-documents database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("search-documents", searchDocuments)
+
+	// Keep track of the state that the bot changes at runtime.
+	runtimeStates, err := persist.NewMap[string, runtimeState](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "runtime-state-v1"),
+	)
+	if err != nil {
+		slog.Error(
+			"Bot could not open the runtime-state database. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+	defer closeStore("runtime-state", runtimeStates)
+
+	savedRuntime, _, err := runtimeStates.Load(runtimeStateKey)
+	if err != nil {
+		slog.Error(
+			"Bot could not load its runtime state. It will not be able to function.",
+			"err", err)
+		return 1
+	}
+
+	translator, err := newTranslator(settings.Translation)
+	if err != nil {
+		slog.Error(
+			"Bot could not set up translations.",
+			"err", err)
+		return 1
+	}
+
+	processors, err := newProcessors(settings.Processors)
+	if err != nil {
+		slog.Error(
+			"Bot could not set up its processors.",
+			"err", err)
+		return 1
+	}
+
+	token, fallback := pickToken(ctx, token, os.Getenv(fallbackTokenEnv))
+
+	// API calls are not tied to ctx, so that whatever is being sent when the
+	// bot is asked to shut down still gets through.
+	session := newSession(token).WithContext(context.WithoutCancel(ctx))
+
+	metrics := newMetrics(newGatewayMonitor(session))
+	debug := newDebugHandler()
+
+	// A nil channel is never ready, so the event loop never waits for debug
+	// requests if the debug endpoint is disabled.
+	var debugRequests <-chan debugRequest
+	if debug != nil {
+		debugRequests = debug.requests
+	}
+
+	if url := os.Getenv(alertWebhookEnv); url != "" && settings.OutageAlertAfter > 0 {
+		errg.Go(func() error {
+			watchOutages(ctx, url, settings.OutageAlertAfter, metrics.gateway)
+			return nil
+		})
+	}
+
+	// Like debug requests, probes are never waited for if heartbeats are
+	// disabled.
+	var heartbeatProbes chan heartbeatProbe
+	if url := os.Getenv(heartbeatURLEnv); url != "" {
+		heartbeatProbes = make(chan heartbeatProbe)
+		errg.Go(func() error {
+			sendHeartbeats(ctx, url, metrics.gateway, heartbeatProbes)
+			return nil
+		})
+	}
+
+	if addr := os.Getenv(metricsAddressEnv); addr != "" {
+		errg.Go(func() error {
+			return serveMetrics(ctx, addr, metrics, debug)
+		})
+	}
 
 	var (
 		msgCh   = make(chan *gateway.MessageCreateEvent)
 		readyCh = newEventChannel[*gateway.ReadyEvent](session)
 		guildCh = newEventChannel[*gateway.GuildCreateEvent](session)
+
+		interactionCh = newEventChannel[*gateway.InteractionCreateEvent](session)
+
+		reactionAddCh    = newEventChannel[*gateway.MessageReactionAddEvent](session)
+		reactionRemoveCh = newEventChannel[*gateway.MessageReactionRemoveEvent](session)
+
+		roleUpdateCh = newEventChannel[*gateway.GuildRoleUpdateEvent](session)
+		roleDeleteCh = newEventChannel[*gateway.GuildRoleDeleteEvent](session)
+		msgUpdateCh  = newEventChannel[*gateway.MessageUpdateEvent](session)
+		msgDeleteCh  = newEventChannel[*gateway.MessageDeleteEvent](session)
+		bulkDeleteCh = newEventChannel[*gateway.MessageDeleteBulkEvent](session)
 	)
 
 	errg.Go(func() error {
-		bot := botState{botSettings: settings}
+		bot := botState{botSettings: settings, Runtime: savedRuntime}
+		handler := &commandHandler{
+			session:          session,
+			lastSentAuthors:  lastSentAuthors,
+			announcements:    announcements,
+			subscribePrompts: subscribePrompts,
+			runtimeStates:    runtimeStates,
+			dedupeKeys:       dedupeKeys,
+			outbox:           outbox,
+			staged:           staged,
+			countdowns:       countdowns,
+			templates:        templates,
+			snippets:         snippets,
+			deletions:        deletions,
+			scheduled:        scheduled,
+			sendingScheduled: make(map[int]struct{}),
+			stickies:         stickies,
+			bumps:            bumps,
+			slowmodes:        slowmodes,
+			auditLog:         auditLog,
+			auditSeq:         auditSeq,
+			auditHash:        auditHash,
+			signatures:       signatures,
+			searchTerms:      searchTerms,
+			searchDocuments:  searchDocuments,
+			pushedStickies:   make(map[discord.ChannelID]struct{}),
+			translator:       translator,
+			processors:       processors,
+			metrics:          metrics,
+			gateway:          metrics.gateway,
+			pending:          make(map[string]*pendingAnnouncement),
+			roleNames:        make(map[discord.RoleID]string),
+			replies:          make(map[discord.MessageID]*trackedReplies),
+			botDeletions:     make(map[discord.MessageID]struct{}),
+			completions:      make(chan func()),
+			stopped:          make(chan struct{}),
+			flood:            newFloodGuard(),
+			forgetRequests:   make(map[discord.UserID]time.Time),
+			editRequests:     make(map[discord.UserID]editRequest),
+			stats:            sessionStats{Started: time.Now()},
+			bot:              &bot,
+		}
+
 		trySubscribe := func() bool {
 			if bot.TargetGuildID.IsValid() {
 				return true
@@ -133,10 +565,34 @@ func run(ctx context.Context) int {
 			}
 
 			bot.TargetGuildID = ch.GuildID
+			if guild, err := session.Cabinet.Guild(ch.GuildID); err == nil {
+				bot.GuildLocale = guild.PreferredLocale
+			}
 
 			session.MemberState.Subscribe(ch.GuildID)
 			session.AddSyncHandler(msgCh)
 
+			if bot.AppID.IsValid() {
+				registerCommands(session, bot)
+			}
+
+			checkStartupPermissions(session, bot)
+			handler.recordRoleNames()
+
+			// Load the search index before anything is sent, since sending
+			// adds to it.
+			handler.loadSearchIndex()
+
+			// Send anything that didn't make it out before the bot last
+			// stopped.
+			handler.resumeOutbox()
+
+			// Let edit work for announcements from before the bot
+			// remembered who made them.
+			handler.backfillOnce()
+
+			handler.updatePresence()
+
 			slog.Info(
 				"Bot has subscribed to the target channel's guild. It is now ready to serve.",
 				"guild_id", ch.GuildID,
@@ -145,19 +601,93 @@ func run(ctx context.Context) int {
 			return true
 		}
 
+		dumpCh, stopDump := notifyDump()
+		defer stopDump()
+
+		reachTicker := time.NewTicker(reachCheckInterval)
+		defer reachTicker.Stop()
+
+		expiryTicker := time.NewTicker(expiryCheckInterval)
+		defer expiryTicker.Stop()
+
+		digestTicker := time.NewTicker(digestCheckInterval)
+		defer digestTicker.Stop()
+
+		countdownTicker := time.NewTicker(countdownInterval)
+		defer countdownTicker.Stop()
+
+		deletionTicker := time.NewTicker(deletionCheckInterval)
+		defer deletionTicker.Stop()
+
+		scheduleTicker := time.NewTicker(scheduleCheckInterval)
+		defer scheduleTicker.Stop()
+
+		stickyTicker := time.NewTicker(stickyRepostInterval)
+		defer stickyTicker.Stop()
+
+		bumpTicker := time.NewTicker(bumpCheckInterval)
+		defer bumpTicker.Stop()
+
+		slowmodeTicker := time.NewTicker(slowmodeCheckInterval)
+		defer slowmodeTicker.Stop()
+
+		// Only rotate the presence if there is an activity to show.
+		var presenceTick <-chan time.Time
+		if len(settings.Presence.Activities) > 0 {
+			presenceTicker := time.NewTicker(settings.Presence.interval())
+			defer presenceTicker.Stop()
+			presenceTick = presenceTicker.C
+		}
+
+		controlCh := make(chan controlRequest)
+		errg.Go(func() error {
+			// The bot works fine without the control socket, so failing to
+			// serve it isn't fatal.
+			if err := serveControl(ctx, filepath.Join(stateDirectory, controlSocketDirectory), controlCh); err != nil {
+				slog.Warn(
+					"Bot could not serve its control socket. It will run without one.",
+					"err", err)
+			}
+			return nil
+		})
+
+		var drainTick <-chan time.Time
+
+		var replCh chan *parsedCommand
+		if *replEnabled {
+			replCh = make(chan *parsedCommand)
+			go readREPL(ctx, os.Stdin, replCh)
+		}
+
+		defer close(handler.stopped)
+
 		var startupTimeout <-chan time.Time
+		// Events are handled one at a time, so any command that is being
+		// handled is finished before the loop notices that it should stop.
+		// Whatever it left to background work, such as retrying, is finished
+		// before the loop returns.
 		for {
 			select {
 			case <-ctx.Done():
+				handler.finishBackground()
+				handler.logShutdownSummary()
 				return ctx.Err()
 
 			case ev := <-readyCh:
+				handler.noteEvent(ev)
 				bot.SelfID = ev.User.ID
+				if ev.User.Bot {
+					// Only bot accounts can receive interactions, so only
+					// bother with slash commands for those.
+					bot.AppID = ev.Application.ID
+					bot.AppFlags = ev.Application.Flags
+				}
 
 				slog.Info(
 					"This bot is online. It is preparing to serve.",
 					"bot_id", ev.User.ID,
-					"bot_name", ev.User.Tag())
+					"bot_name", ev.User.Tag(),
+					"fallback_token", fallback)
 
 				// When the bot comes online, immediately start subscribing to
 				// the guild that it cares about. This tells Discord to start
@@ -171,10 +701,17 @@ func run(ctx context.Context) int {
 			case <-startupTimeout:
 				return fmt.Errorf("bot has failed to start up in time")
 
-			case <-guildCh:
+			case ev := <-guildCh:
+				handler.noteEvent(ev)
 				trySubscribe()
 
 			case ev := <-msgCh:
+				handler.noteEvent(ev)
+				handler.noteChannelActivity(ev)
+				if handler.handleEditReply(ev) {
+					continue
+				}
+
 				command, err := parseCommand(session, bot, ev)
 				if err != nil {
 					slog.Warn(
@@ -187,86 +724,130 @@ func run(ctx context.Context) int {
 					continue
 				}
 
-				slog.Info(
-					"This bot has received a valid command.",
-					"author.id", ev.Author.ID,
-					"author.tag", ev.Author.Tag(),
-					"command", command.Command,
-					"body", command.Body)
-
-				switch command.Command {
-				case "announce":
-					// For announcing a new message, ensure that the global rate
-					// limit is respected.
-					if time.Since(bot.LastAnnouncedTime) < bot.MinAnnounceTimeGap {
-						sendReply(session, ev, "please wait before sending another announcement.")
-						continue
-					}
-
-					target, err := session.SendMessage(bot.TargetChannelID, command.Body)
-					if err != nil {
-						slog.Error(
-							"Bot has failed to send the announcement message.",
-							"channel_id", bot.TargetChannelID,
-							"err", err)
-
-						replyInternalError(session, ev)
-						continue
-					}
-
-					// Update the last announcement time.
-					bot.LastAnnouncedTime = time.Now()
-
-					// Send a reply to the author.
-					sendReply(session, ev, "the announcement has been sent.")
-
-					// Store the last message sent by the author.
-					if err := lastSentAuthors.Store(ev.Author.ID, target.ID); err != nil {
-						slog.Warn(
-							"Bot has failed to store the last message sent by the author.",
-							"author_id", ev.Author.ID,
-							"err", err)
-					}
-
-				case "edit":
-					// Look up the last message sent by the author.
-					lastSent, ok, err := lastSentAuthors.Load(ev.Author.ID)
-					if err != nil {
-						slog.Error(
-							"Bots has failed to look up the last message sent by the author.",
-							"author_id", ev.Author.ID,
-							"err", err)
-
-						replyInternalError(session, ev)
-						continue
-					}
-
-					if !ok {
-						sendReply(session, ev, "this bot could not find the last announcement you sent.")
-						continue
-					}
-
-					if _, err := session.EditMessage(bot.TargetChannelID, lastSent, command.Body); err != nil {
-						slog.Error(
-							"Bot has failed to edit the last announcement message.",
-							"channel_id", bot.TargetChannelID,
-							"message_id", lastSent,
-							"err", err)
-
-						replyInternalError(session, ev)
-						continue
-					}
+				handler.handleCommand(newMessageInvocation(ev, bot.locale()), command)
+
+			case ev := <-msgUpdateCh:
+				handler.noteEvent(ev)
+				handler.handleCommandEdit(ev)
+				handler.handleAnnouncementEdit(ev)
+
+			case ev := <-msgDeleteCh:
+				handler.noteEvent(ev)
+				handler.handleDeleteEvent(ev)
+
+			case ev := <-bulkDeleteCh:
+				handler.noteEvent(ev)
+				handler.handleBulkDeleteEvent(ev)
+
+			case ev := <-interactionCh:
+				handler.noteEvent(ev)
+				inv, command := handler.handleInteraction(ev)
+				if command == nil {
+					continue
+				}
+
+				handler.handleCommand(inv, command)
+
+			case ev := <-reactionAddCh:
+				handler.noteEvent(ev)
+				handler.handleReactionAdd(ev)
+				handler.handleEditReaction(ev)
+
+			case ev := <-reactionRemoveCh:
+				handler.noteEvent(ev)
+				handler.handleReactionRemove(ev)
+
+			case ev := <-roleUpdateCh:
+				handler.noteEvent(ev)
+				handler.handleRoleUpdate(ev)
+
+			case ev := <-roleDeleteCh:
+				handler.noteEvent(ev)
+				handler.handleRoleDelete(ev)
+
+			case done := <-handler.completions:
+				done()
+
+			case <-dumpCh:
+				handler.dumpState()
+
+			case req := <-controlCh:
+				req.Reply <- handler.handleControl(req.Line)
+
+				if handler.draining && drainTick == nil {
+					drainTicker := time.NewTicker(drainCheckInterval)
+					defer drainTicker.Stop()
+					drainTick = drainTicker.C
+				}
+
+			case <-drainTick:
+				if handler.drained() {
+					handler.logShutdownSummary()
+					return errDrained
 				}
+
+			case command := <-replCh:
+				handler.handleREPL(command)
+
+			case reply := <-debugRequests:
+				handler.serveDebug(reply)
+
+			case probe := <-heartbeatProbes:
+				probe <- struct{}{}
+
+			case <-reachTicker.C:
+				if bot.TargetGuildID.IsValid() {
+					handler.measureReach()
+				}
+
+			case <-expiryTicker.C:
+				handler.expireState()
+
+			case <-slowmodeTicker.C:
+				handler.restoreSlowmodes()
+
+			case <-bumpTicker.C:
+				handler.bumpDueAnnouncements()
+
+			case <-stickyTicker.C:
+				handler.repostStickies()
+
+			case <-deletionTicker.C:
+				handler.deleteDueAnnouncements()
+
+			case <-scheduleTicker.C:
+				handler.sendDueAnnouncements()
+
+			case <-countdownTicker.C:
+				handler.updateCountdowns()
+
+			case <-digestTicker.C:
+				if bot.TargetGuildID.IsValid() {
+					handler.postDigestIfDue()
+				}
+
+			case <-presenceTick:
+				handler.rotatePresence()
 			}
 		}
 	})
 
 	errg.Go(func() error {
 		slog.Info("Bot is now connecting to Discord.")
-		return session.Connect(ctx)
+		return connectWithWatchdog(ctx, session, metrics.gateway, settings.WatchdogTimeout)
 	})
 
 	if err := errg.Wait(); err != nil {
+		if stopped.Err() != nil && errors.Is(err, context.Canceled) {
+			slog.Info("Bot has been shut down.")
+			return 0
+		}
+
+		if errors.Is(err, errDrained) {
+			slog.Info("Bot has been drained and shut down.")
+			return 0
+		}
+
 		// Try to extract the cause of the cancellation, if any.
 		if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() {
 			err = cause
@@ -281,21 +862,26 @@ func run(ctx context.Context) int {
 	return 0
 }
 
-func replyInternalError(session *ningen.State, msg *gateway.MessageCreateEvent) {
-	sendReply(session, msg, "this bot has encountered an internal error. This error has been logged.")
-}
-
-func sendReply(session *ningen.State, msg *gateway.MessageCreateEvent, content string) {
-	content = msg.Author.Mention() + ", " + content
+// newSession creates a new session that identifies the way this bot does.
+func newSession(token string) *ningen.State {
+	gatewayID := gateway.DefaultIdentifier(token)
+	gatewayID.Capabilities = settings.Identify.Capabilities
+	gatewayID.Properties = gateway.IdentifyProperties{
+		OS:      runtime.GOOS,
+		Browser: settings.Identify.Browser,
+		Device:  settings.Identify.Device,
+	}
+	// Intents are only understood by bot accounts.
+	if intents, ok := settings.Identify.intents(); ok && strings.HasPrefix(token, "Bot ") {
+		gatewayID.AddIntents(intents)
+	}
+	// The activity is filled in once the bot knows its state.
+	gatewayID.Presence = settings.Presence.command("")
 
-	_, err := session.SendMessageReply(msg.ChannelID, content, msg.ID)
-	if err != nil {
-		slog.Error(
-			"Bot has failed to deliver a reply.",
-			"channel_id", msg.ChannelID,
-			"author_id", msg.Author.ID,
-			"err", err)
+	if customGatewayURL != "" {
+		return newCustomGatewayState(gatewayID)
 	}
+	return ningen.NewWithIdentifier(gatewayID)
 }
 
 func newEventChannel[T gateway.Event](session *ningen.State) <-chan T {
@@ -307,14 +893,21 @@ func newEventChannel[T gateway.Event](session *ningen.State) <-chan T {
 // parsedCommand describes a parsed command from a message.
 // The bot expects a message of the following format:
 //
-//	<@botID> command
+//	<@botID> command [args...]
 //	body
 //
 // The command is case-insensitive.
-// The new line is necessary.
+// The new line is necessary for commands that need a body.
+//
+// The body may optionally begin with a front matter block containing options
+// for the command; see cutFrontMatter.
 type parsedCommand struct {
 	Command string
+	Args    string
 	Body    string
+	Options string
+	// Attachments are the files to post along with the body.
+	Attachments []discord.Attachment
 }
 
 // parseCommand parses the command from the message.
@@ -352,46 +945,110 @@ func parseCommand(dsession *ningen.State, bot botState, msg *gateway.MessageCrea
 		return nil, nil
 	}
 
-	// The message must come from a user with the right role.
-	if !slices.ContainsFunc(msg.Member.RoleIDs, func(id discord.RoleID) bool {
-		return slices.Contains(bot.AllowedRoleIDs, id)
-	}) {
-		return nil, nil
-	}
-
 	// The message must conform to the expected format.
 
-	// It expects a message with at least two lines, the first one being the
-	// header and the rest being the body.
-	header, body, ok := strings.Cut(msg.Content, "\n")
-	if !ok {
-		return nil, nil
-	}
+	// It expects a message with a header line and, for most commands, the body
+	// on the lines after it.
+	header, body, _ := strings.Cut(msg.Content, "\n")
 
 	// The header must begin with its mention.
 	if !strings.HasPrefix(header, bot.SelfID.Mention()) {
 		return nil, nil
 	}
 
-	// Parse the command out.
+	// Parse the command out. Anything after the command on the same line is
+	// its arguments.
 	command := header
 	command = strings.TrimPrefix(command, bot.SelfID.Mention())
 	command = strings.TrimSpace(command)
+	command, args, _ := strings.Cut(command, " ")
 	command = strings.ToLower(command)
 
-	// The command must be non-empty.
-	if command == "" {
+	// The command must be known.
+	spec, ok := commandSpecs[command]
+	if !ok {
+		return nil, nil
+	}
+
+	// The message must not come from a blocked user, whatever their roles.
+	if bot.isBlocked(msg.Author.ID) {
+		return nil, nil
+	}
+
+	// The message must come from a user with the right role or permissions
+	// for the command.
+	perms, err := memberPermissions(dsession, bot, msg.Author.ID, msg.Member)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canUse(bot, command, msg.Member, perms) {
 		return nil, nil
 	}
 
-	// The body must be non-empty.
-	if body == "" {
+	// Split out the options, if any.
+	options, body := cutFrontMatter(body)
+
+	// An announcement made in reply to another message with no body of its
+	// own posts that message, which is handy when someone else drafted it.
+	var attachments []discord.Attachment
+	if command == "announce" && body == "" && options == "" && msg.ReferencedMessage != nil {
+		options, body = cutFrontMatter(msg.ReferencedMessage.Content)
+		attachments = msg.ReferencedMessage.Attachments
+	}
+
+	// The body must be non-empty if the command needs one.
+	if spec.NeedsBody && body == "" {
 		return nil, nil
 	}
 
 	// We now have a valid command.
 	return &parsedCommand{
-		Command: command,
-		Body:    body,
+		Command:     command,
+		Args:        strings.TrimSpace(args),
+		Body:        body,
+		Options:     options,
+		Attachments: attachments,
 	}, nil
 }
+
+// canUse returns true if the given member may use the given command. Roles
+// configured for the command in CommandRoleIDs take precedence over its spec.
+func canUse(bot botState, command string, member *discord.Member, perms discord.Permissions) bool {
+	if roleIDs, ok := bot.CommandRoleIDs[command]; ok {
+		return hasAnyRole(member, roleIDs)
+	}
+
+	spec := commandSpecs[command]
+	switch {
+	case spec.Public:
+		return true
+	case spec.Admin:
+		return isAdmin(bot, member, perms)
+	default:
+		return isAuthorized(bot, member, perms)
+	}
+}
+
+// isAuthorized returns true if the given member is allowed to use this bot,
+// either through their roles or through their permissions in the target
+// channel.
+func isAuthorized(bot botState, member *discord.Member, perms discord.Permissions) bool {
+	return hasAnyRole(member, bot.AllowedRoleIDs) || perms&bot.AllowedPermissions != 0
+}
+
+// isAdmin returns true if the given member is allowed to administrate this
+// bot. If no admin roles are configured, then everyone allowed to use the bot
+// is an admin.
+func isAdmin(bot botState, member *discord.Member, perms discord.Permissions) bool {
+	if len(bot.AdminRoleIDs) == 0 {
+		return isAuthorized(bot, member, perms)
+	}
+	return hasAnyRole(member, bot.AdminRoleIDs)
+}
+
+func hasAnyRole(member *discord.Member, roleIDs []discord.RoleID) bool {
+	return slices.ContainsFunc(member.RoleIDs, func(id discord.RoleID) bool {
+		return slices.Contains(roleIDs, id)
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// messageLink returns the jump link to the given message.
+func messageLink(guildID discord.GuildID, msg messageRef) string {
+	return fmt.Sprintf("https://discord.com/channels/%d/%d/%d", guildID, msg.ChannelID, msg.MessageID)
+}
+
+// timestampStyle is the style of a Discord timestamp markup.
+type timestampStyle byte
+
+const (
+	timestampRelative timestampStyle = 'R'
+	timestampFull     timestampStyle = 'F'
+)
+
+// timestampMarkup returns the Discord markup for the given time, which is
+// rendered in the reader's own timezone.
+func timestampMarkup(t time.Time, style timestampStyle) string {
+	return fmt.Sprintf("<t:%d:%c>", t.Unix(), style)
+}
+
+var markupEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"`", "\\`",
+	"|", `\|`,
+	">", `\>`,
+	"@", "@\u200b",
+)
+
+// escapeMarkup escapes the markdown in the text, so that it is shown as-is.
+// Mentions of everyone are broken up, so that they don't ping.
+func escapeMarkup(text string) string {
+	return markupEscaper.Replace(text)
+}
+
+// codePattern matches code blocks and inline code, whose text Discord shows
+// as-is.
+var codePattern = regexp.MustCompile("(?s)```.*?```|``.+?``|`[^`]+`")
+
+// outsideCode applies replace to the parts of the text that aren't code, and
+// leaves the code as it is.
+func outsideCode(text string, replace func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range codePattern.FindAllStringIndex(text, -1) {
+		b.WriteString(replace(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(replace(text[last:]))
+	return b.String()
+}
+
+// parseUserMention parses a user mention or a raw user ID.
+func parseUserMention(text string) (discord.UserID, error) {
+	text = strings.TrimSpace(text)
+	raw := strings.TrimPrefix(strings.TrimSuffix(text, ">"), "<@")
+	raw = strings.TrimPrefix(raw, "!")
+
+	sf, err := discord.ParseSnowflake(raw)
+	if err != nil || !sf.IsValid() {
+		return 0, fmt.Errorf("%q is not a user mention", text)
+	}
+	return discord.UserID(sf), nil
+}
+
+// parseMessageID parses a jump link to a message or a bare message ID.
+func parseMessageID(text string) (discord.MessageID, error) {
+	text = strings.TrimSpace(text)
+	if match := messageLinkPattern.FindStringSubmatch(text); match != nil {
+		text = match[1]
+	}
+
+	sf, err := discord.ParseSnowflake(text)
+	if err != nil || !sf.IsValid() {
+		return 0, fmt.Errorf("%q is not a message link", text)
+	}
+	return discord.MessageID(sf), nil
+}
+
+// parseChannelMentions parses a list of channel mentions separated by spaces
+// or commas.
+func parseChannelMentions(text string) ([]discord.ChannelID, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' })
+
+	channelIDs := make([]discord.ChannelID, 0, len(fields))
+	for _, field := range fields {
+		raw := strings.TrimSuffix(strings.TrimPrefix(field, "<#"), ">")
+		sf, err := discord.ParseSnowflake(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a channel mention", field)
+		}
+		channelIDs = append(channelIDs, discord.ChannelID(sf))
+	}
+
+	return channelIDs, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// plainMentionPattern matches plain-text mentions such as "@alice". The
+// character before the mention is captured so that email addresses, paths in
+// links, such as "https://example.com/@alice", and mention markup, such as
+// "<@123>", aren't matched.
+var plainMentionPattern = regexp.MustCompile(`(^|[^\w<@/])@([\w.]{2,32})`)
+
+// plainChannelPattern matches plain-text channel references such as
+// "#general". The character before the reference is captured so that
+// fragments in links and mention markup aren't matched.
+var plainChannelPattern = regexp.MustCompile(`(^|[^\w<#&/])#([\w-]{1,100})`)
+
+// wordEnd matches text that ends in a word character, after which \b may
+// follow.
+var wordEnd = regexp.MustCompile(`\w$`)
+
+// resolveMentions replaces the plain-text mentions of the target guild's
+// roles, members and channels in the body with their markup, since drafts
+// pasted from elsewhere usually only have their names. Code is left alone.
+func (h *commandHandler) resolveMentions(body string) string {
+	// Roles go first, since their names may have spaces in them, and they
+	// would otherwise be mistaken for members with part of the name.
+	body = h.resolveRoleMentions(body)
+	body = h.resolveUserMentions(body)
+	body = h.resolveChannelMentions(body)
+	return body
+}
+
+// resolveRoleMentions replaces plain-text mentions of the target guild's
+// roles, such as "@Dev Board", with their mention markup.
+func (h *commandHandler) resolveRoleMentions(body string) string {
+	if !strings.Contains(body, "@") {
+		return body
+	}
+
+	roles, err := h.session.Cabinet.Roles(h.bot.TargetGuildID)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to get the roles of the target guild. Plain role mentions will be left as-is.",
+			"guild_id", h.bot.TargetGuildID,
+			"err", err)
+		return body
+	}
+
+	ids := make(map[string]discord.RoleID, len(roles))
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		// The @everyone role shares its ID with the guild, and is never
+		// mentioned as a role.
+		if discord.Snowflake(role.ID) == discord.Snowflake(h.bot.TargetGuildID) || role.Name == "" {
+			continue
+		}
+		name := strings.ToLower(role.Name)
+		if _, dup := ids[name]; dup {
+			// Two roles with the same name can't be told apart.
+			ids[name] = 0
+			continue
+		}
+		ids[name] = role.ID
+
+		// Names that end in a word character must end there, so that
+		// "@Dev" doesn't match "@Developers".
+		quoted := regexp.QuoteMeta(role.Name)
+		if wordEnd.MatchString(role.Name) {
+			quoted += `\b`
+		}
+		names = append(names, quoted)
+	}
+	if len(names) == 0 {
+		return body
+	}
+
+	// The longest names are tried first, so that "@Dev Board" isn't taken
+	// for "@Dev".
+	slices.SortFunc(names, func(a, b string) int { return len(b) - len(a) })
+	pattern, err := regexp.Compile(`(?i)(^|[^\w<@/])@(` + strings.Join(names, "|") + `)`)
+	if err != nil {
+		return body
+	}
+
+	return outsideCode(body, func(text string) string {
+		return pattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := pattern.FindStringSubmatch(match)
+			if id := ids[strings.ToLower(m[2])]; id.IsValid() {
+				return m[1] + id.Mention()
+			}
+			return match
+		})
+	})
+}
+
+// resolveChannelMentions replaces plain-text references to the target
+// guild's channels, such as "#general", with their mention markup.
+func (h *commandHandler) resolveChannelMentions(body string) string {
+	if !plainChannelPattern.MatchString(body) {
+		return body
+	}
+
+	channels, err := h.session.Cabinet.Channels(h.bot.TargetGuildID)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to get the channels of the target guild. Plain channel references will be left as-is.",
+			"guild_id", h.bot.TargetGuildID,
+			"err", err)
+		return body
+	}
+
+	ids := make(map[string]discord.ChannelID, len(channels))
+	for _, ch := range channels {
+		// Categories can't be linked to.
+		if ch.Type == discord.GuildCategory {
+			continue
+		}
+		name := strings.ToLower(ch.Name)
+		if _, dup := ids[name]; dup {
+			ids[name] = 0
+			continue
+		}
+		ids[name] = ch.ID
+	}
+
+	return outsideCode(body, func(text string) string {
+		return plainChannelPattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := plainChannelPattern.FindStringSubmatch(match)
+			if id := ids[strings.ToLower(m[2])]; id.IsValid() {
+				return m[1] + id.Mention()
+			}
+			return match
+		})
+	})
+}
+
+// resolveUserMentions replaces plain-text mentions of the target guild's
+// members, such as "@alice", with their mention markup. Names that are
+// nobody's, or more than one member's, are left alone.
+func (h *commandHandler) resolveUserMentions(body string) string {
+	if !plainMentionPattern.MatchString(body) {
+		return body
+	}
+
+	members, err := h.session.Cabinet.Members(h.bot.TargetGuildID)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to get the members of the target guild. Plain mentions will be left as-is.",
+			"guild_id", h.bot.TargetGuildID,
+			"err", err)
+		return body
+	}
+
+	// Usernames are unique, so they win over the names that members pick
+	// for themselves.
+	usernames := make(map[string]discord.UserID, len(members))
+	names := make(map[string][]discord.UserID, len(members))
+	for _, member := range members {
+		usernames[strings.ToLower(member.User.Username)] = member.User.ID
+		for _, name := range []string{member.Nick, member.User.DisplayName} {
+			name = strings.ToLower(name)
+			if name != "" && !slices.Contains(names[name], member.User.ID) {
+				names[name] = append(names[name], member.User.ID)
+			}
+		}
+	}
+
+	resolve := func(name string) (discord.UserID, bool) {
+		if id, ok := usernames[name]; ok {
+			return id, true
+		}
+		if ids := names[name]; len(ids) == 1 {
+			return ids[0], true
+		}
+		return 0, false
+	}
+
+	return outsideCode(body, func(text string) string {
+		return plainMentionPattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := plainMentionPattern.FindStringSubmatch(match)
+
+			// Trailing dots are more likely the end of a sentence than a part
+			// of the name.
+			name := strings.TrimRight(m[2], ".")
+			rest := m[2][len(name):]
+
+			switch strings.ToLower(name) {
+			case "everyone", "here":
+				return match
+			}
+
+			if id, ok := resolve(strings.ToLower(name)); ok {
+				return m[1] + id.Mention() + rest
+			}
+			return match
+		})
+	})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsAddressEnv is the environment variable that holds the address to
+// serve metrics and the debug endpoint on, such as "localhost:9100". If it is
+// empty, neither is served.
+const metricsAddressEnv = "METRICS_ADDRESS"
+
+// durationBuckets are the upper bounds of the buckets that durations are
+// counted in, in seconds.
+var durationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Command outcomes, as recorded in the metrics.
+const (
+	outcomeOK       = "ok"
+	outcomeRejected = "rejected"
+	outcomeError    = "error"
+)
+
+// metrics holds the metrics of the bot. Metrics are recorded by the event loop
+// and read by the metrics server, so they are guarded by a mutex.
+type metrics struct {
+	gateway *gatewayMonitor
+
+	mu       sync.Mutex
+	commands map[commandOutcome]*durationHistogram
+}
+
+// commandOutcome labels the metrics of a command.
+type commandOutcome struct {
+	Command string
+	Outcome string
+}
+
+// durationHistogram counts durations into durationBuckets.
+type durationHistogram struct {
+	Buckets []int
+	Count   int
+	Sum     time.Duration
+}
+
+func newMetrics(gateway *gatewayMonitor) *metrics {
+	return &metrics{
+		gateway:  gateway,
+		commands: make(map[commandOutcome]*durationHistogram),
+	}
+}
+
+// observeCommand records that a command took the given time to handle.
+func (m *metrics) observeCommand(command, outcome string, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := commandOutcome{Command: command, Outcome: outcome}
+	hist, ok := m.commands[key]
+	if !ok {
+		hist = &durationHistogram{Buckets: make([]int, len(durationBuckets))}
+		m.commands[key] = hist
+	}
+
+	hist.Count++
+	hist.Sum += took
+	for i, bound := range durationBuckets {
+		if took.Seconds() <= bound {
+			hist.Buckets[i]++
+		}
+	}
+}
+
+// writeTo writes the metrics in the Prometheus text format.
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]commandOutcome, 0, len(m.commands))
+	for key := range m.commands {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b commandOutcome) int {
+		if a.Command != b.Command {
+			return strings.Compare(a.Command, b.Command)
+		}
+		return strings.Compare(a.Outcome, b.Outcome)
+	})
+
+	fmt.Fprintln(w, "# HELP message_for_me_command_duration_seconds How long commands took to handle.")
+	fmt.Fprintln(w, "# TYPE message_for_me_command_duration_seconds histogram")
+	for _, key := range keys {
+		hist := m.commands[key]
+		labels := fmt.Sprintf("command=%q,outcome=%q", key.Command, key.Outcome)
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(w, "message_for_me_command_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound, hist.Buckets[i])
+		}
+		fmt.Fprintf(w, "message_for_me_command_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, hist.Count)
+		fmt.Fprintf(w, "message_for_me_command_duration_seconds_sum{%s} %g\n", labels, hist.Sum.Seconds())
+		fmt.Fprintf(w, "message_for_me_command_duration_seconds_count{%s} %d\n", labels, hist.Count)
+	}
+
+	status := m.gateway.status()
+
+	var up int
+	if status.Alive {
+		up = 1
+	}
+
+	fmt.Fprintln(w, "# HELP message_for_me_gateway_up Whether the gateway is connected or reconnecting.")
+	fmt.Fprintln(w, "# TYPE message_for_me_gateway_up gauge")
+	fmt.Fprintf(w, "message_for_me_gateway_up %d\n", up)
+	fmt.Fprintln(w, "# HELP message_for_me_gateway_latency_seconds The round-trip time of the last acknowledged heartbeat.")
+	fmt.Fprintln(w, "# TYPE message_for_me_gateway_latency_seconds gauge")
+	fmt.Fprintf(w, "message_for_me_gateway_latency_seconds %g\n", status.Latency.Seconds())
+	fmt.Fprintln(w, "# HELP message_for_me_gateway_last_heartbeat_timestamp_seconds The time that the last heartbeat was acknowledged.")
+	fmt.Fprintln(w, "# TYPE message_for_me_gateway_last_heartbeat_timestamp_seconds gauge")
+	fmt.Fprintf(w, "message_for_me_gateway_last_heartbeat_timestamp_seconds %d\n", unixOrZero(status.EchoBeat))
+	fmt.Fprintln(w, "# HELP message_for_me_gateway_last_event_timestamp_seconds The time that the last event was received.")
+	fmt.Fprintln(w, "# TYPE message_for_me_gateway_last_event_timestamp_seconds gauge")
+	fmt.Fprintf(w, "message_for_me_gateway_last_event_timestamp_seconds %d\n", unixOrZero(status.LastEvent))
+}
+
+// unixOrZero returns the Unix time of t, or 0 if t is the zero time.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// serveMetrics serves the metrics on the given address until ctx is done. If
+// debug is non-nil, the debug endpoint is served alongside them.
+func serveMetrics(ctx context.Context, addr string, m *metrics, debug *debugHandler) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.writeTo(w)
+	})
+	if debug != nil {
+		mux.Handle("/debug/state", debug)
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	slog.Info(
+		"Bot is now serving its metrics.",
+		"address", addr)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("cannot serve metrics: %w", err)
+	}
+	return nil
+}
+
+// observeCommand records how the command went, both in the metrics and in the
+// debug log.
+func (h *commandHandler) observeCommand(inv *invocation, command *parsedCommand, took time.Duration) {
+	outcome := inv.outcome
+	if outcome == "" {
+		outcome = outcomeOK
+	}
+
+	h.metrics.observeCommand(command.Command, outcome, took)
+
+	slog.Debug(
+		"Bot has handled a command.",
+		"command", command.Command,
+		"outcome", outcome,
+		"took", took)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"libdb.so/persist"
+	persistbadgerdb "libdb.so/persist/driver/badgerdb"
+)
+
+// schemaDatabase is the database that records the schema version of every
+// other database in the state directory.
+const schemaDatabase = "schema-v1"
+
+// stateMigration upgrades a database to a new schema version in place.
+type stateMigration struct {
+	// Database is the name of the database, such as "last-sent-authors-v1".
+	Database string
+	// Version is the schema version that the migration upgrades to. The
+	// first schema version of every database is 1.
+	Version int
+	// Description describes what the migration changes.
+	Description string
+	// Migrate upgrades the database at path from the previous version.
+	Migrate func(path string) error
+}
+
+// stateMigrations lists every migration in the order that they are applied.
+// When the schema of a database changes, add a migration here instead of
+// starting a new directory. Most migrations convert each value using
+// rewriteDatabase, for example:
+//
+//	{
+//		Database:    "last-sent-authors-v1",
+//		Version:     2,
+//		Description: "keep a stack of announcements per author",
+//		Migrate: func(path string) error {
+//			return rewriteDatabase(path, func(_ discord.UserID, id discord.MessageID) ([]discord.MessageID, error) {
+//				return []discord.MessageID{id}, nil
+//			})
+//		},
+//	},
+var stateMigrations = []stateMigration{}
+
+// latestSchemaVersion returns the schema version of the database that this
+// version of the bot works with.
+func latestSchemaVersion(database string) int {
+	version := 1
+	for _, m := range stateMigrations {
+		if m.Database == database {
+			version = max(version, m.Version)
+		}
+	}
+	return version
+}
+
+// migrateStateDirectory brings every database in the state directory up to
+// its latest schema version. It returns the descriptions of the migrations
+// that were applied. The caller must hold the state lock.
+func migrateStateDirectory(dir string) ([]string, error) {
+	schema, err := persist.NewMap[string, int](
+		persistbadgerdb.Open,
+		filepath.Join(dir, schemaDatabase),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the schema database: %w", err)
+	}
+	defer closeStore("schema", schema)
+
+	var applied []string
+	for _, database := range stateDatabases {
+		if database == schemaDatabase {
+			continue
+		}
+
+		version, ok, err := schema.Load(database)
+		if err != nil {
+			return applied, fmt.Errorf("cannot load the schema version of %s: %w", database, err)
+		}
+
+		if !ok {
+			// Databases from before schema versions were recorded are at the
+			// first version. Databases that don't exist yet will be created
+			// at the latest one.
+			version = latestSchemaVersion(database)
+			if _, err := os.Stat(filepath.Join(dir, database)); err == nil {
+				version = 1
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return applied, err
+			}
+
+			if err := schema.Store(database, version); err != nil {
+				return applied, fmt.Errorf("cannot record the schema version of %s: %w", database, err)
+			}
+		}
+
+		if latest := latestSchemaVersion(database); version > latest {
+			return applied, fmt.Errorf(
+				"%s is at schema version %d, but this version of the bot only knows up to %d",
+				database, version, latest)
+		}
+
+		for _, m := range stateMigrations {
+			if m.Database != database || m.Version <= version {
+				continue
+			}
+
+			if err := m.Migrate(filepath.Join(dir, database)); err != nil {
+				return applied, fmt.Errorf("cannot migrate %s to version %d: %w", database, m.Version, err)
+			}
+
+			version = m.Version
+			if err := schema.Store(database, version); err != nil {
+				return applied, fmt.Errorf("cannot record the schema version of %s: %w", database, err)
+			}
+
+			applied = append(applied, fmt.Sprintf("%s v%d: %s", database, m.Version, m.Description))
+		}
+	}
+
+	return applied, nil
+}
+
+// rewriteDatabase converts every value in the database at path from Old to
+// New, keeping the keys.
+func rewriteDatabase[K comparable, Old, New any](path string, convert func(K, Old) (New, error)) error {
+	old, err := persist.NewMap[K, Old](persistbadgerdb.Open, path)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[K]Old)
+	old.All()(func(k K, v Old) bool {
+		values[k] = v
+		return true
+	})
+
+	if err := old.Close(); err != nil {
+		return err
+	}
+
+	db, err := persist.NewMap[K, New](persistbadgerdb.Open, path)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range values {
+		converted, err := convert(k, v)
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("cannot convert %v: %w", k, err)
+		}
+		if err := db.Store(k, converted); err != nil {
+			db.Close()
+			return err
+		}
+	}
+
+	return db.Close()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"libdb.so/persist"
+)
+
+// entryNamePattern matches the names that templates and snippets may be saved
+// under, such as "release" or "support-links".
+var entryNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// namedCommand is a command whose subcommands work on entries saved by name,
+// such as the template and snippet commands.
+type namedCommand struct {
+	// Kind is what the entries are called, such as "template".
+	Kind string
+	// Prefix is what names may be written with, such as "!" for snippets.
+	Prefix string
+	// Actions are the subcommands, in the order that they are listed.
+	Actions []namedAction
+	// Invalid is the reply to a subcommand that can't be run.
+	Invalid messageKey
+}
+
+// namedAction is a subcommand of a namedCommand. Every subcommand but "list"
+// is given the name of an entry.
+type namedAction struct {
+	Name string
+	Run  func(name string)
+}
+
+// runNamedCommand runs the subcommand that args pick, along with the name of
+// the entry that it works on, such as "save release".
+func (h *commandHandler) runNamedCommand(inv *invocation, args string, c namedCommand) {
+	action, name, _ := strings.Cut(args, " ")
+	action = strings.ToLower(action)
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), c.Prefix))
+
+	if action != "list" && !entryNamePattern.MatchString(name) {
+		sendRejection(h.session, inv, inv.textWith(c.Invalid, replyData{
+			Error: fmt.Errorf("the %s needs a name of up to 32 lowercase letters, digits and dashes", c.Kind),
+		}))
+		return
+	}
+
+	names := make([]string, len(c.Actions))
+	for i, a := range c.Actions {
+		if a.Name == action {
+			a.Run(name)
+			return
+		}
+		names[i] = a.Name
+	}
+
+	last := len(names) - 1
+	sendRejection(h.session, inv, inv.textWith(c.Invalid, replyData{
+		Error: errors.New("the action must be " + strings.Join(names[:last], ", ") + " or " + names[last]),
+	}))
+}
+
+// deleteNamed deletes the entry with the given name from db, and tells the
+// author whether there was one. kind is what the entries are called, such as
+// "template".
+func deleteNamed[T any](h *commandHandler, inv *invocation, db persist.Map[string, T], kind, name string, notFound, deleted messageKey) {
+	_, ok, err := db.Load(name)
+	if err == nil && ok {
+		err = db.Delete(name)
+	}
+	if err != nil {
+		slog.Error(
+			"Bot has failed to delete a saved entry.",
+			"kind", kind,
+			"name", name,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+	if !ok {
+		sendRejection(h.session, inv, inv.textWith(notFound, replyData{Name: name}))
+		return
+	}
+
+	h.acknowledge(inv, inv.textWith(deleted, replyData{Name: name}))
+}
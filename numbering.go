@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// numberLine returns the line below the body that shows the number of the
+// announcement, or an empty string if it isn't numbered.
+func (b botState) numberLine(number int) string {
+	if number == 0 {
+		return ""
+	}
+	return localize(b.locale(), msgAnnouncementNumber, replyData{Count: number})
+}
+
+// nextNumber takes the number of the next announcement, or returns 0 if
+// announcements aren't numbered.
+func (h *commandHandler) nextNumber() int {
+	if !h.bot.NumberAnnouncements {
+		return 0
+	}
+	h.bot.Runtime.LastNumber++
+	h.saveRuntime()
+	return h.bot.Runtime.LastNumber
+}
+
+// releaseNumber gives the number back if the announcement that took it
+// wasn't posted after all, so long as no other announcement has taken a
+// number since.
+func (h *commandHandler) releaseNumber(number int) {
+	if number != 0 && number == h.bot.Runtime.LastNumber {
+		h.bot.Runtime.LastNumber--
+		h.saveRuntime()
+	}
+}
+
+// parseAnnouncementNumber parses an announcement number, such as "142" or
+// "#142".
+func parseAnnouncementNumber(text string) (int, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "#")
+	n, err := strconv.Atoi(text)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not an announcement number", text)
+	}
+	return n, nil
+}
+
+// findNumbered returns the primary message of the announcement with the
+// given number, or false if there is none.
+func (h *commandHandler) findNumbered(number int) (discord.MessageID, announcementRecord, bool) {
+	var (
+		found  discord.MessageID
+		record announcementRecord
+	)
+	h.announcements.All()(func(id discord.MessageID, r announcementRecord) bool {
+		if r.Number == number {
+			found, record = id, r
+			return false
+		}
+		return true
+	})
+	return found, record, found.IsValid()
+}
+
+// getNumbered replies with a link to the announcement whose number is given
+// in the command's arguments.
+func (h *commandHandler) getNumbered(inv *invocation, command *parsedCommand) {
+	number, err := parseAnnouncementNumber(command.Args)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidNumber, replyData{Error: err}))
+		return
+	}
+
+	id, record, ok := h.findNumbered(number)
+	if !ok {
+		sendRejection(h.session, inv, inv.textWith(msgNumberNotFound, replyData{Count: number}))
+		return
+	}
+
+	sendReply(h.session, inv, inv.textWith(msgNumbered, replyData{
+		Count: number,
+		Link:  messageLink(h.bot.TargetGuildID, messageRef{ChannelID: record.ChannelID, MessageID: id}),
+	}))
+}
+
+// editNumbered edits the announcement whose number is given in the command's
+// arguments. Only its author and admins may edit it.
+func (h *commandHandler) editNumbered(inv *invocation, command *parsedCommand, opts announceOptions) {
+	number, err := parseAnnouncementNumber(command.Args)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidNumber, replyData{Error: err}))
+		return
+	}
+
+	id, record, ok := h.findNumbered(number)
+	if !ok {
+		sendRejection(h.session, inv, inv.textWith(msgNumberNotFound, replyData{Count: number}))
+		return
+	}
+
+	if record.AuthorID != inv.Author.ID {
+		if member := inv.member(); member != nil {
+			perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, member)
+			if err != nil || !isAdmin(*h.bot, member, perms) {
+				sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+				return
+			}
+		}
+	}
+
+	h.editAnnouncementBody(inv, id, command.Options, opts, command.Body, func() {
+		slog.Info(
+			"Bot has edited an announcement by its number.",
+			"author_id", record.AuthorID,
+			"edited_by", inv.Author.ID,
+			"number", number,
+			"message_id", id)
+	})
+}
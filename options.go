@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// frontMatterDelimiter delimits the front matter block of a command body.
+const frontMatterDelimiter = "---"
+
+// cutFrontMatter splits the front matter block out of a command body.
+// A front matter block is a list of options wrapped in delimiter lines at the
+// very top of the body, like so:
+//
+//	---
+//	title: Release v1.0
+//	---
+//	body
+//
+// If the body has no front matter, then options is empty and body is returned
+// as-is.
+func cutFrontMatter(body string) (options, rest string) {
+	first, after, ok := strings.Cut(body, "\n")
+	if !ok || strings.TrimSpace(first) != frontMatterDelimiter {
+		return "", body
+	}
+
+	var lines []string
+	for {
+		var line string
+		line, after, ok = strings.Cut(after, "\n")
+		if strings.TrimSpace(line) == frontMatterDelimiter {
+			return strings.Join(lines, "\n"), after
+		}
+		if !ok {
+			// The block was never closed, so it wasn't front matter after
+			// all.
+			return "", body
+		}
+		lines = append(lines, line)
+	}
+}
+
+// announceOptions holds the per-announcement options. They are given either
+// through the front matter of the body or through the options field of the
+// announcement modal.
+type announceOptions struct {
+	// Title is the title of the announcement. It is rendered as a heading
+	// above the body.
+	Title string
+	// Channels are the channels to post the announcement in. If empty, the
+	// author is asked to pick, or the target channel is used.
+	Channels []discord.ChannelID
+	// DeleteCommand overrides the DeleteCommands setting if non-nil.
+	DeleteCommand *bool
+	// NoPing skips pinging AnnounceRoleID.
+	NoPing bool
+	// MassMention confirms that the body is meant to mention everyone or
+	// more than MaxMentions users and roles.
+	MassMention bool
+	// IgnoreWarnings confirms that the announcement should be sent despite
+	// formatting warnings.
+	IgnoreWarnings bool
+	// ReuploadMedia uploads the media that the body links to on lines of
+	// their own as attachments, instead of leaving Discord to embed them
+	// from their host. It only applies to new announcements.
+	ReuploadMedia bool
+	// NoEmbeds suppresses the previews of the links in the body. Embeds
+	// given in the body are suppressed too.
+	NoEmbeds bool
+	// Silent posts the announcement without push or desktop notifications,
+	// like messages starting with @silent do. Role pings are still shown.
+	Silent bool
+	// TTS posts the announcement as a text-to-speech message. Only members
+	// with one of the TTSRoleIDs may use it.
+	TTS bool
+	// SendAt is when the announcement is sent, if it is scheduled for later
+	// using "send-at". If zero, it is sent right away.
+	SendAt time.Time
+	// DeleteAt is when the announcement deletes itself, along with its
+	// copies and translations. If zero, it stays up.
+	DeleteAt time.Time
+	// Sticky keeps the announcement at the bottom of its channels by posting
+	// it again when other messages push it up.
+	Sticky bool
+	// BumpAfter is how long after the announcement is posted that the bot
+	// links to it once more. If zero, it isn't bumped.
+	BumpAfter time.Duration
+	// Slowmode is the slowmode that the channels of the announcement get for
+	// a while after it is posted, so that the replies don't bury it. If
+	// zero, the slowmode is left alone.
+	Slowmode time.Duration
+	// Critical marks the announcement as high-impact using "severity:
+	// critical", so that it needs the sign-off of ApproverRoleIDs.
+	Critical bool
+}
+
+// parseAnnounceOptions parses the given options of the format
+//
+//	key: value
+//
+// with each option on its own line. Blank lines are ignored. Unknown keys are
+// rejected.
+func parseAnnounceOptions(text string) (announceOptions, error) {
+	var opts announceOptions
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return opts, fmt.Errorf("option %q is missing a value", line)
+		}
+
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+
+		switch k {
+		case "title":
+			opts.Title = v
+		case "channels":
+			channelIDs, err := parseChannelMentions(v)
+			if err != nil {
+				return opts, fmt.Errorf("option %q is invalid: %w", k, err)
+			}
+			opts.Channels = channelIDs
+		case "delete-command":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.DeleteCommand = &b
+		case "ping":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.NoPing = !b
+		case "mass-mention":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.MassMention = b
+		case "delete-at":
+			t, err := parseHumanTime(v, time.Now().In(settings.timezone()))
+			if err != nil {
+				return opts, fmt.Errorf("option %q is invalid: %w", k, err)
+			}
+			if !t.After(time.Now()) {
+				return opts, fmt.Errorf("option %q must be in the future", k)
+			}
+			opts.DeleteAt = t
+		case "send-at":
+			t, err := parseHumanTime(v, time.Now().In(settings.timezone()))
+			if err != nil {
+				return opts, fmt.Errorf("option %q is invalid: %w", k, err)
+			}
+			if !t.After(time.Now()) {
+				return opts, fmt.Errorf("option %q must be in the future", k)
+			}
+			opts.SendAt = t
+		case "bump-after":
+			d, err := parseLongDuration(v)
+			if err != nil {
+				return opts, fmt.Errorf("option %q is invalid: %w", k, err)
+			}
+			if d < minBumpAfter || d > maxBumpAfter {
+				return opts, fmt.Errorf("option %q must be between %d hour and %d days", k, minBumpAfter/time.Hour, maxBumpAfter/(24*time.Hour))
+			}
+			opts.BumpAfter = d
+		case "slowmode":
+			d, err := parseLongDuration(v)
+			if err != nil {
+				return opts, fmt.Errorf("option %q is invalid: %w", k, err)
+			}
+			if d < time.Second || d > maxSlowmode {
+				return opts, fmt.Errorf("option %q must be between 1 second and %d hours", k, maxSlowmode/time.Hour)
+			}
+			opts.Slowmode = d
+		case "severity":
+			switch strings.ToLower(v) {
+			case "normal":
+				opts.Critical = false
+			case "critical":
+				opts.Critical = true
+			default:
+				return opts, fmt.Errorf("option %q must be normal or critical", k)
+			}
+		case "sticky":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.Sticky = b
+		case "ignore-warnings":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.IgnoreWarnings = b
+		case "reupload-media":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.ReuploadMedia = b
+		case "noembed":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.NoEmbeds = b
+		case "silent":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.Silent = b
+		case "tts":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return opts, err
+			}
+			opts.TTS = b
+		default:
+			return opts, fmt.Errorf("unknown option %q", k)
+		}
+	}
+
+	if !opts.SendAt.IsZero() && !opts.DeleteAt.IsZero() && !opts.DeleteAt.After(opts.SendAt) {
+		return opts, errors.New(`option "delete-at" must be after "send-at"`)
+	}
+
+	return opts, nil
+}
+
+// setsOption returns true if the options give the key, whatever its value.
+func setsOption(text, key string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		k, _, ok := strings.Cut(line, ":")
+		if ok && strings.ToLower(strings.TrimSpace(k)) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func parseBoolOption(k, v string) (bool, error) {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("option %q must be true or false", k)
+	}
+	return b, nil
+}
+
+// renderAnnouncement renders the final message of an announcement.
+func (b botState) renderAnnouncement(opts announceOptions, body string) (announcementMessage, error) {
+	body, embeds, err := cutEmbeds(body)
+	if err != nil {
+		return announcementMessage{}, err
+	}
+
+	if opts.Title != "" {
+		body = "# " + opts.Title + "\n" + body
+	}
+	if b.AnnounceRoleID.IsValid() && !opts.NoPing {
+		body = b.AnnounceRoleID.Mention() + "\n" + body
+	}
+
+	var flags discord.MessageFlags
+	if opts.NoEmbeds {
+		flags |= discord.SuppressEmbeds
+	}
+	if opts.Silent {
+		flags |= discord.SuppressNotifications
+	}
+
+	return announcementMessage{
+		Content:  body,
+		Embeds:   embeds,
+		Flags:    flags,
+		TTS:      opts.TTS,
+		Mentions: b.allowedMentions(opts),
+	}, nil
+}
+
+// allowedMentions returns the mentions that an announcement may ping. Only
+// AnnounceRoleID pings, unless the author has confirmed that the body is
+// meant to ping everyone it mentions, since screening only lets through
+// mentions that were confirmed.
+func (b botState) allowedMentions(opts announceOptions) *api.AllowedMentions {
+	if opts.MassMention {
+		return &api.AllowedMentions{
+			Parse: []api.AllowedMentionType{api.AllowEveryoneMention, api.AllowUserMention, api.AllowRoleMention},
+		}
+	}
+
+	mentions := &api.AllowedMentions{}
+	if b.AnnounceRoleID.IsValid() && !opts.NoPing {
+		mentions.Roles = []discord.RoleID{b.AnnounceRoleID}
+	}
+	return mentions
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// alertWebhookEnv is the environment variable that holds the URL of the
+// webhook that outage alerts are posted to. It is kept out of the config file
+// since the URL is a secret.
+const alertWebhookEnv = "ALERT_WEBHOOK_URL"
+
+// outageCheckInterval is how often the gateway connection is checked for an
+// outage.
+const outageCheckInterval = 30 * time.Second
+
+// lastSignOfLife returns when the gateway was last known to be reachable.
+func (s gatewayStatus) lastSignOfLife() time.Time {
+	if s.EchoBeat.After(s.LastEvent) {
+		return s.EchoBeat
+	}
+	return s.LastEvent
+}
+
+// watchOutages posts an alert to the webhook once the gateway has been
+// unreachable for longer than after, and another once it is reachable again.
+// The webhook is called directly rather than through the bot's session, since
+// the session is what isn't working.
+func watchOutages(ctx context.Context, url string, after time.Duration, monitor *gatewayMonitor) {
+	started := time.Now()
+
+	ticker := time.NewTicker(outageCheckInterval)
+	defer ticker.Stop()
+
+	var down bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		last := monitor.status().lastSignOfLife()
+		if last.IsZero() {
+			last = started
+		}
+		age := time.Since(last)
+
+		switch {
+		case !down && age > after:
+			down = true
+			slog.Error(
+				"Bot has been unable to reach the gateway for too long. It will alert the webhook.",
+				"since", last)
+			sendAlert(ctx, url, msgOutage, replyData{Duration: age.Round(time.Second)})
+
+		case down && age <= after:
+			down = false
+			slog.Info("Bot can reach the gateway again.")
+			sendAlert(ctx, url, msgOutageOver, replyData{})
+		}
+	}
+}
+
+// sendAlert posts the message to the webhook. The payload is understood by
+// both Discord and Slack webhooks.
+func sendAlert(ctx context.Context, url string, key messageKey, data replyData) {
+	text := localize(settings.Locale, key, data)
+	payload := struct {
+		Content string `json:"content"`
+		Text    string `json:"text"`
+	}{text, text}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := postJSON(ctx, url, nil, payload, nil); err != nil {
+		slog.Error(
+			"Bot has failed to post an alert to the webhook.",
+			"key", key,
+			"err", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// outboxEntry is an announcement that has been accepted but not yet fully
+// sent. It is keyed by the announcement's idempotency key.
+type outboxEntry struct {
+	AuthorID   discord.UserID
+	Body       string
+	Options    announceOptions
+	ChannelIDs []discord.ChannelID
+	Created    time.Time
+	// Sent are the messages that have been sent so far.
+	Sent []messageRef
+	// Staged is the message in the staging channel that the announcement was
+	// promoted from, if it was staged.
+	Staged *messageRef
+	// Attachments are the files to post along with the body.
+	Attachments []discord.Attachment
+	// Number is the number that the announcement was given, if any.
+	Number int
+}
+
+// maxOutboxAge is how old an outbox entry may get before the bot gives up on
+// resuming it.
+const maxOutboxAge = 24 * time.Hour
+
+func (h *commandHandler) storeOutbox(id string, entry *outboxEntry) {
+	if err := h.outbox.Store(id, *entry); err != nil {
+		slog.Warn(
+			"Bot has failed to write the announcement to the outbox. It will be lost if the bot dies before sending it.",
+			"author_id", entry.AuthorID,
+			"err", err)
+	}
+}
+
+func (h *commandHandler) deleteOutbox(id string) {
+	if err := h.outbox.Delete(id); err != nil {
+		slog.Warn(
+			"Bot has failed to remove the announcement from the outbox. It may be sent again on restart.",
+			"err", err)
+	}
+}
+
+// deliverOutbox sends the outbox entry into every channel that it hasn't
+// been sent to yet, recording each message as it goes, and then calls done.
+// inv is the command waiting on it, or nil if nobody is. The cooldown holds
+// until done is called.
+func (h *commandHandler) deliverOutbox(inv *invocation, id string, entry *outboxEntry, msg announcementMessage, done func()) {
+	h.bot.Announcing++
+
+	var next func(i int)
+	next = func(i int) {
+		for i < len(entry.ChannelIDs) && slices.ContainsFunc(entry.Sent, func(ref messageRef) bool {
+			return ref.ChannelID == entry.ChannelIDs[i]
+		}) {
+			i++
+		}
+		if i == len(entry.ChannelIDs) {
+			h.bot.Announcing--
+			done()
+			return
+		}
+
+		channelID := entry.ChannelIDs[i]
+
+		var target *discord.Message
+		h.retry("send announcement", inv, func() (err error) {
+			target, err = h.sendAnnouncement(channelID, msg, entry.Options)
+			return err
+		}, func(err error) {
+			if err != nil {
+				slog.Error(
+					"Bot has failed to send the announcement message.",
+					"channel_id", channelID,
+					"err", err)
+			} else {
+				// Forum posts are sent into a new thread rather than the
+				// forum.
+				entry.Sent = append(entry.Sent, messageRef{ChannelID: target.ChannelID, MessageID: target.ID})
+				h.storeOutbox(id, entry)
+			}
+			next(i + 1)
+		})
+	}
+	next(0)
+}
+
+// finishAnnouncement records a sent announcement and removes it from the
+// outbox. msg is what was sent.
+func (h *commandHandler) finishAnnouncement(id string, entry *outboxEntry, msg announcementMessage) {
+	sent := entry.Sent
+
+	// Update the last announcement time.
+	h.bot.LastAnnouncedTime = time.Now()
+	h.stats.Announcements++
+
+	record := announcementRecord{
+		AuthorID:  entry.AuthorID,
+		ChannelID: sent[0].ChannelID,
+		Copies:    sent[1:],
+		Time:      h.bot.LastAnnouncedTime,
+		Staged:    entry.Staged,
+		Body:      entry.Body,
+		Options:   entry.Options,
+		Number:    entry.Number,
+		Revisions: []announcementRevision{{
+			Time:    h.bot.LastAnnouncedTime,
+			Content: msg.Content,
+		}},
+	}
+
+	if err := h.announcements.Store(sent[0].MessageID, record); err != nil {
+		slog.Warn(
+			"Bot has failed to store the announcement record.",
+			"message_id", sent[0].MessageID,
+			"err", err)
+	}
+	h.indexAnnouncement(sent[0].MessageID, record)
+	h.postTranslations(sent[0].MessageID, entry.Body, entry.Options)
+
+	if entry.Options.Sticky {
+		h.setSticky(sent[0].MessageID, sent, true)
+	}
+
+	if entry.Options.BumpAfter > 0 {
+		h.scheduleBump(sent[0].MessageID, record.Time.Add(entry.Options.BumpAfter))
+	}
+
+	if entry.Options.Slowmode > 0 {
+		h.applySlowmode(sent, entry.Options.Slowmode)
+	}
+
+	if !entry.Options.DeleteAt.IsZero() {
+		h.scheduleDeletion(sent[0].MessageID, entry.Options.DeleteAt)
+	}
+
+	// Store the last message sent by the author.
+	if err := h.lastSentAuthors.Store(entry.AuthorID, sent[0].MessageID); err != nil {
+		slog.Warn(
+			"Bot has failed to store the last message sent by the author.",
+			"author_id", entry.AuthorID,
+			"err", err)
+	}
+
+	h.deleteOutbox(id)
+	h.updatePresence()
+
+	if h.bot.SubscribePrompt && h.bot.AnnounceRoleID.IsValid() {
+		h.repostSubscribePrompt()
+	}
+}
+
+// resumeOutbox sends every announcement that was left in the outbox, such as
+// when the bot died while sending it.
+func (h *commandHandler) resumeOutbox() {
+	type item struct {
+		id    string
+		entry outboxEntry
+	}
+
+	var items []item
+	h.outbox.All()(func(id string, entry outboxEntry) bool {
+		items = append(items, item{id, entry})
+		return true
+	})
+
+	for _, item := range items {
+		id, entry := item.id, &item.entry
+
+		if time.Since(entry.Created) > maxOutboxAge {
+			slog.Error(
+				"Bot has given up on an announcement that was left in the outbox for too long.",
+				"author_id", entry.AuthorID,
+				"created", entry.Created,
+				"body", entry.Body)
+
+			h.deleteOutbox(id)
+			continue
+		}
+
+		msg, err := h.bot.renderAnnouncement(entry.Options, withFooter(entry.Body, h.bot.numberLine(entry.Number)))
+		if err != nil {
+			slog.Error(
+				"Bot has failed to render an announcement in the outbox. It will be dropped.",
+				"author_id", entry.AuthorID,
+				"err", err)
+
+			h.deleteOutbox(id)
+			continue
+		}
+
+		msg.Attachments = entry.Attachments
+
+		h.downloadThen(nil, entry.Attachments, func(files []attachmentFile, err error) {
+			if err != nil {
+				slog.Error(
+					"Bot has failed to download the attachments of an announcement in the outbox. It will try again on the next start.",
+					"author_id", entry.AuthorID,
+					"err", err)
+				return
+			}
+
+			msg.Files = files
+
+			h.deliverOutbox(nil, id, entry, msg, func() {
+				if len(entry.Sent) == 0 {
+					slog.Error(
+						"Bot has failed to resume an announcement in the outbox. It will try again on the next start.",
+						"author_id", entry.AuthorID)
+					return
+				}
+
+				h.finishAnnouncement(id, entry, msg)
+
+				slog.Info(
+					"Bot has resumed an announcement that was left in the outbox.",
+					"author_id", entry.AuthorID,
+					"message_id", entry.Sent[0].MessageID)
+			})
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/ningen/v3"
+)
+
+// permissionNames maps the names of the permissions that make sense to grant
+// access by to their bits. Names are matched case-insensitively, ignoring
+// spaces.
+var permissionNames = map[string]discord.Permissions{
+	"administrator":   discord.PermissionAdministrator,
+	"manageguild":     discord.PermissionManageGuild,
+	"managechannels":  discord.PermissionManageChannels,
+	"manageroles":     discord.PermissionManageRoles,
+	"managemessages":  discord.PermissionManageMessages,
+	"mentioneveryone": discord.PermissionMentionEveryone,
+	"moderatemembers": discord.PermissionModerateMembers,
+	"kickmembers":     discord.PermissionKickMembers,
+	"banmembers":      discord.PermissionBanMembers,
+}
+
+// configPermissions is a set of permissions that is written as a list of
+// names in the config file, such as ["Manage Guild", "Manage Messages"].
+type configPermissions discord.Permissions
+
+func (p *configPermissions) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return fmt.Errorf("permissions must be written as a list of names: %w", err)
+	}
+
+	var perms discord.Permissions
+	for _, name := range names {
+		perm, ok := permissionNames[strings.ToLower(strings.ReplaceAll(name, " ", ""))]
+		if !ok {
+			return fmt.Errorf("unknown permission %q", name)
+		}
+		perms |= perm
+	}
+
+	*p = configPermissions(perms)
+	return nil
+}
+
+// memberPermissions calculates the permissions of the member in the target
+// channel from their roles and the channel's overwrites. It only uses what is
+// in the cache. If the bot doesn't authorize by permissions, then it does
+// nothing and returns zero.
+func memberPermissions(session *ningen.State, bot botState, userID discord.UserID, member *discord.Member) (discord.Permissions, error) {
+	if bot.AllowedPermissions == 0 {
+		return 0, nil
+	}
+
+	guild, err := session.Cabinet.Guild(bot.TargetGuildID)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get the target guild: %w", err)
+	}
+
+	roles, err := session.Cabinet.Roles(bot.TargetGuildID)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get the roles of the target guild: %w", err)
+	}
+
+	channel, err := session.Cabinet.Channel(bot.TargetChannelID)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get the target channel: %w", err)
+	}
+
+	// Members in message events don't carry their user.
+	m := *member
+	m.User.ID = userID
+
+	return discord.CalcOverrides(*guild, *channel, m, roles), nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+)
+
+// Discord's limits on polls.
+const (
+	maxPollQuestionLength = 300
+	maxPollAnswerLength   = 55
+	maxPollAnswers        = 10
+	maxPollDuration       = 32 * 24 * time.Hour
+	defaultPollDuration   = 24 * time.Hour
+)
+
+// pollRequest is a poll parsed from a command.
+type pollRequest struct {
+	Question string
+	Answers  []string
+	// Duration is how long the poll stays open. Discord only takes whole
+	// hours.
+	Duration    time.Duration
+	Multiselect bool
+}
+
+// parsePoll parses a poll from the body of a command. The first line of the
+// body is the question and every line after it is an answer, optionally
+// written as a list item. The options may set these keys:
+//
+//	duration: 3d
+//	multiselect: true
+func parsePoll(options, body string) (pollRequest, error) {
+	poll := pollRequest{Duration: defaultPollDuration}
+
+	for _, line := range strings.Split(options, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return poll, fmt.Errorf("option %q is missing a value", line)
+		}
+
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+
+		switch k {
+		case "duration":
+			d, err := parsePollDuration(v)
+			if err != nil {
+				return poll, fmt.Errorf("option %q is invalid: %w", k, err)
+			}
+			poll.Duration = d
+		case "multiselect":
+			b, err := parseBoolOption(k, v)
+			if err != nil {
+				return poll, err
+			}
+			poll.Multiselect = b
+		default:
+			return poll, fmt.Errorf("unknown option %q", k)
+		}
+	}
+
+	question, rest, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	poll.Question = strings.TrimSpace(question)
+	if poll.Question == "" {
+		return poll, errors.New("the poll has no question")
+	}
+	if n := utf8.RuneCountInString(poll.Question); n > maxPollQuestionLength {
+		return poll, fmt.Errorf("the question is %d characters long, but only %d are allowed", n, maxPollQuestionLength)
+	}
+
+	for _, line := range strings.Split(rest, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*"))
+		if line == "" {
+			continue
+		}
+		if n := utf8.RuneCountInString(line); n > maxPollAnswerLength {
+			return poll, fmt.Errorf("the answer %q is %d characters long, but only %d are allowed", line, n, maxPollAnswerLength)
+		}
+		poll.Answers = append(poll.Answers, line)
+	}
+
+	switch {
+	case len(poll.Answers) == 0:
+		return poll, errors.New("the poll has no answers")
+	case len(poll.Answers) > maxPollAnswers:
+		return poll, fmt.Errorf("the poll has %d answers, but only %d are allowed", len(poll.Answers), maxPollAnswers)
+	}
+
+	return poll, nil
+}
+
+// parsePollDuration parses how long a poll lasts, such as "12h" or "3d".
+func parsePollDuration(v string) (time.Duration, error) {
+	d, err := parseLongDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration", v)
+	}
+	if d < time.Hour || d > maxPollDuration {
+		return 0, fmt.Errorf("polls must last between 1 hour and %d days", maxPollDuration/(24*time.Hour))
+	}
+	return d, nil
+}
+
+// pollMessageData is the body of a request that creates a message with a
+// poll. The API client doesn't know about polls, so this is sent as-is.
+type pollMessageData struct {
+	Poll struct {
+		Question struct {
+			Text string `json:"text"`
+		} `json:"question"`
+		Answers []pollAnswer `json:"answers"`
+		// Duration is in hours.
+		Duration         int  `json:"duration"`
+		AllowMultiselect bool `json:"allow_multiselect"`
+	} `json:"poll"`
+	AllowedMentions *api.AllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+type pollAnswer struct {
+	PollMedia struct {
+		Text string `json:"text"`
+	} `json:"poll_media"`
+}
+
+// sendPoll sends the poll into the channel.
+func (h *commandHandler) sendPoll(channelID discord.ChannelID, poll pollRequest) (*discord.Message, error) {
+	var data pollMessageData
+	data.Poll.Question.Text = poll.Question
+	data.Poll.Duration = int(poll.Duration / time.Hour)
+	data.Poll.AllowMultiselect = poll.Multiselect
+	for _, answer := range poll.Answers {
+		var a pollAnswer
+		a.PollMedia.Text = answer
+		data.Poll.Answers = append(data.Poll.Answers, a)
+	}
+	// Polls should never ping anyone.
+	data.AllowedMentions = &api.AllowedMentions{}
+
+	var msg *discord.Message
+	return msg, h.session.RequestJSON(
+		&msg, "POST",
+		api.EndpointChannels+channelID.String()+"/messages",
+		httputil.WithJSONBody(data),
+	)
+}
+
+func (h *commandHandler) poll(inv *invocation, command *parsedCommand) {
+	// Polls share the announcement cooldown.
+	if remaining := h.bot.cooldownRemaining(); remaining > 0 && !h.bot.cooldownExempt(inv.member()) {
+		sendRejection(h.session, inv, inv.textWith(msgCooldown, replyData{Remaining: remaining.Round(time.Second)}))
+		return
+	}
+
+	body, ok := h.screenSignedBody(inv, command.Options, command.Body)
+	if !ok {
+		return
+	}
+
+	poll, err := parsePoll(command.Options, body)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidPoll, replyData{Error: err}))
+		return
+	}
+
+	// Exemptions from the cooldown are only noted once the poll is sure to
+	// be sent. The cooldown holds while it is being sent.
+	if !h.checkCooldown(inv) {
+		return
+	}
+
+	var msg *discord.Message
+	h.bot.Announcing++
+	h.retry("send poll", inv, func() (err error) {
+		msg, err = h.sendPoll(h.bot.TargetChannelID, poll)
+		return err
+	}, func(err error) {
+		h.bot.Announcing--
+		if err != nil {
+			slog.Error(
+				"Bot has failed to send the poll.",
+				"channel_id", h.bot.TargetChannelID,
+				"ref", inv.errorRef(),
+				"err", err)
+
+			replyInternalError(h.session, inv)
+			return
+		}
+
+		h.bot.LastAnnouncedTime = time.Now()
+
+		ref := messageRef{ChannelID: msg.ChannelID, MessageID: msg.ID}
+		h.acknowledge(inv, inv.textWith(msgPolled, replyData{Link: messageLink(h.bot.TargetGuildID, ref)}))
+
+		if inv.Message != nil {
+			h.deleteCommand(messageRef{ChannelID: inv.ChannelID, MessageID: inv.Message.ID}, announceOptions{})
+		}
+	})
+}
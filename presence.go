@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+const (
+	// defaultPresenceInterval is how often the presence is rotated if
+	// RotateEvery isn't set.
+	defaultPresenceInterval = 5 * time.Minute
+	// minPresenceInterval is the shortest RotateEvery allowed. Discord only
+	// takes a handful of presence updates a minute.
+	minPresenceInterval = time.Minute
+	// presenceTimeout is how long a presence update may take to send.
+	presenceTimeout = 10 * time.Second
+)
+
+// presenceSettings configures what the bot shows as its status.
+type presenceSettings struct {
+	// Status is the bot's status, either "online", "idle", "dnd" or
+	// "invisible". If empty, the bot is idle and marked as AFK, so that it
+	// doesn't block notifications from arriving on a user account.
+	Status discord.Status
+	// ActivityType is how the activities are shown, either "playing",
+	// "streaming", "listening", "watching", "competing" or "custom". If
+	// empty, "custom" is used.
+	ActivityType string
+	// Activities are the texts shown under the bot's name. Each is a
+	// text/template template executed with the fields of presenceData, such
+	// as "next announcement window opens in {{.Cooldown}}". The bot rotates
+	// through them in order. If empty, no activity is shown.
+	Activities []string
+	// RotateEvery is how often the bot moves on to the next activity. The
+	// current one is refreshed as well, so that templates stay up to date.
+	// If zero, it is 5 minutes.
+	RotateEvery time.Duration
+}
+
+// UnmarshalJSON unmarshals the presence settings, taking RotateEvery as a
+// string such as "5m".
+func (s *presenceSettings) UnmarshalJSON(b []byte) error {
+	type rawSettings presenceSettings
+	aux := struct {
+		*rawSettings
+		RotateEvery *configDuration
+	}{
+		rawSettings: (*rawSettings)(s),
+		RotateEvery: (*configDuration)(&s.RotateEvery),
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(&aux)
+}
+
+// activityTypes maps the names used in ActivityType to their types.
+var activityTypes = map[string]discord.ActivityType{
+	"playing":   discord.GameActivity,
+	"streaming": discord.StreamingActivity,
+	"listening": discord.ListeningActivity,
+	"watching":  discord.WatchingActivity,
+	"competing": discord.CompetingActivity,
+	"custom":    discord.CustomActivity,
+}
+
+// presenceStatuses are the statuses that Status may be set to.
+var presenceStatuses = []discord.Status{
+	discord.OnlineStatus,
+	discord.IdleStatus,
+	discord.DoNotDisturbStatus,
+	discord.InvisibleStatus,
+}
+
+// interval returns how often the presence is rotated.
+func (s presenceSettings) interval() time.Duration {
+	if s.RotateEvery > 0 {
+		return s.RotateEvery
+	}
+	return defaultPresenceInterval
+}
+
+// command returns the presence update that shows the given activity text. An
+// empty text shows no activity.
+func (s presenceSettings) command(text string) *gateway.UpdatePresenceCommand {
+	cmd := &gateway.UpdatePresenceCommand{
+		Status: s.Status,
+		// Mark that the bot is perpetually AFK so that it doesn't block any
+		// notifications from arriving.
+		AFK: s.Status == "" || s.Status == discord.IdleStatus,
+	}
+	if cmd.Status == "" {
+		cmd.Status = discord.IdleStatus
+	}
+
+	if text != "" {
+		activityType := discord.CustomActivity
+		if s.ActivityType != "" {
+			activityType = activityTypes[s.ActivityType]
+		}
+
+		activity := discord.Activity{Name: text, Type: activityType}
+		if activityType == discord.CustomActivity {
+			activity.Name = "Custom Status"
+			activity.State = text
+		}
+		cmd.Activities = []discord.Activity{activity}
+	}
+
+	return cmd
+}
+
+// presenceData is the data that activity templates are executed with.
+type presenceData struct {
+	// Cooldown is the time until the next announcement may be sent. It is
+	// zero if one may be sent right away.
+	Cooldown time.Duration
+	// Paused is true if announcements are paused.
+	Paused bool
+	// PauseReason is the reason that announcements are paused, if any.
+	PauseReason string
+}
+
+// rotatePresence moves on to the next activity and shows it.
+func (h *commandHandler) rotatePresence() {
+	if len(h.bot.Presence.Activities) == 0 {
+		return
+	}
+	h.presenceIndex = (h.presenceIndex + 1) % len(h.bot.Presence.Activities)
+	h.updatePresence()
+}
+
+// updatePresence shows the current activity, filled in with the bot's state.
+func (h *commandHandler) updatePresence() {
+	activities := h.bot.Presence.Activities
+	if len(activities) == 0 {
+		return
+	}
+
+	data := presenceData{
+		Paused:      h.bot.Runtime.Paused,
+		PauseReason: h.bot.Runtime.PauseReason,
+	}
+	if remaining := h.bot.cooldownRemaining(); remaining > 0 {
+		data.Cooldown = remaining.Round(time.Minute)
+	}
+
+	text, err := renderActivity(activities[h.presenceIndex], data)
+	if err != nil {
+		// This was already checked when the settings were loaded.
+		slog.Warn(
+			"Bot has failed to render its activity.",
+			"index", h.presenceIndex,
+			"err", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+
+	if err := h.session.SendGateway(ctx, h.bot.Presence.command(text)); err != nil {
+		slog.Warn(
+			"Bot has failed to update its presence.",
+			"err", err)
+	}
+}
+
+// renderActivity executes the activity template with the given data.
+func renderActivity(text string, data presenceData) (string, error) {
+	tmpl, err := template.New("activity").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// processorAPIKeyEnv is the environment variable that holds the API key of the
+// language model used by the "tldr" processor.
+const processorAPIKeyEnv = "PROCESSOR_API_KEY"
+
+// processTimeout is how long the whole processor pipeline may take for a
+// single announcement. It is kept short, since the bot handles nothing else
+// while it waits.
+const processTimeout = 5 * time.Second
+
+// processorSettings configures one step of the processor pipeline.
+type processorSettings struct {
+	// Name is the processor to run. It is either "tidy", which trims trailing
+	// spaces and squashes runs of blank lines, or "tldr", which appends a
+	// one-line summary written by a language model.
+	Name string
+	// URL is the base URL of an OpenAI-compatible API, such as
+	// "https://api.openai.com". It is only used by "tldr".
+	URL string
+	// Model is the model to ask for the summary. It is only used by "tldr".
+	Model string
+	// Prompt overrides the instructions given to the model. It is only used
+	// by "tldr".
+	Prompt string
+}
+
+// processor rewrites the body of an announcement before it is posted.
+type processor interface {
+	Process(ctx context.Context, body string) (string, error)
+}
+
+// newProcessors creates the processor pipeline configured in the settings.
+// An empty pipeline leaves bodies as they are.
+func newProcessors(steps []processorSettings) ([]processor, error) {
+	processors := make([]processor, 0, len(steps))
+	for i, step := range steps {
+		switch step.Name {
+		case "tidy":
+			processors = append(processors, tidyProcessor{})
+		case "tldr":
+			apiKey := os.Getenv(processorAPIKeyEnv)
+			if apiKey == "" {
+				return nil, fmt.Errorf("processor %d: tldr requires $%s to be set", i, processorAPIKeyEnv)
+			}
+			prompt := step.Prompt
+			if prompt == "" {
+				prompt = defaultTLDRPrompt
+			}
+			processors = append(processors, tldrProcessor{
+				url:    step.URL,
+				model:  step.Model,
+				prompt: prompt,
+				apiKey: apiKey,
+			})
+		default:
+			return nil, fmt.Errorf("processor %d: unknown processor %q", i, step.Name)
+		}
+	}
+	return processors, nil
+}
+
+// processBody runs the body through the processor pipeline. A processor that
+// fails is skipped, so that a flaky API never stops an announcement.
+func (h *commandHandler) processBody(body string) string {
+	if len(h.processors) == 0 {
+		return body
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), processTimeout)
+	defer cancel()
+
+	for i, p := range h.processors {
+		processed, err := p.Process(ctx, body)
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to run a processor on the announcement. It is skipped.",
+				"processor", h.bot.Processors[i].Name,
+				"err", err)
+			continue
+		}
+		body = processed
+	}
+
+	return body
+}
+
+var blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+
+// tidyProcessor trims trailing spaces off every line and squashes runs of
+// blank lines into one.
+type tidyProcessor struct{}
+
+func (tidyProcessor) Process(ctx context.Context, body string) (string, error) {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	body = strings.Join(lines, "\n")
+	body = blankLinesPattern.ReplaceAllString(body, "\n\n")
+	return strings.TrimSpace(body), nil
+}
+
+const defaultTLDRPrompt = "Summarize the following announcement in one short sentence. " +
+	"Reply with only the summary."
+
+// tldrProcessor appends a TL;DR line written by a language model behind an
+// OpenAI-compatible chat completions API.
+type tldrProcessor struct {
+	url    string
+	model  string
+	prompt string
+	apiKey string
+}
+
+func (t tldrProcessor) Process(ctx context.Context, body string) (string, error) {
+	text, _, err := cutEmbeds(body)
+	if err != nil {
+		return "", err
+	}
+
+	type chatMessage struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+
+	req := struct {
+		Model    string        `json:"model"`
+		Messages []chatMessage `json:"messages"`
+	}{
+		Model: t.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: t.prompt},
+			{Role: "user", Content: text},
+		},
+	}
+
+	var resp struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+
+	header := http.Header{"Authorization": {"Bearer " + t.apiKey}}
+	if err := postJSON(ctx, strings.TrimSuffix(t.url, "/")+"/v1/chat/completions", header, req, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", errors.New("the model returned no summary")
+	}
+
+	summary := strings.Join(strings.Fields(resp.Choices[0].Message.Content), " ")
+	if summary == "" {
+		return "", errors.New("the model returned an empty summary")
+	}
+
+	return strings.TrimRight(body, "\n") + "\n\n**TL;DR:** " + summary, nil
+}
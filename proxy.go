@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+)
+
+var proxyURL = flag.String("proxy", "", "the HTTP or SOCKS5 proxy to reach Discord through, such as socks5://localhost:1080")
+
+// proxySchemes are the proxy URL schemes that both the REST client and the
+// gateway's websocket dialer understand.
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// applyProxy makes all traffic go through the proxy given using --proxy.
+//
+// Both the REST client and the gateway's websocket dialer already take their
+// proxy from $HTTPS_PROXY and $HTTP_PROXY, as does every other request that
+// the bot makes, so the flag just overrides those. It must be called before
+// any request is made, since the variables are only read once.
+func applyProxy() error {
+	if *proxyURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(*proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	if !slices.Contains(proxySchemes, u.Scheme) || u.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q: must be like http://host:port or socks5://host:port", *proxyURL)
+	}
+
+	for _, env := range []string{"HTTPS_PROXY", "HTTP_PROXY"} {
+		if err := os.Setenv(env, u.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
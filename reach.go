@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// reachDelay is how long after an announcement its reach is measured.
+const reachDelay = 24 * time.Hour
+
+// reachMaxAge is how old an announcement may be for its reach to still be
+// measured. Older announcements, such as those from before the bot measured
+// reach, are left alone.
+const reachMaxAge = 7 * 24 * time.Hour
+
+// reachCheckInterval is how often the bot looks for announcements whose reach
+// is due to be measured.
+const reachCheckInterval = 10 * time.Minute
+
+// announcementReach is how far an announcement reached in its first day.
+type announcementReach struct {
+	// Measured is the time that the reach was measured.
+	Measured time.Time
+	// Reactions is the number of reactions to the announcement, keyed by
+	// emoji.
+	Reactions map[string]int
+	// ThreadMessages and ThreadMembers are the approximate number of
+	// messages and members in the announcement's thread, if it has one.
+	ThreadMessages int
+	ThreadMembers  int
+}
+
+// measureReach measures the reach of every announcement that has been up for
+// reachDelay and whose reach hasn't been measured yet.
+func (h *commandHandler) measureReach() {
+	now := time.Now()
+
+	var due []discord.MessageID
+	h.announcements.All()(func(id discord.MessageID, record announcementRecord) bool {
+		age := now.Sub(record.Time)
+		if record.Reach == nil && age >= reachDelay && age < reachMaxAge {
+			due = append(due, id)
+		}
+		return true
+	})
+
+	for _, id := range due {
+		record, ok, err := h.announcements.Load(id)
+		if err != nil || !ok {
+			continue
+		}
+
+		reach, err := h.fetchReach(messageRef{ChannelID: record.ChannelID, MessageID: id})
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to measure the reach of an announcement. It will try again later.",
+				"message_id", id,
+				"err", err)
+			continue
+		}
+
+		record.Reach = &reach
+		if err := h.announcements.Store(id, record); err != nil {
+			slog.Error(
+				"Bot has failed to store the reach of an announcement.",
+				"message_id", id,
+				"err", err)
+			continue
+		}
+
+		slog.Info(
+			"Bot has measured the reach of an announcement.",
+			"message_id", id,
+			"reactions", reach.Reactions,
+			"thread_messages", reach.ThreadMessages,
+			"thread_members", reach.ThreadMembers)
+
+		if h.bot.ReportReach {
+			h.sendAudit(msgReachReport, replyData{
+				Link:   messageLink(h.bot.TargetGuildID, messageRef{ChannelID: record.ChannelID, MessageID: id}),
+				Report: formatReach(reach),
+			})
+		}
+	}
+}
+
+// fetchReach fetches the reactions and the thread activity of the message.
+func (h *commandHandler) fetchReach(ref messageRef) (announcementReach, error) {
+	msg, err := h.session.Message(ref.ChannelID, ref.MessageID)
+	if err != nil {
+		return announcementReach{}, err
+	}
+
+	reach := announcementReach{
+		Measured:  time.Now(),
+		Reactions: make(map[string]int, len(msg.Reactions)),
+	}
+
+	for _, reaction := range msg.Reactions {
+		count := reaction.Count
+		if reaction.Me {
+			// The bot's own reaction doesn't count.
+			count--
+		}
+		if count > 0 {
+			reach.Reactions[reaction.Emoji.String()] = count
+		}
+	}
+
+	// A thread started from a message shares its ID. Forum posts are threads
+	// themselves.
+	if msg.Flags&discord.MessageHasThread != 0 || ref.isForumPost() {
+		thread, err := h.session.Channel(discord.ChannelID(ref.MessageID))
+		if err != nil {
+			return announcementReach{}, fmt.Errorf("cannot get the thread: %w", err)
+		}
+		reach.ThreadMessages = thread.MessageCount
+		reach.ThreadMembers = thread.MemberCount
+	}
+
+	return reach, nil
+}
+
+// formatReach formats the reach as one line of reactions, most used first,
+// and one line of thread activity.
+func formatReach(reach announcementReach) string {
+	emojis := make([]string, 0, len(reach.Reactions))
+	for emoji := range reach.Reactions {
+		emojis = append(emojis, emoji)
+	}
+	slices.SortFunc(emojis, func(a, b string) int {
+		if n := reach.Reactions[b] - reach.Reactions[a]; n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+
+	reactions := make([]string, len(emojis))
+	for i, emoji := range emojis {
+		reactions[i] = fmt.Sprintf("%s %d", emoji, reach.Reactions[emoji])
+	}
+	if len(reactions) == 0 {
+		reactions = []string{"–"}
+	}
+
+	return formatBulletList([]string{
+		strings.Join(reactions, " · "),
+		fmt.Sprintf("🧵 %d · 👥 %d", reach.ThreadMessages, reach.ThreadMembers),
+	})
+}
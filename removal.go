@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// removalLookback is how far back the guild's audit log is searched for who
+// deleted an announcement.
+const removalLookback = 5 * time.Minute
+
+// findAnnouncement returns the ID of the announcement record that the message
+// belongs to, either as the announcement itself or as one of its copies.
+func (h *commandHandler) findAnnouncement(ref messageRef) (discord.MessageID, announcementRecord, bool) {
+	if record, ok, err := h.announcements.Load(ref.MessageID); err == nil && ok {
+		return ref.MessageID, record, true
+	}
+
+	// Only announcement channels can have copies in them, so don't bother
+	// going through every record for any other channel.
+	if !slices.Contains(h.bot.announceChannels(), ref.ChannelID) {
+		return 0, announcementRecord{}, false
+	}
+
+	var (
+		found  discord.MessageID
+		record announcementRecord
+	)
+	h.announcements.All()(func(id discord.MessageID, r announcementRecord) bool {
+		if slices.Contains(r.Copies, ref) {
+			found, record = id, r
+			return false
+		}
+		return true
+	})
+	return found, record, found.IsValid()
+}
+
+// findDeleter looks through the guild's audit log for who deleted the bot's
+// message in the channel just now. It returns 0 if it can't tell, such as when
+// the bot may not view the audit log.
+func (h *commandHandler) findDeleter(channelID discord.ChannelID) discord.UserID {
+	log, err := h.session.AuditLog(h.bot.TargetGuildID, api.AuditLogData{
+		ActionType: discord.MessageDelete,
+		Limit:      10,
+	})
+	if err != nil {
+		slog.Debug(
+			"Bot could not look up who deleted an announcement.",
+			"err", err)
+		return 0
+	}
+
+	for _, entry := range log.Entries {
+		if discord.UserID(entry.TargetID) == h.bot.SelfID &&
+			entry.Options.ChannelID == channelID &&
+			time.Since(entry.CreatedAt()) < removalLookback {
+			return entry.UserID
+		}
+	}
+	return 0
+}
+
+// noteRemoval tells the author and the audit channel that someone has deleted
+// one of the author's announcements.
+func (h *commandHandler) noteRemoval(ref messageRef) {
+	id, record, ok := h.findAnnouncement(ref)
+	if !ok {
+		return
+	}
+
+	// The edit command can't edit a deleted announcement.
+	if lastSent, ok, err := h.lastSentAuthors.Load(record.AuthorID); err == nil && ok && lastSent == id && id == ref.MessageID {
+		if err := h.lastSentAuthors.Delete(record.AuthorID); err != nil {
+			slog.Warn(
+				"Bot has failed to forget the deleted announcement of the author.",
+				"author_id", record.AuthorID,
+				"err", err)
+		}
+	}
+
+	// A deleted announcement can no longer be jumped to from search.
+	if id == ref.MessageID {
+		h.unindexAnnouncement(id)
+	}
+
+	// The bot deletes the announcement itself once its time has come.
+	if !record.Options.DeleteAt.IsZero() && !time.Now().Before(record.Options.DeleteAt) {
+		return
+	}
+
+	data := replyData{
+		Author:  record.AuthorID.Mention(),
+		Channel: ref.ChannelID.Mention(),
+	}
+	if deleter := h.findDeleter(ref.ChannelID); deleter.IsValid() {
+		data.Target = deleter.Mention()
+	}
+
+	slog.Info(
+		"An announcement has been deleted by someone other than the bot.",
+		"author_id", record.AuthorID,
+		"channel_id", ref.ChannelID,
+		"message_id", ref.MessageID)
+
+	h.sendAudit(msgRemovalAlert, data)
+
+	// Forgotten authors can't be told.
+	if isPseudonym(record.AuthorID) {
+		return
+	}
+
+	content := localize(h.bot.locale(), msgRemovalNotice, data)
+
+	dm, err := h.session.CreatePrivateChannel(record.AuthorID)
+	if err == nil {
+		_, err = h.session.SendMessage(dm.ID, content)
+	}
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to DM the author about their deleted announcement.",
+			"author_id", record.AuthorID,
+			"err", err)
+	}
+}
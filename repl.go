@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+var replEnabled = flag.Bool("repl", false, "accept admin commands on stdin")
+
+// replBodyEnd ends the body of a command typed into the REPL.
+const replBodyEnd = "."
+
+// replCommands are the commands that only the REPL has, on top of every
+// regular command.
+var replCommands = map[string]string{
+	"settings": "print the current settings",
+	"queue":    "print the pending and unsent announcements",
+	"help":     "print this help",
+}
+
+// readREPL reads commands from r and sends them to commands until r runs out
+// or ctx is done. Each command is written like it would be in a message,
+// minus the mention:
+//
+//	announce
+//	---
+//	title: Release v1.0
+//	---
+//	body
+//	.
+//
+// The body of a command that needs one ends at a line containing only a dot.
+func readREPL(ctx context.Context, r io.Reader, commands chan<- *parsedCommand) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		header := strings.TrimSpace(scanner.Text())
+		if header == "" {
+			continue
+		}
+
+		name, args, _ := strings.Cut(header, " ")
+		command := &parsedCommand{
+			Command: strings.ToLower(name),
+			Args:    strings.TrimSpace(args),
+		}
+
+		if commandSpecs[command.Command].NeedsBody {
+			var lines []string
+			for scanner.Scan() && scanner.Text() != replBodyEnd {
+				lines = append(lines, scanner.Text())
+			}
+			command.Options, command.Body = cutFrontMatter(strings.Join(lines, "\n"))
+		}
+
+		select {
+		case commands <- command:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		slog.Warn(
+			"Bot has failed to read from the REPL. It will no longer accept commands from it.",
+			"err", err)
+	}
+}
+
+// handleREPL handles a command typed into the REPL. Regular commands go
+// through the same router as commands from Discord, acting as the bot itself,
+// and the replies are printed instead of sent.
+func (h *commandHandler) handleREPL(command *parsedCommand) {
+	out := os.Stdout
+
+	if !h.bot.TargetGuildID.IsValid() {
+		fmt.Fprintln(out, "the bot is not ready yet")
+		return
+	}
+
+	switch command.Command {
+	case "help":
+		fmt.Fprintln(out, "commands:")
+		for _, name := range sortedKeys(commandSpecs) {
+			fmt.Fprintln(out, " ", name)
+		}
+		for _, name := range sortedKeys(replCommands) {
+			fmt.Fprintf(out, "  %s: %s\n", name, replCommands[name])
+		}
+		fmt.Fprintf(out, "end the body of a command with a line containing only %q\n", replBodyEnd)
+		return
+	case "settings":
+		printJSON(out, h.bot.botSettings)
+		return
+	case "queue":
+		outbox := make(map[string]outboxEntry)
+		h.outbox.All()(func(id string, entry outboxEntry) bool {
+			outbox[id] = entry
+			return true
+		})
+		printJSON(out, struct {
+			Pending map[string]*pendingAnnouncement
+			Outbox  map[string]outboxEntry
+		}{h.pending, outbox})
+		return
+	}
+
+	if _, ok := commandSpecs[command.Command]; !ok {
+		fmt.Fprintf(out, "unknown command %q, try help\n", command.Command)
+		return
+	}
+
+	inv := &invocation{
+		Author:    discord.User{ID: h.bot.SelfID, Username: "console"},
+		ChannelID: h.bot.TargetChannelID,
+		Locale:    h.bot.locale(),
+		Console:   out,
+		consoleID: fmt.Sprintf("console-%d", time.Now().UnixNano()),
+	}
+	h.handleCommand(inv, command)
+}
+
+func printJSON(w io.Writer, v any) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(w, "cannot encode:", err)
+		return
+	}
+	fmt.Fprintln(w, string(b))
+}
+
+// sortedKeys returns the keys of the map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
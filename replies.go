@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// replyTrackingTTL is how long the bot remembers its replies to a command
+// message, so that it can delete them if the command is deleted.
+const replyTrackingTTL = 24 * time.Hour
+
+// trackedReplies are the replies that the bot has sent to a command message.
+type trackedReplies struct {
+	ChannelID discord.ChannelID
+	Replies   []discord.MessageID
+	Created   time.Time
+}
+
+// trackReplies remembers the replies that the bot has sent to the command
+// message of the invocation.
+func (h *commandHandler) trackReplies(inv *invocation) {
+	if inv.Message == nil || len(inv.replies) == 0 {
+		return
+	}
+
+	// Forget the replies to commands that are too old to bother with.
+	for id, tracked := range h.replies {
+		if time.Since(tracked.Created) > replyTrackingTTL {
+			delete(h.replies, id)
+		}
+	}
+
+	tracked, ok := h.replies[inv.Message.ID]
+	if !ok {
+		tracked = &trackedReplies{ChannelID: inv.ChannelID, Created: time.Now()}
+		h.replies[inv.Message.ID] = tracked
+	}
+	tracked.Replies = append(tracked.Replies, inv.replies...)
+	inv.replies = nil
+}
+
+// handleMessageDelete deletes the bot's replies to a command message that its
+// author has deleted, so that they aren't left behind without context. If the
+// message was an announcement, its author is told.
+func (h *commandHandler) handleMessageDelete(channelID discord.ChannelID, ids []discord.MessageID) {
+	for _, id := range ids {
+		// The bot deleting the command itself must not take the replies
+		// with it, since they link to what the command did.
+		if _, ok := h.botDeletions[id]; ok {
+			delete(h.botDeletions, id)
+			continue
+		}
+
+		h.noteRemoval(messageRef{ChannelID: channelID, MessageID: id})
+
+		// A deleted command can no longer have its channels picked.
+		delete(h.pending, id.String())
+
+		tracked, ok := h.replies[id]
+		if !ok || tracked.ChannelID != channelID {
+			continue
+		}
+		delete(h.replies, id)
+
+		for _, replyID := range tracked.Replies {
+			if err := h.session.DeleteMessage(channelID, replyID, "command message was deleted"); err != nil {
+				slog.Warn(
+					"Bot has failed to delete its reply to a deleted command.",
+					"channel_id", channelID,
+					"message_id", replyID,
+					"err", err)
+			}
+		}
+
+		slog.Info(
+			"Bot has deleted its replies to a deleted command.",
+			"channel_id", channelID,
+			"message_id", id,
+			"replies", len(tracked.Replies))
+	}
+}
+
+func (h *commandHandler) handleDeleteEvent(ev *gateway.MessageDeleteEvent) {
+	if ev.GuildID == h.bot.TargetGuildID {
+		h.handleMessageDelete(ev.ChannelID, []discord.MessageID{ev.ID})
+	}
+}
+
+func (h *commandHandler) handleBulkDeleteEvent(ev *gateway.MessageDeleteBulkEvent) {
+	if ev.GuildID == h.bot.TargetGuildID {
+		h.handleMessageDelete(ev.ChannelID, ev.IDs)
+	}
+}
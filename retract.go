@@ -0,0 +1,158 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// retract replaces the announcement given in the command's arguments with a
+// notice that it was retracted, given as
+//
+//	retract <message> [reason]
+//
+// Unlike deleting it, this keeps the message and its thread where they are.
+// Only its author and admins may retract it, and only approvers may retract
+// one that needed approval. If announcements are signed, the reason must be
+// signed too, on the lines after the command.
+func (h *commandHandler) retract(inv *invocation, command *parsedCommand) {
+	target, reason, _ := strings.Cut(strings.TrimSpace(command.Args), " ")
+	if reason = strings.TrimSpace(reason); reason == "" {
+		reason = strings.TrimSpace(command.Body)
+	}
+
+	id, err := parseMessageID(target)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidMessage, replyData{Error: err}))
+		return
+	}
+
+	record, ok, err := h.announcements.Load(id)
+	if err != nil {
+		slog.Error(
+			"Bot has failed to look up the announcement to retract.",
+			"message_id", id,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+	if !ok {
+		sendRejection(h.session, inv, inv.text(msgNotAnnouncement))
+		return
+	}
+
+	if record.AuthorID != inv.Author.ID {
+		if member := inv.member(); member != nil {
+			perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, member)
+			if err != nil || !isAdmin(*h.bot, member, perms) {
+				sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+				return
+			}
+		}
+	}
+
+	if !h.checkEditWindow(inv, record) {
+		return
+	}
+
+	// Retracting overwrites the announcement, so one that needed approval
+	// may only be retracted by those who may edit it.
+	if !h.checkEditApproval(inv, record.Options, record.Body, record) {
+		return
+	}
+
+	// The reason is posted in place of the announcement, so it is signed and
+	// screened like one.
+	if reason != "" {
+		if reason, ok = h.screenSignedBody(inv, "", reason); !ok {
+			return
+		}
+	}
+
+	// The number stays, since it is how the announcement is referred to.
+	text := localize(h.bot.locale(), msgRetractedNotice, replyData{
+		Author: inv.Author.Mention(),
+		Reason: reason,
+	})
+	notice := announcementMessage{
+		Content:          withFooter(text, h.bot.numberLine(record.Number)),
+		ClearAttachments: true,
+	}
+
+	// The notice is recorded first, so that its edit events aren't taken for
+	// edits made outside of the bot. It is also what sticky copies would be
+	// reposted from, but those are taken down below anyway.
+	before := record.Body
+	record.Body = text
+	record.Footer = ""
+	record.Options = announceOptions{DeleteAt: record.Options.DeleteAt}
+	record.Revisions = append(record.Revisions, announcementRevision{
+		Time:    time.Now(),
+		Content: notice.Content,
+	})
+	if err := h.announcements.Store(id, record); err != nil {
+		slog.Error(
+			"Bot has failed to record the retraction of the announcement.",
+			"message_id", id,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	h.indexAnnouncement(id, record)
+	h.setSticky(id, nil, false)
+	h.scheduleBump(id, time.Time{})
+
+	messages := []messageRef{{ChannelID: record.ChannelID, MessageID: id}}
+	messages = append(messages, record.Copies...)
+	for _, ref := range record.Translations {
+		messages = append(messages, ref)
+	}
+	if record.Staged != nil {
+		messages = append(messages, *record.Staged)
+	}
+
+	var failed bool
+
+	var retractNext func(i int)
+	retractNext = func(i int) {
+		if i < len(messages) {
+			ref := messages[i]
+			h.retry("retract announcement", inv, func() error {
+				return h.editAnnouncement(ref, notice)
+			}, func(err error) {
+				if err != nil {
+					slog.Error(
+						"Bot has failed to replace the announcement with the retraction notice.",
+						"channel_id", ref.ChannelID,
+						"message_id", ref.MessageID,
+						"ref", inv.errorRef(),
+						"err", err)
+					failed = true
+				}
+				retractNext(i + 1)
+			})
+			return
+		}
+
+		slog.Info(
+			"Bot has retracted an announcement.",
+			"author_id", record.AuthorID,
+			"retracted_by", inv.Author.ID,
+			"message_id", id,
+			"reason", reason)
+
+		h.auditEdit(inv, messages[0], before, text)
+
+		if failed {
+			replyInternalError(h.session, inv)
+			return
+		}
+		h.acknowledge(inv, inv.textWith(msgRetracted, replyData{Link: messageLink(h.bot.TargetGuildID, messages[0])}))
+	}
+	retractNext(0)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+)
+
+const (
+	// maxRetries is the maximum number of times a failed request is retried.
+	maxRetries = 4
+	// retryBaseDelay is the delay before the first retry. Each following retry
+	// doubles it.
+	retryBaseDelay = 500 * time.Millisecond
+	// maxRetryAfter is the longest that a rate limited request waits before
+	// it's retried. Discord asking for more than that is treated as a hard
+	// failure.
+	maxRetryAfter = time.Minute
+)
+
+// withRetry calls fn, retrying it with exponential backoff for as long as it
+// fails with a transient error, up to maxRetries times. It sleeps between
+// attempts, so it must only be used off the event loop, such as in work given
+// to inBackground. On the event loop, use retry instead.
+func withRetry(op string, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		wait, ok := retryDelay(op, attempt, delay, err)
+		if !ok {
+			return err
+		}
+		time.Sleep(wait)
+		delay = wait * 2
+	}
+}
+
+// retry calls fn on the event loop, retrying it with exponential backoff for
+// as long as it fails with a transient error, up to maxRetries times, and
+// then calls done with the last error. The event loop carries on while it
+// waits between attempts. If Discord rate limits fn, the author of inv is
+// told why their reply is taking longer. inv may be nil.
+func (h *commandHandler) retry(op string, inv *invocation, fn func() error, done func(error)) {
+	onRateLimit := h.rateLimitNotice(inv)
+
+	var attempt func(n int, delay time.Duration)
+	attempt = func(n int, delay time.Duration) {
+		err := fn()
+		wait, ok := retryDelay(op, n, delay, err)
+		if !ok {
+			done(err)
+			return
+		}
+
+		if _, limited := retryAfter(err); limited && onRateLimit != nil {
+			onRateLimit(wait)
+			onRateLimit = nil
+		}
+
+		h.after(wait, func() { attempt(n+1, wait*2) })
+	}
+	attempt(0, retryBaseDelay)
+}
+
+// retryDelay returns how long to wait before retrying the attempt that failed
+// with err, given the delay that the backoff is at. It returns false if the
+// attempt shouldn't be retried, such as when it succeeded.
+func retryDelay(op string, attempt int, delay time.Duration, err error) (time.Duration, bool) {
+	if err == nil || attempt == maxRetries || !isTransient(err) {
+		return 0, false
+	}
+
+	// Wait for as long as Discord asks us to, if it's asking.
+	if after, ok := retryAfter(err); ok {
+		if after > maxRetryAfter {
+			return 0, false
+		}
+		delay = max(delay, after)
+	}
+
+	slog.Warn(
+		"Bot has hit a transient error. It will retry.",
+		"op", op,
+		"attempt", attempt+1,
+		"delay", delay,
+		"err", err)
+
+	return delay, true
+}
+
+// isTransient returns true if the error is worth retrying, such as when
+// Discord is rate limiting us, is having server errors or can't be reached.
+func isTransient(err error) bool {
+	var httpErr *httputil.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status == http.StatusTooManyRequests || httpErr.Status >= 500
+	}
+
+	var reqErr httputil.RequestError
+	return errors.As(err, &reqErr)
+}
+
+// retryAfter returns how long Discord asked us to wait before retrying, if
+// the error is a rate limit. The delay is read from the body of the response,
+// since the header isn't kept around.
+func retryAfter(err error) (time.Duration, bool) {
+	var httpErr *httputil.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	var body struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.Unmarshal(httpErr.Body, &body); err != nil || body.RetryAfter <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(body.RetryAfter * float64(time.Second)), true
+}
+
+// rateLimitNotice returns a callback for retry that tells the author of inv
+// that the reply is going to take a while. It returns nil if inv is nil.
+func (h *commandHandler) rateLimitNotice(inv *invocation) func(time.Duration) {
+	if inv == nil {
+		return nil
+	}
+	return func(delay time.Duration) {
+		delay = max(delay.Round(time.Second), time.Second)
+		sendReply(h.session, inv, inv.textWith(msgRateLimited, replyData{Duration: delay}))
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// announcementRevision is a version of the content of an announcement.
+type announcementRevision struct {
+	Time    time.Time
+	Content string
+	// OutOfBand is true if the revision wasn't made by the bot, such as when
+	// another tool edited the announcement using the bot's token.
+	OutOfBand bool
+}
+
+// latestContent returns the content of the latest revision of the
+// announcement, or false if it has none.
+func (r announcementRecord) latestContent() (string, bool) {
+	if len(r.Revisions) == 0 {
+		return "", false
+	}
+	return r.Revisions[len(r.Revisions)-1].Content, true
+}
+
+// handleAnnouncementEdit records edits to announcements that weren't made
+// through the bot, so that the revision history stays complete.
+func (h *commandHandler) handleAnnouncementEdit(ev *gateway.MessageUpdateEvent) {
+	// Updates without an edit timestamp only add embeds to the message. Only
+	// the bot can edit its own messages, so anything else isn't an
+	// announcement.
+	if !ev.EditedTimestamp.IsValid() || ev.Author.ID != h.bot.SelfID || ev.GuildID != h.bot.TargetGuildID {
+		return
+	}
+
+	ref := messageRef{ChannelID: ev.ChannelID, MessageID: ev.ID}
+	id, record, ok := h.findAnnouncement(ref)
+	if !ok {
+		return
+	}
+
+	// Edits made through the bot are recorded before their events arrive, so
+	// they match the latest revision. Discord trims the content of messages.
+	if content, ok := record.latestContent(); ok && strings.TrimSpace(content) == ev.Content {
+		return
+	}
+
+	record.Revisions = append(record.Revisions, announcementRevision{
+		Time:      ev.EditedTimestamp.Time(),
+		Content:   ev.Content,
+		OutOfBand: true,
+	})
+	if err := h.announcements.Store(id, record); err != nil {
+		slog.Error(
+			"Bot has failed to record an edit made to an announcement outside of the bot.",
+			"message_id", id,
+			"err", err)
+		return
+	}
+
+	// Records from before the body was kept are searched by their content.
+	if record.Body == "" {
+		h.indexAnnouncement(id, record)
+	}
+
+	slog.Warn(
+		"An announcement has been edited outside of the bot.",
+		"channel_id", ev.ChannelID,
+		"message_id", ev.ID)
+
+	h.sendAudit(msgOutOfBandEdit, replyData{Link: messageLink(h.bot.TargetGuildID, ref)})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// roleSettings returns the name of every setting that refers to the role.
+func (b botState) roleSettings(id discord.RoleID) []string {
+	var names []string
+	if slices.Contains(b.AllowedRoleIDs, id) {
+		names = append(names, "AllowedRoleIDs")
+	}
+	if slices.Contains(b.AdminRoleIDs, id) {
+		names = append(names, "AdminRoleIDs")
+	}
+	if b.AnnounceRoleID == id {
+		names = append(names, "AnnounceRoleID")
+	}
+	if slices.Contains(b.ApproverRoleIDs, id) {
+		names = append(names, "ApproverRoleIDs")
+	}
+	if slices.Contains(b.TTSRoleIDs, id) {
+		names = append(names, "TTSRoleIDs")
+	}
+	return names
+}
+
+// recordRoleNames remembers the names of the roles in the target guild, so that
+// renames can be noticed. It also alerts about roles that are already gone.
+func (h *commandHandler) recordRoleNames() {
+	roles, err := h.session.Roles(h.bot.TargetGuildID)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to get the roles of the target guild. It will not notice renamed roles.",
+			"guild_id", h.bot.TargetGuildID,
+			"err", err)
+		return
+	}
+
+	for _, role := range roles {
+		h.roleNames[role.ID] = role.Name
+	}
+
+	for _, id := range slices.Concat(h.bot.AllowedRoleIDs, h.bot.AdminRoleIDs, h.bot.ApproverRoleIDs, h.bot.TTSRoleIDs, []discord.RoleID{h.bot.AnnounceRoleID}) {
+		if _, ok := h.roleNames[id]; id.IsValid() && !ok {
+			h.alertRoleDeleted(id)
+		}
+	}
+}
+
+func (h *commandHandler) handleRoleUpdate(ev *gateway.GuildRoleUpdateEvent) {
+	if ev.GuildID != h.bot.TargetGuildID {
+		return
+	}
+
+	oldName, ok := h.roleNames[ev.Role.ID]
+	h.roleNames[ev.Role.ID] = ev.Role.Name
+
+	if !ok || oldName == ev.Role.Name {
+		return
+	}
+
+	for _, setting := range h.bot.roleSettings(ev.Role.ID) {
+		h.sendAudit(msgRoleRenamed, replyData{
+			Role:    ev.Role.Name,
+			OldRole: oldName,
+			Setting: setting,
+		})
+	}
+}
+
+func (h *commandHandler) handleRoleDelete(ev *gateway.GuildRoleDeleteEvent) {
+	if ev.GuildID != h.bot.TargetGuildID {
+		return
+	}
+
+	h.alertRoleDeleted(ev.RoleID)
+	delete(h.roleNames, ev.RoleID)
+}
+
+func (h *commandHandler) alertRoleDeleted(id discord.RoleID) {
+	name, ok := h.roleNames[id]
+	if !ok {
+		name = id.String()
+	}
+
+	for _, setting := range h.bot.roleSettings(id) {
+		h.sendAudit(msgRoleDeleted, replyData{
+			Role:    name,
+			Setting: setting,
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// runtimeStateKey is the key that the runtime state is stored under.
+const runtimeStateKey = "state"
+
+// runtimeState is the state that is changed through commands while the bot is
+// running. It is persisted so that it survives restarts.
+type runtimeState struct {
+	// Paused is true if announcements are paused.
+	Paused bool
+	// PauseReason is the reason given for pausing announcements.
+	PauseReason string
+	// PausedBy is the mention of the user who paused announcements.
+	PausedBy string
+	// PausedAt is the time announcements were paused.
+	PausedAt time.Time
+	// BlockedUserIDs is the list of users blocked using the block command, on
+	// top of those in the BlockedUserIDs setting.
+	BlockedUserIDs []discord.UserID
+	// LastDigest is the time that the weekly digest was last posted.
+	LastDigest time.Time
+	// LastNumber is the number of the latest numbered announcement.
+	LastNumber int
+	// Backfilled is true once the last announcements have been backfilled
+	// from the channel history, so that it is only done once.
+	Backfilled bool
+}
+
+// saveRuntime persists the current runtime state.
+func (h *commandHandler) saveRuntime() {
+	if err := h.runtimeStates.Store(runtimeStateKey, h.bot.Runtime); err != nil {
+		slog.Error(
+			"Bot has failed to persist its runtime state. The change will be lost on restart.",
+			"err", err)
+	}
+}
+
+// setPaused pauses or resumes announcements and persists the change. by
+// describes who paused them.
+func (h *commandHandler) setPaused(paused bool, by, reason string) {
+	if paused {
+		h.bot.Runtime.Paused = true
+		h.bot.Runtime.PauseReason = reason
+		h.bot.Runtime.PausedBy = by
+		h.bot.Runtime.PausedAt = time.Now()
+	} else {
+		h.bot.Runtime.Paused = false
+		h.bot.Runtime.PauseReason = ""
+		h.bot.Runtime.PausedBy = ""
+		h.bot.Runtime.PausedAt = time.Time{}
+	}
+	h.saveRuntime()
+	h.updatePresence()
+
+	if paused {
+		slog.Info(
+			"Announcements have been paused.",
+			"by", by,
+			"reason", reason)
+	} else {
+		slog.Info("Announcements have been resumed.")
+	}
+}
+
+func (h *commandHandler) pause(inv *invocation, command *parsedCommand) {
+	reason := command.Args
+	if reason == "" {
+		reason = command.Body
+	}
+
+	h.setPaused(true, inv.Author.Mention(), reason)
+	h.acknowledge(inv, inv.text(msgPausedNow))
+}
+
+func (h *commandHandler) resume(inv *invocation) {
+	h.setPaused(false, "", "")
+	h.acknowledge(inv, inv.text(msgResumed))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// isInvisible returns true for characters that render as nothing and are only
+// ever pasted into announcements by accident or to dodge filters. Zero-width
+// joiners are left alone, since emoji sequences need them.
+func isInvisible(r rune) bool {
+	switch r {
+	case '\u200B', '\u200C', '\u2060', '\uFEFF', '\u00AD', '\u180E':
+		return true
+	}
+	// Bidirectional overrides and isolates can make text read differently
+	// from how it is written.
+	return ('\u202A' <= r && r <= '\u202E') || ('\u2066' <= r && r <= '\u2069')
+}
+
+// homoglyphs maps Cyrillic and Greek letters to the Latin letters that they
+// look like.
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x',
+	'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O',
+	'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X', 'І': 'I', 'Ј': 'J', 'Ѕ': 'S',
+	'ο': 'o', 'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I',
+	'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Χ': 'X',
+	'Υ': 'Y',
+}
+
+// sanitizeBody strips invisible characters from the body, normalizes it to
+// NFC and replaces look-alike letters in otherwise Latin words. It returns
+// the sanitized body and a summary of what was changed.
+func sanitizeBody(body string) (string, []string) {
+	var notes []string
+
+	var invisible int
+	body = strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			invisible++
+			return -1
+		}
+		return r
+	}, body)
+	if invisible > 0 {
+		notes = append(notes, fmt.Sprintf("removed %d invisible characters", invisible))
+	}
+
+	if normalized := norm.NFC.String(body); normalized != body {
+		body = normalized
+		notes = append(notes, "normalized the Unicode of the text")
+	}
+
+	body, words := collapseHomoglyphs(body)
+	if len(words) > 0 {
+		notes = append(notes, fmt.Sprintf("replaced look-alike letters in %s", strings.Join(words, ", ")))
+	}
+
+	return body, notes
+}
+
+// collapseHomoglyphs replaces the look-alike letters in every word that mixes
+// them with Latin letters, such as "pаypal" with a Cyrillic "а". Words that
+// are entirely in another script are left alone. The words that were changed
+// are returned as they were.
+func collapseHomoglyphs(body string) (string, []string) {
+	var b strings.Builder
+	var changed []string
+
+	runes := []rune(body)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && unicode.IsLetter(runes[j]) {
+			j++
+		}
+		word := runes[i:j]
+		i = j
+
+		var latin, lookalike bool
+		for _, r := range word {
+			if _, ok := homoglyphs[r]; ok {
+				lookalike = true
+			} else if unicode.Is(unicode.Latin, r) {
+				latin = true
+			}
+		}
+
+		if !latin || !lookalike {
+			b.WriteString(string(word))
+			continue
+		}
+
+		changed = append(changed, fmt.Sprintf("%q", string(word)))
+		for _, r := range word {
+			if l, ok := homoglyphs[r]; ok {
+				r = l
+			}
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String(), changed
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// scheduleCheckInterval is how often the bot looks for announcements whose
+// "send-at" time has come.
+const scheduleCheckInterval = time.Minute
+
+// scheduledAnnouncement is an announcement that waits for its "send-at" time,
+// which is kept in its options. It is keyed by a short number, which its
+// author snoozes it by.
+type scheduledAnnouncement struct {
+	AuthorID    discord.UserID
+	Body        string
+	Options     announceOptions
+	ChannelIDs  []discord.ChannelID
+	Attachments []discord.Attachment
+	Created     time.Time
+	// Staged is the message in the staging channel that the announcement was
+	// promoted from, if it was staged.
+	Staged *messageRef
+}
+
+// scheduleAnnouncement stores the announcement until its "send-at" time and
+// tells the author how to postpone it.
+func (h *commandHandler) scheduleAnnouncement(inv *invocation, s scheduledAnnouncement) bool {
+	// Numbers follow the highest one that is still scheduled, so that they
+	// stay short.
+	n := 1
+	h.scheduled.Keys()(func(k int) bool {
+		n = max(n, k+1)
+		return true
+	})
+
+	if err := h.scheduled.Store(n, s); err != nil {
+		slog.Error(
+			"Bot has failed to schedule the announcement.",
+			"author_id", s.AuthorID,
+			"send_at", s.Options.SendAt,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return false
+	}
+
+	slog.Info(
+		"Bot has scheduled an announcement.",
+		"author_id", s.AuthorID,
+		"number", n,
+		"send_at", s.Options.SendAt)
+
+	h.acknowledge(inv, inv.textWith(msgScheduled, replyData{
+		Count: n,
+		When:  timestampMarkup(s.Options.SendAt, timestampFull) + " (" + timestampMarkup(s.Options.SendAt, timestampRelative) + ")",
+	}))
+	return true
+}
+
+// sendDueAnnouncements sends the scheduled announcements whose "send-at" time
+// has come, oldest first. They keep waiting while the bot is paused or in its
+// cooldown, and those that fail to send are tried again later. Each one holds
+// the cooldown from when it starts, so the rest wait for the next check once
+// it has been sent.
+func (h *commandHandler) sendDueAnnouncements() {
+	type item struct {
+		n int
+		s scheduledAnnouncement
+	}
+
+	now := time.Now()
+
+	var due []item
+	h.scheduled.All()(func(n int, s scheduledAnnouncement) bool {
+		if !now.Before(s.Options.SendAt) {
+			due = append(due, item{n, s})
+		}
+		return true
+	})
+	slices.SortFunc(due, func(a, b item) int { return a.s.Options.SendAt.Compare(b.s.Options.SendAt) })
+
+	for _, item := range due {
+		if h.bot.Runtime.Paused || h.bot.cooldownRemaining() > 0 {
+			return
+		}
+		if _, ok := h.sendingScheduled[item.n]; ok {
+			continue
+		}
+		h.sendScheduled(item.n, &item.s)
+	}
+}
+
+// sendScheduled sends the scheduled announcement with the given number once
+// its attachments have been downloaded, and DMs its author the link to it.
+// The cooldown holds while the attachments are downloading.
+func (h *commandHandler) sendScheduled(n int, s *scheduledAnnouncement) {
+	if time.Since(s.Options.SendAt) > maxOutboxAge {
+		slog.Error(
+			"Bot has given up on a scheduled announcement that could not be sent for too long.",
+			"author_id", s.AuthorID,
+			"number", n,
+			"send_at", s.Options.SendAt,
+			"body", s.Body)
+
+		h.unschedule(n)
+		return
+	}
+
+	h.sendingScheduled[n] = struct{}{}
+	h.bot.Announcing++
+	h.downloadThen(nil, s.Attachments, func(files []attachmentFile, err error) {
+		delete(h.sendingScheduled, n)
+		h.bot.Announcing--
+		if err != nil {
+			slog.Error(
+				"Bot has failed to download the attachments of a scheduled announcement. It will try again later.",
+				"author_id", s.AuthorID,
+				"number", n,
+				"err", err)
+			return
+		}
+
+		// The announcement may have been snoozed or forgotten, or the bot
+		// paused, while the attachments were downloading.
+		latest, ok, err := h.scheduled.Load(n)
+		if err != nil || !ok || time.Now().Before(latest.Options.SendAt) {
+			return
+		}
+		if h.bot.Runtime.Paused || h.bot.cooldownRemaining() > 0 {
+			return
+		}
+
+		h.postScheduled(n, &latest, files)
+	})
+}
+
+// postScheduled posts the scheduled announcement with the given number, along
+// with the files that its attachments were downloaded as.
+func (h *commandHandler) postScheduled(n int, s *scheduledAnnouncement, files []attachmentFile) {
+	number := h.nextNumber()
+
+	msg, err := h.bot.renderAnnouncement(s.Options, withFooter(s.Body, h.bot.numberLine(number)))
+	if err != nil {
+		h.releaseNumber(number)
+
+		slog.Error(
+			"Bot has failed to render a scheduled announcement. It will be dropped.",
+			"author_id", s.AuthorID,
+			"number", n,
+			"err", err)
+
+		h.unschedule(n)
+		return
+	}
+
+	msg.Attachments = s.Attachments
+	msg.Files = files
+
+	// If the bot died after sending the announcement but before forgetting
+	// the schedule, then the outbox has already sent it.
+	dedupe, ok := h.claimDedupeKey(s.AuthorID, s.Body)
+	if !ok {
+		h.releaseNumber(number)
+		h.unschedule(n)
+		return
+	}
+
+	entry := &outboxEntry{
+		AuthorID:    s.AuthorID,
+		Body:        s.Body,
+		Options:     s.Options,
+		ChannelIDs:  s.ChannelIDs,
+		Created:     time.Now(),
+		Staged:      s.Staged,
+		Attachments: s.Attachments,
+		Number:      number,
+	}
+	h.storeOutbox(dedupe, entry)
+
+	h.deliverOutbox(nil, dedupe, entry, msg, func() {
+		sent := entry.Sent
+		if len(sent) == 0 {
+			h.deleteOutbox(dedupe)
+			h.releaseDedupeKey(dedupe)
+			h.releaseNumber(number)
+
+			slog.Error(
+				"Bot has failed to send a scheduled announcement to any of its channels. It will try again later.",
+				"author_id", s.AuthorID,
+				"number", n)
+			return
+		}
+
+		h.finishAnnouncement(dedupe, entry, msg)
+		h.unschedule(n)
+
+		slog.Info(
+			"Bot has sent a scheduled announcement.",
+			"author_id", s.AuthorID,
+			"number", n,
+			"message_id", sent[0].MessageID)
+
+		// Forgotten authors can't be told.
+		if isPseudonym(s.AuthorID) {
+			return
+		}
+
+		content := localize(h.bot.locale(), msgScheduledSent, replyData{
+			Link: messageLink(h.bot.TargetGuildID, sent[0]),
+		})
+
+		dm, err := h.session.CreatePrivateChannel(s.AuthorID)
+		if err == nil {
+			_, err = h.session.SendMessage(dm.ID, content)
+		}
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to DM the author about their scheduled announcement.",
+				"author_id", s.AuthorID,
+				"err", err)
+		}
+	})
+}
+
+func (h *commandHandler) unschedule(n int) {
+	if err := h.scheduled.Delete(n); err != nil {
+		slog.Warn(
+			"Bot has failed to forget a scheduled announcement. It may be sent again.",
+			"number", n,
+			"err", err)
+	}
+}
+
+// snoozeScheduled postpones the scheduled announcement with the given number
+// by d. Only its author and admins may snooze it.
+func (h *commandHandler) snoozeScheduled(inv *invocation, n int, s scheduledAnnouncement, d time.Duration) {
+	if s.AuthorID != inv.Author.ID {
+		if member := inv.member(); member != nil {
+			perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, member)
+			if err != nil || !isAdmin(*h.bot, member, perms) {
+				sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+				return
+			}
+		}
+	}
+
+	// Snoozing an overdue announcement counts from now.
+	at := s.Options.SendAt
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	at = at.Add(d)
+
+	if !s.Options.DeleteAt.IsZero() && !s.Options.DeleteAt.After(at) {
+		sendRejection(h.session, inv, inv.text(msgSnoozedPastDelete))
+		return
+	}
+
+	s.Options.SendAt = at
+	if err := h.scheduled.Store(n, s); err != nil {
+		slog.Error(
+			"Bot has failed to record the snoozed announcement.",
+			"number", n,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	h.acknowledge(inv, inv.textWith(msgSendSnoozed, replyData{
+		When: timestampMarkup(at, timestampFull) + " (" + timestampMarkup(at, timestampRelative) + ")",
+	}))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	// maxSearchResults is how many announcements the search command shows,
+	// so that its reply fits in a message.
+	maxSearchResults = 10
+	// maxSearchSnippetLength is how much of each announcement the search
+	// command shows.
+	maxSearchSnippetLength = 80
+)
+
+// searchQuery is what the search command looks for.
+type searchQuery struct {
+	// Clauses are the words and phrases that the announcement must all
+	// contain.
+	Clauses []searchClause
+	// AuthorID is who must have made the announcement, if valid.
+	AuthorID discord.UserID
+	// After and Before bound when the announcement was posted, if not zero.
+	After  time.Time
+	Before time.Time
+}
+
+// parseSearchQuery parses a search query such as
+//
+//	"api migration" deprecat* from:@alice after:2024-01-01 before:2024-06-01
+//
+// Quoted words must appear together as a phrase, and a word ending in "*"
+// matches any word that starts with it. Announcements from the day given by
+// "after" onwards and before the day given by "before" match. Days are read
+// in the configured Timezone.
+func parseSearchQuery(text string, loc *time.Location) (searchQuery, error) {
+	var q searchQuery
+
+	for _, field := range splitSearchFields(text) {
+		k, v, ok := strings.Cut(field, ":")
+		switch k = strings.ToLower(k); {
+		case ok && (k == "from" || k == "by"):
+			id, err := parseUserMention(v)
+			if err != nil {
+				return q, err
+			}
+			q.AuthorID = id
+		case ok && (k == "after" || k == "before"):
+			day, err := time.ParseInLocation("2006-01-02", v, loc)
+			if err != nil {
+				return q, fmt.Errorf("%q is not a day, such as 2024-06-01", v)
+			}
+			if k == "after" {
+				q.After = day
+			} else {
+				q.Before = day
+			}
+		default:
+			c := searchClause{
+				Terms:  tokenize(field),
+				Prefix: strings.HasSuffix(strings.TrimSuffix(field, `"`), "*"),
+			}
+			if len(c.Terms) > 0 {
+				q.Clauses = append(q.Clauses, c)
+			}
+		}
+	}
+
+	if len(q.Clauses) == 0 && !q.AuthorID.IsValid() && q.After.IsZero() && q.Before.IsZero() {
+		return q, errors.New("there is nothing to search for")
+	}
+	return q, nil
+}
+
+// splitSearchFields splits a search query around spaces, except for those
+// within quotes. The quotes are kept.
+func splitSearchFields(text string) []string {
+	var (
+		fields []string
+		field  strings.Builder
+		quoted bool
+	)
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			field.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// searchText returns the text of the announcement that is searched.
+func (r announcementRecord) searchText() string {
+	// Records from before the body was kept only have what was posted.
+	body := r.Body
+	if body == "" {
+		body, _ = r.latestContent()
+	}
+	return strings.TrimSpace(r.Options.Title + "\n" + body)
+}
+
+// matchesFilters returns true if the announcement was made by whom and when
+// the query asks for. Its words and phrases are matched by the search index.
+func (q searchQuery) matchesFilters(r announcementRecord) bool {
+	switch {
+	case q.AuthorID.IsValid() && r.AuthorID != q.AuthorID:
+		return false
+	case !q.After.IsZero() && r.Time.Before(q.After):
+		return false
+	case !q.Before.IsZero() && !r.Time.Before(q.Before):
+		return false
+	}
+	return true
+}
+
+// search replies with the latest announcements that match the query given in
+// the command's arguments, along with links to them. Words and phrases are
+// looked up in the search index, so only the announcements that have them
+// are loaded.
+func (h *commandHandler) search(inv *invocation, command *parsedCommand) {
+	q, err := parseSearchQuery(command.Args, h.bot.timezone())
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidSearch, replyData{Error: err}))
+		return
+	}
+
+	type result struct {
+		id     discord.MessageID
+		record announcementRecord
+	}
+
+	var results []result
+	if len(q.Clauses) == 0 {
+		h.announcements.All()(func(id discord.MessageID, record announcementRecord) bool {
+			if q.matchesFilters(record) {
+				results = append(results, result{id, record})
+			}
+			return true
+		})
+	} else {
+		matches, err := h.matchClauses(q.Clauses)
+		if err != nil {
+			slog.Error(
+				"Bot has failed to look up the search in the search index.",
+				"query", command.Args,
+				"ref", inv.errorRef(),
+				"err", err)
+
+			replyInternalError(h.session, inv)
+			return
+		}
+
+		for id := range matches {
+			record, ok, err := h.announcements.Load(id)
+			if err != nil || !ok {
+				continue
+			}
+			if q.matchesFilters(record) {
+				results = append(results, result{id, record})
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		sendReply(h.session, inv, inv.text(msgNoSearchResults))
+		return
+	}
+
+	slices.SortFunc(results, func(a, b result) int { return cmp.Compare(b.id, a.id) })
+
+	lines := make([]string, 0, min(len(results), maxSearchResults))
+	for _, r := range results[:cap(lines)] {
+		snippet, _, _ := strings.Cut(r.record.searchText(), "\n")
+
+		line := fmt.Sprintf("%s by **%s** %s: %s",
+			messageLink(h.bot.TargetGuildID, messageRef{ChannelID: r.record.ChannelID, MessageID: r.id}),
+			escapeMarkup(h.displayName(r.record.AuthorID)),
+			timestampMarkup(r.record.Time, timestampRelative),
+			escapeMarkup(truncateText(snippet, maxSearchSnippetLength)))
+		if r.record.Number != 0 {
+			line = fmt.Sprintf("#%d %s", r.record.Number, line)
+		}
+		lines = append(lines, line)
+	}
+
+	sendReply(h.session, inv, inv.textWith(msgSearchResults, replyData{
+		Count:  len(results),
+		Report: formatBulletList(lines),
+	}))
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// searchPostings maps each announcement that contains a term to the positions
+// of the term in it, in order.
+type searchPostings map[discord.MessageID][]int
+
+// tokenize splits the text into the terms that are indexed. Terms are runs of
+// letters and digits, in lower case.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// loadSearchIndex loads the terms of the search index, and indexes every
+// announcement if nothing has been indexed yet, such as on the first run
+// after upgrading.
+func (h *commandHandler) loadSearchIndex() {
+	h.searchVocabulary = h.searchVocabulary[:0]
+	h.searchTerms.Keys()(func(term string) bool {
+		h.searchVocabulary = append(h.searchVocabulary, term)
+		return true
+	})
+	slices.Sort(h.searchVocabulary)
+
+	empty := true
+	h.searchDocuments.Keys()(func(discord.MessageID) bool {
+		empty = false
+		return false
+	})
+	if !empty {
+		return
+	}
+
+	type announcement struct {
+		id     discord.MessageID
+		record announcementRecord
+	}
+
+	// Indexing writes to the database, so gather everything first.
+	var all []announcement
+	h.announcements.All()(func(id discord.MessageID, record announcementRecord) bool {
+		all = append(all, announcement{id, record})
+		return true
+	})
+	if len(all) == 0 {
+		return
+	}
+
+	for _, a := range all {
+		h.indexAnnouncement(a.id, a.record)
+	}
+
+	slog.Info(
+		"Bot has indexed the past announcements for search.",
+		"announcements", len(all),
+		"terms", len(h.searchVocabulary))
+}
+
+// indexAnnouncement adds the announcement to the search index, replacing
+// what was indexed for it before. It is called whenever its text changes.
+func (h *commandHandler) indexAnnouncement(id discord.MessageID, record announcementRecord) {
+	h.unindexAnnouncement(id)
+
+	positions := make(map[string][]int)
+	for i, term := range tokenize(record.searchText()) {
+		positions[term] = append(positions[term], i)
+	}
+
+	terms := make([]string, 0, len(positions))
+	for term, pos := range positions {
+		if err := h.updatePostings(term, func(p searchPostings) { p[id] = pos }); err != nil {
+			slog.Warn(
+				"Bot has failed to index the announcement for search.",
+				"message_id", id,
+				"err", err)
+			continue
+		}
+		terms = append(terms, term)
+	}
+	slices.Sort(terms)
+
+	if err := h.searchDocuments.Store(id, terms); err != nil {
+		slog.Warn(
+			"Bot has failed to record what was indexed for the announcement.",
+			"message_id", id,
+			"err", err)
+	}
+}
+
+// unindexAnnouncement removes the announcement from the search index, such as
+// once it has been deleted.
+func (h *commandHandler) unindexAnnouncement(id discord.MessageID) {
+	terms, ok, err := h.searchDocuments.Load(id)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to look up what was indexed for the announcement.",
+			"message_id", id,
+			"err", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	for _, term := range terms {
+		if err := h.updatePostings(term, func(p searchPostings) { delete(p, id) }); err != nil {
+			slog.Warn(
+				"Bot has failed to remove the announcement from the search index.",
+				"message_id", id,
+				"err", err)
+		}
+	}
+
+	if err := h.searchDocuments.Delete(id); err != nil {
+		slog.Warn(
+			"Bot has failed to forget what was indexed for the announcement.",
+			"message_id", id,
+			"err", err)
+	}
+}
+
+// updatePostings changes the postings of the term, dropping the term from the
+// index once no announcement has it.
+func (h *commandHandler) updatePostings(term string, update func(searchPostings)) error {
+	postings, _, err := h.searchTerms.Load(term)
+	if err != nil {
+		return fmt.Errorf("cannot load the postings of %q: %w", term, err)
+	}
+	if postings == nil {
+		postings = make(searchPostings)
+	}
+	update(postings)
+
+	i, found := slices.BinarySearch(h.searchVocabulary, term)
+	if len(postings) == 0 {
+		if err := h.searchTerms.Delete(term); err != nil {
+			return fmt.Errorf("cannot delete the postings of %q: %w", term, err)
+		}
+		if found {
+			h.searchVocabulary = slices.Delete(h.searchVocabulary, i, i+1)
+		}
+		return nil
+	}
+
+	if err := h.searchTerms.Store(term, postings); err != nil {
+		return fmt.Errorf("cannot store the postings of %q: %w", term, err)
+	}
+	if !found {
+		h.searchVocabulary = slices.Insert(h.searchVocabulary, i, term)
+	}
+	return nil
+}
+
+// termPostings returns the postings of the term, or of every term that starts
+// with it if prefix is true, with the positions of the terms merged.
+func (h *commandHandler) termPostings(term string, prefix bool) (searchPostings, error) {
+	if !prefix {
+		postings, _, err := h.searchTerms.Load(term)
+		return postings, err
+	}
+
+	merged := make(searchPostings)
+	i, _ := slices.BinarySearch(h.searchVocabulary, term)
+	for _, t := range h.searchVocabulary[i:] {
+		if !strings.HasPrefix(t, term) {
+			break
+		}
+		postings, _, err := h.searchTerms.Load(t)
+		if err != nil {
+			return nil, err
+		}
+		for id, pos := range postings {
+			merged[id] = append(merged[id], pos...)
+		}
+	}
+	for _, pos := range merged {
+		slices.Sort(pos)
+	}
+	return merged, nil
+}
+
+// searchClause is a word or phrase that announcements must contain.
+type searchClause struct {
+	// Terms are the terms that must appear in this order, one after the
+	// other.
+	Terms []string
+	// Prefix is true if the last term only needs to start the word.
+	Prefix bool
+}
+
+// matchClause returns the announcements that contain the clause.
+func (h *commandHandler) matchClause(c searchClause) (map[discord.MessageID]struct{}, error) {
+	postings := make([]searchPostings, len(c.Terms))
+	for i, term := range c.Terms {
+		p, err := h.termPostings(term, c.Prefix && i == len(c.Terms)-1)
+		if err != nil {
+			return nil, err
+		}
+		postings[i] = p
+	}
+
+	matches := make(map[discord.MessageID]struct{})
+	for id, starts := range postings[0] {
+	next:
+		for _, start := range starts {
+			for i, p := range postings[1:] {
+				if _, ok := slices.BinarySearch(p[id], start+i+1); !ok {
+					continue next
+				}
+			}
+			matches[id] = struct{}{}
+			break
+		}
+	}
+	return matches, nil
+}
+
+// matchClauses returns the announcements that contain every clause.
+func (h *commandHandler) matchClauses(clauses []searchClause) (map[discord.MessageID]struct{}, error) {
+	var matches map[discord.MessageID]struct{}
+	for _, c := range clauses {
+		m, err := h.matchClause(c)
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil {
+			matches = m
+			continue
+		}
+		for id := range matches {
+			if _, ok := m[id]; !ok {
+				delete(matches, id)
+			}
+		}
+	}
+	return matches, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -10,10 +11,174 @@ import (
 type botSettings struct {
 	// TargetChannelID is the channel ID of the channel to send the messages to.
 	TargetChannelID discord.ChannelID
+	// ExtraChannelIDs is a list of additional channels that announcements may
+	// be sent to. If this is non-empty, the author is asked which channels to
+	// announce in.
+	ExtraChannelIDs []discord.ChannelID
+	// CommandChannelIDs are the channels that commands are usually sent in.
+	// The bot's replies to commands there are looked through when backfilling
+	// who made past announcements. The target channel is always looked
+	// through.
+	CommandChannelIDs []discord.ChannelID
+	// StagingChannelID is a private channel that announcements are posted to
+	// first. Once reviewed, an admin copies them to their channels using the
+	// promote command. If zero, announcements are posted right away.
+	StagingChannelID discord.ChannelID
+	// SigningKeys is a list of minisign public keys, each the second line of
+	// its .pub file. If non-empty, the body of every announcement and edit
+	// must end with a signature of its front matter and the rest of it by one
+	// of these keys, as made by "minisign -Sm", so that a hijacked Discord
+	// account alone can't post announcements. Each signature may only be used
+	// once, and signed bodies are posted as-is, without snippets, mentions or
+	// processors being expanded in them.
+	SigningKeys []string
+	// ApproverRoleIDs is a list of role IDs whose members sign off
+	// high-impact announcements, which are those with the "severity:
+	// critical" option or that ping everyone. Such announcements are only
+	// posted once two approvers other than the author have promoted them, so
+	// approvers must also be allowed to use the promote command. If empty,
+	// they are promoted like any other.
+	ApproverRoleIDs []discord.RoleID
+	// AuditChannelID is the channel that the bot posts alerts into, such as
+	// when a role that it relies on is deleted. Each alert carries the hash
+	// that the audit log ends in, which "audit verify" is checked against.
+	// If zero, alerts are only logged.
+	AuditChannelID discord.ChannelID
+	// ForumTags is a list of tag names that are applied to the posts that the
+	// bot creates when announcing in a forum channel.
+	ForumTags []string
+	// StageChannelID is the stage channel that the stage command opens a
+	// stage in. If zero, the stage command is unavailable.
+	StageChannelID discord.ChannelID
+	// Translation configures posting translated copies of announcements into
+	// other channels.
+	Translation translationSettings
 	// AllowedRoleIDs is a list of role IDs that are allowed to use this bot.
 	AllowedRoleIDs []discord.RoleID
+	// AllowedPermissions lets anyone who has any of these permissions in the
+	// target channel use this bot, on top of AllowedRoleIDs. In the config
+	// file, it is written as a list of names, such as ["Manage Guild"].
+	AllowedPermissions discord.Permissions
+	// BlockedUserIDs is a list of users who may not use this bot, even if
+	// they have an allowed role. More users can be blocked at runtime using
+	// the block command.
+	BlockedUserIDs []discord.UserID
+	// AdminRoleIDs is a list of role IDs that are allowed to use the admin
+	// commands of this bot. If empty, AllowedRoleIDs is used.
+	AdminRoleIDs []discord.RoleID
+	// CommandRoleIDs overrides who may use each command. It is keyed by the
+	// command name, such as "announce" or "pause", and only members with one
+	// of the listed roles may use that command, regardless of the other role
+	// settings.
+	CommandRoleIDs map[string][]discord.RoleID
+	// AnnounceRoleID is the role that is pinged at the top of every
+	// announcement. If zero, no role is pinged. It can be skipped per
+	// announcement using the "ping" option.
+	AnnounceRoleID discord.RoleID
+	// SubscribePrompt makes the bot keep a message below the latest
+	// announcement that members can react to to get AnnounceRoleID.
+	SubscribePrompt bool
 	// MinAnnounceTimeGap is the minimum time gap between each announcement.
 	MinAnnounceTimeGap time.Duration
+	// CooldownExemptRoleIDs is a list of role IDs whose members may announce
+	// regardless of MinAnnounceTimeGap. Each time an exemption is used, it is
+	// noted in the audit channel.
+	CooldownExemptRoleIDs []discord.RoleID
+	// TTSRoleIDs is a list of role IDs whose members may post announcements
+	// as text-to-speech messages using the "tts" option. If empty, nobody
+	// may.
+	TTSRoleIDs []discord.RoleID
+	// NumberAnnouncements makes the bot number each announcement, such as
+	// "Announcement #142", below its body. The number can be used to find the
+	// announcement using the get command, and to edit it using "edit 142".
+	NumberAnnouncements bool
+	// EditFooter makes the bot add a line to the bottom of announcements that
+	// are edited through it, saying who last edited them and when, so that
+	// readers can tell that the post has changed.
+	EditFooter bool
+	// EditWindow is how long after an announcement is posted that its author
+	// may still edit it or snooze its deletion. Afterwards, only admins may
+	// change it, so that official posts keep their history. If zero,
+	// announcements can be changed forever.
+	EditWindow time.Duration
+	// SlowmodeWindow is how long the channels of an announcement that uses
+	// the "slowmode" option stay slowed down before their slowmode is
+	// restored. If zero, it is 15 minutes.
+	SlowmodeWindow time.Duration
+	// FloodLimit is the number of commands that a user may send within a
+	// minute. Users who send more are ignored for a while. If zero, users are
+	// never ignored.
+	FloodLimit int
+	// Locale is the locale that the bot replies in, such as "en" or "de". If
+	// empty, the guild's preferred locale is used.
+	Locale string
+	// Timezone is the IANA timezone, such as "Europe/Berlin", that the
+	// {{time: ...}} helper in announcements reads times without a timezone
+	// in. If empty, UTC is used.
+	Timezone string
+	// ReplyTemplates overrides the text of the bot's replies. It is keyed by
+	// the message key, such as "announced" or "cooldown", and each value is a
+	// text/template template executed with the fields of replyData, such as
+	// {{.Remaining}} and {{.Link}}.
+	ReplyTemplates map[messageKey]string
+	// BannedPhrases is a list of phrases that announcements may not contain.
+	// Each phrase is a case-insensitive regular expression, such as
+	// `\bcrypto\b`.
+	BannedPhrases []string
+	// AllowedLinkDomains restricts the links in announcements to these
+	// domains and their subdomains, such as "github.com". If empty, any link
+	// is allowed.
+	AllowedLinkDomains []string
+	// DefangLinks makes the bot defang links outside of AllowedLinkDomains so
+	// that they can't be clicked, instead of refusing the announcement.
+	DefangLinks bool
+	// MaxMentions is the number of user and role mentions that an
+	// announcement may have unless the author confirms it using the
+	// "mass-mention" option. Mentions of everyone always need confirming. If
+	// zero, any number of user and role mentions is allowed.
+	MaxMentions int
+	// Processors is the pipeline that the body of each announcement is run
+	// through before it is posted, in order. If empty, bodies are posted as
+	// they are.
+	Processors []processorSettings
+	// StateRetention is how long the bot remembers each author's last
+	// announcement and the idempotency keys of announcements. Older entries
+	// are deleted, after which the announcement can no longer be edited using
+	// the edit command. If zero, entries are kept forever.
+	StateRetention time.Duration
+	// DigestChannelID is the channel that the bot posts a weekly digest of
+	// the past week's announcements into. If zero, no digest is posted.
+	DigestChannelID discord.ChannelID
+	// ReportReach makes the bot post how far each announcement reached into
+	// the audit channel, a day after it was posted.
+	ReportReach bool
+	// OutageAlertAfter is how long the bot may be unable to reach the
+	// gateway before it alerts the webhook in $ALERT_WEBHOOK_URL. If zero,
+	// no alerts are sent.
+	OutageAlertAfter time.Duration
+	// WatchdogTimeout is how long the bot may hear nothing from the gateway,
+	// not even a heartbeat, before it tears down the connection and connects
+	// again from scratch. If zero, the bot relies on the gateway reconnecting
+	// by itself.
+	WatchdogTimeout time.Duration
+	// Presence configures the status and activities that the bot shows.
+	Presence presenceSettings
+	// Identify configures how the bot identifies itself to the gateway.
+	Identify identifySettings
+	// QuietMode makes the bot acknowledge successful commands by reacting to
+	// them instead of replying.
+	QuietMode bool
+	// DeleteCommands makes the bot delete the author's command message once
+	// the command has been carried out. It can be overridden per command using
+	// the "delete-command" option.
+	DeleteCommands bool
+	// SendReceipts makes the bot DM the author a receipt with a link to each
+	// announcement that they post.
+	SendReceipts bool
+
+	// bannedPatterns are the BannedPhrases, compiled when the config is
+	// loaded.
+	bannedPatterns []*regexp.Regexp
 }
 
 var settings = botSettings{
@@ -24,4 +189,17 @@ var settings = botSettings{
 	},
 
 	MinAnnounceTimeGap: 4 * time.Hour,
+	FloodLimit:         10,
+	MaxMentions:        5,
+	OutageAlertAfter:   5 * time.Minute,
+	WatchdogTimeout:    3 * time.Minute,
+
+	Identify: identifySettings{
+		Capabilities: 253, // magic constant from reverse-engineering
+		Browser:      "message-for-me",
+		Device:       "message-for-me",
+	},
 }
+
+// builtinSettings are the settings before any config file is applied.
+var builtinSettings = settings
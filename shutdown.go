@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"time"
+)
+
+// sessionStats counts what the bot has done since it started.
+type sessionStats struct {
+	Started       time.Time
+	Commands      int
+	Announcements int
+}
+
+// closeStore closes the store, flushing any writes that are still pending.
+func closeStore(name string, store io.Closer) {
+	if err := store.Close(); err != nil {
+		slog.Error(
+			"Bot has failed to close a database cleanly. Recent writes may be lost.",
+			"database", name,
+			"err", err)
+	}
+}
+
+// shutdownTimeout is how long the bot waits for its background work, such as
+// announcements that are being retried, to finish when it is shut down. It
+// allows for the longest rate limit that is waited out.
+const shutdownTimeout = maxRetryAfter + 15*time.Second
+
+// finishBackground keeps running what background work leaves to do on the
+// event loop until none of it is left, so that announcements that are being
+// sent are finished on shutdown. It gives up after shutdownTimeout.
+func (h *commandHandler) finishBackground() {
+	if h.backgroundTasks == 0 {
+		return
+	}
+
+	slog.Info(
+		"Bot is finishing its background work before shutting down.",
+		"tasks", h.backgroundTasks)
+
+	timeout := time.After(shutdownTimeout)
+	for h.backgroundTasks > 0 {
+		select {
+		case done := <-h.completions:
+			done()
+		case <-timeout:
+			slog.Warn(
+				"Bot has given up on its background work to shut down in time. Announcements that were being sent are left in the outbox.",
+				"tasks", h.backgroundTasks)
+			return
+		}
+	}
+}
+
+// logShutdownSummary logs what the bot has done since it started and what it
+// is leaving behind.
+func (h *commandHandler) logShutdownSummary() {
+	var unsent int
+	h.outbox.Keys()(func(string) bool {
+		unsent++
+		return true
+	})
+
+	slog.Info(
+		"Bot is shutting down. It has stopped accepting commands.",
+		"uptime", time.Since(h.stats.Started).Round(time.Second),
+		"commands", h.stats.Commands,
+		"announcements", h.stats.Announcements,
+		"dropped_pending", len(h.pending),
+		"unsent_outbox", unsent)
+}
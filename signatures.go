@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignKey is a minisign public key.
+type minisignKey struct {
+	ID  uint64
+	Key ed25519.PublicKey
+}
+
+// parseMinisignKey parses a minisign public key, which is the second line of
+// its .pub file, such as "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3".
+func parseMinisignKey(text string) (minisignKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil || len(b) != 2+8+ed25519.PublicKeySize || string(b[:2]) != "Ed" {
+		return minisignKey{}, errors.New("not a minisign public key")
+	}
+	return minisignKey{
+		ID:  binary.LittleEndian.Uint64(b[2:10]),
+		Key: ed25519.PublicKey(b[10:]),
+	}, nil
+}
+
+// minisignSignature is a minisign signature, as found in a .minisig file.
+type minisignSignature struct {
+	// Prehashed is true if the message was hashed using BLAKE2b before it was
+	// signed, which minisign does by default.
+	Prehashed bool
+	KeyID     uint64
+	Signature []byte
+	// TrustedComment is the comment that is signed along with the signature
+	// using GlobalSignature.
+	TrustedComment  string
+	GlobalSignature []byte
+}
+
+// cutSignature cuts the minisign signature from the end of the body. It
+// returns false if the body doesn't end with one.
+func cutSignature(body string) (string, minisignSignature, bool, error) {
+	lines := strings.Split(strings.TrimRight(body, " \t\r\n"), "\n")
+	if len(lines) < 4 {
+		return body, minisignSignature{}, false, nil
+	}
+
+	tail := lines[len(lines)-4:]
+	for i := range tail {
+		tail[i] = strings.TrimSpace(tail[i])
+	}
+	if !strings.HasPrefix(tail[0], "untrusted comment:") {
+		return body, minisignSignature{}, false, nil
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(tail[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return body, minisignSignature{}, true, errors.New("the signature line is malformed")
+	}
+
+	var s minisignSignature
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		s.Prehashed = true
+	default:
+		return body, minisignSignature{}, true, fmt.Errorf("%q is not a known signature algorithm", sig[:2])
+	}
+	s.KeyID = binary.LittleEndian.Uint64(sig[2:10])
+	s.Signature = sig[10:]
+
+	comment, ok := strings.CutPrefix(tail[2], "trusted comment:")
+	if !ok {
+		return body, minisignSignature{}, true, errors.New("the trusted comment is missing")
+	}
+	s.TrustedComment = strings.TrimPrefix(comment, " ")
+
+	s.GlobalSignature, err = base64.StdEncoding.DecodeString(tail[3])
+	if err != nil || len(s.GlobalSignature) != ed25519.SignatureSize {
+		return body, minisignSignature{}, true, errors.New("the global signature is malformed")
+	}
+
+	return strings.Join(lines[:len(lines)-4], "\n"), s, true, nil
+}
+
+// verify returns true if the signature is of the message by the key.
+func (s minisignSignature) verify(key minisignKey, message []byte) bool {
+	if s.KeyID != key.ID {
+		return false
+	}
+
+	if s.Prehashed {
+		sum := blake2b.Sum512(message)
+		message = sum[:]
+	}
+	if !ed25519.Verify(key.Key, message, s.Signature) {
+		return false
+	}
+
+	// The trusted comment is signed separately, so that it can't be swapped
+	// for another.
+	global := append(bytes.Clone(s.Signature), s.TrustedComment...)
+	return ed25519.Verify(key.Key, global, s.GlobalSignature)
+}
+
+// signedText returns the text that the author signed, which is the front
+// matter along with the body, so that options can't be swapped under a signed
+// body either.
+func signedText(options, body string) string {
+	if options == "" {
+		return body
+	}
+	return frontMatterDelimiter + "\n" + options + "\n" + frontMatterDelimiter + "\n" + body
+}
+
+// print returns what the signature is remembered by once it has been
+// used.
+func (s minisignSignature) print() string {
+	sum := sha256.Sum256(s.Signature)
+	return hex.EncodeToString(sum[:])
+}
+
+// signsAnnouncements returns true if announcements must be signed, in which
+// case they are posted exactly as signed.
+func (b botState) signsAnnouncements() bool {
+	return len(b.SigningKeys) > 0
+}
+
+// verifySignature checks that the body ends with a minisign signature of the
+// front matter and the rest of the body by one of the SigningKeys, and returns
+// the body without it. If no SigningKeys are configured, the body is returned
+// as-is. Each signature may only be used once, so that a hijacked account
+// can't post or edit back in anything that was signed before. If the
+// signature is missing, invalid or used, the author is told why, the attempt
+// is noted in the audit channel and false is returned.
+func (h *commandHandler) verifySignature(inv *invocation, options, body string) (string, bool) {
+	if !h.bot.signsAnnouncements() {
+		return body, true
+	}
+
+	text, sig, found, err := cutSignature(body)
+	if err == nil && !found {
+		err = errors.New("the body isn't signed")
+	}
+
+	if err == nil {
+		if _, used, lerr := h.signatures.Load(sig.print()); lerr != nil {
+			slog.Error(
+				"Bot has failed to look up whether the signature has been used.",
+				"ref", inv.errorRef(),
+				"err", lerr)
+
+			replyInternalError(h.session, inv)
+			return "", false
+		} else if used {
+			err = errors.New("the signature has been used before, so sign the announcement again")
+		}
+	}
+
+	if err == nil {
+		err = errors.New("the signature isn't by any of the team's keys")
+
+		// The signed file usually ends with a newline, which Discord trims.
+		text = strings.TrimRight(text, " \t\r\n")
+		signed := signedText(options, text)
+		for _, k := range h.bot.SigningKeys {
+			key, kerr := parseMinisignKey(k)
+			if kerr != nil {
+				// This is caught when validating the config.
+				continue
+			}
+			if !sig.verify(key, []byte(signed)) && !sig.verify(key, []byte(signed+"\n")) {
+				continue
+			}
+
+			if err := h.signatures.Store(sig.print(), time.Now()); err != nil {
+				slog.Error(
+					"Bot has failed to remember the signature as used.",
+					"ref", inv.errorRef(),
+					"err", err)
+
+				replyInternalError(h.session, inv)
+				return "", false
+			}
+
+			slog.Info(
+				"Bot has verified the signature of the body.",
+				"author_id", inv.Author.ID,
+				"key_id", fmt.Sprintf("%016X", key.ID),
+				"trusted_comment", sig.TrustedComment)
+			return text, true
+		}
+	}
+
+	h.sendAudit(msgSignatureAudit, replyData{Author: inv.Author.Mention(), Error: err})
+	sendRejection(h.session, inv, inv.textWith(msgBadSignature, replyData{Error: err}))
+	return "", false
+}
+
+// screenSignedBody checks the signature of the body of something other than
+// an announcement that is posted into the target channel, such as a poll or
+// a countdown, and then screens it. Like announcements, signed bodies are
+// refused if screening would change them. If the body is refused, the author
+// is told why and false is returned.
+func (h *commandHandler) screenSignedBody(inv *invocation, options, body string) (string, bool) {
+	body, ok := h.verifySignature(inv, options, body)
+	if !ok {
+		return "", false
+	}
+
+	screened, _, ok := h.screenBody(inv, announceOptions{}, body)
+	if !ok {
+		return "", false
+	}
+	if h.bot.signsAnnouncements() && screened != body {
+		sendRejection(h.session, inv, inv.text(msgSignedBodyChanged))
+		return "", false
+	}
+	return screened, true
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const (
+	// slowmodeCheckInterval is how often the bot looks for channels whose
+	// slowmode should be restored.
+	slowmodeCheckInterval = time.Minute
+	// defaultSlowmodeWindow is how long the slowmode of the "slowmode" option
+	// lasts if SlowmodeWindow isn't set.
+	defaultSlowmodeWindow = 15 * time.Minute
+	// maxSlowmode is the longest slowmode that Discord allows.
+	maxSlowmode = 6 * time.Hour
+)
+
+// slowmodeRestore is a channel whose slowmode the bot has changed for an
+// announcement. It is keyed by the channel.
+type slowmodeRestore struct {
+	// Previous is the slowmode that the channel had before, which it is
+	// restored to.
+	Previous discord.Seconds
+	// Until is when the slowmode is restored.
+	Until time.Time
+}
+
+// slowmodeWindow returns how long the slowmode of the "slowmode" option lasts.
+func (b botState) slowmodeWindow() time.Duration {
+	if b.SlowmodeWindow > 0 {
+		return b.SlowmodeWindow
+	}
+	return defaultSlowmodeWindow
+}
+
+// applySlowmode sets the slowmode of the channels that the announcement was
+// posted in, and schedules restoring it. A channel that is already slowed
+// down by the bot keeps its original slowmode to restore to.
+func (h *commandHandler) applySlowmode(sent []messageRef, delay time.Duration) {
+	until := time.Now().Add(h.bot.slowmodeWindow())
+
+	for _, ref := range sent {
+		// Forum posts are their own thread, which nobody is talking in yet.
+		if ref.isForumPost() {
+			continue
+		}
+
+		restore, ok, err := h.slowmodes.Load(ref.ChannelID)
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to look up the slowmode to restore. It won't change the slowmode.",
+				"channel_id", ref.ChannelID,
+				"err", err)
+			continue
+		}
+		if !ok {
+			ch, err := h.session.Channel(ref.ChannelID)
+			if err != nil {
+				slog.Warn(
+					"Bot has failed to get the channel to slow down. It won't change the slowmode.",
+					"channel_id", ref.ChannelID,
+					"err", err)
+				continue
+			}
+			restore.Previous = ch.UserRateLimit
+		}
+
+		// Store first, so that a restart in between still restores it.
+		restore.Until = until
+		if err := h.slowmodes.Store(ref.ChannelID, restore); err != nil {
+			slog.Warn(
+				"Bot has failed to store the slowmode to restore. It won't change the slowmode.",
+				"channel_id", ref.ChannelID,
+				"err", err)
+			continue
+		}
+
+		if err := h.setSlowmode(ref.ChannelID, discord.Seconds(delay/time.Second), "slowing down the channel after an announcement"); err != nil {
+			slog.Warn(
+				"Bot has failed to set the slowmode of the channel.",
+				"channel_id", ref.ChannelID,
+				"err", err)
+		}
+	}
+}
+
+// restoreSlowmodes restores the slowmode of the channels whose window has
+// passed. Those that fail are tried again later.
+func (h *commandHandler) restoreSlowmodes() {
+	now := time.Now()
+
+	type item struct {
+		channelID discord.ChannelID
+		restore   slowmodeRestore
+	}
+
+	var due []item
+	h.slowmodes.All()(func(channelID discord.ChannelID, restore slowmodeRestore) bool {
+		if !now.Before(restore.Until) {
+			due = append(due, item{channelID, restore})
+		}
+		return true
+	})
+
+	for _, item := range due {
+		if err := h.setSlowmode(item.channelID, item.restore.Previous, "restoring the slowmode after an announcement"); err != nil {
+			slog.Warn(
+				"Bot has failed to restore the slowmode of the channel. It will try again later.",
+				"channel_id", item.channelID,
+				"err", err)
+			continue
+		}
+
+		if err := h.slowmodes.Delete(item.channelID); err != nil {
+			slog.Warn(
+				"Bot has failed to forget a restored slowmode.",
+				"channel_id", item.channelID,
+				"err", err)
+		}
+	}
+}
+
+func (h *commandHandler) setSlowmode(channelID discord.ChannelID, delay discord.Seconds, reason api.AuditLogReason) error {
+	return h.session.ModifyChannel(channelID, api.ModifyChannelData{
+		UserRateLimit:  option.NewNullableUint(uint(delay)),
+		AuditLogReason: reason,
+	})
+}
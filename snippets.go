@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// snippetPattern matches snippets in announcement bodies, such as "!footer".
+// The character before the snippet is captured so that exclamation marks
+// inside words, such as "hey!footer", aren't matched.
+var snippetPattern = regexp.MustCompile(`(^|\s)!([a-z0-9][a-z0-9-]{0,31})\b`)
+
+// snippet is a block of boilerplate that "!name" expands to in announcement
+// bodies. It is keyed by its name.
+type snippet struct {
+	Text     string
+	AuthorID discord.UserID
+	Updated  time.Time
+}
+
+// expandSnippets replaces the snippets in the body with their text. Names
+// that aren't snippets are left alone, and snippets within snippets aren't
+// expanded.
+func (h *commandHandler) expandSnippets(body string) string {
+	if !snippetPattern.MatchString(body) {
+		return body
+	}
+
+	return snippetPattern.ReplaceAllStringFunc(body, func(match string) string {
+		m := snippetPattern.FindStringSubmatch(match)
+		s, ok, err := h.snippets.Load(m[2])
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to load a snippet. It will be left as-is.",
+				"name", m[2],
+				"err", err)
+			return match
+		}
+		if !ok {
+			return match
+		}
+		return m[1] + s.Text
+	})
+}
+
+// snippet handles the subcommands of the snippet command:
+//
+//	snippet list
+//	snippet set <name>
+//	snippet delete <name>
+//
+// Setting takes the text of the snippet as the body.
+func (h *commandHandler) snippet(inv *invocation, command *parsedCommand) {
+	h.runNamedCommand(inv, command.Args, namedCommand{
+		Kind:   "snippet",
+		Prefix: "!",
+		Actions: []namedAction{
+			{"list", func(string) { h.listSnippets(inv) }},
+			{"set", func(name string) { h.setSnippet(inv, name, command.Body) }},
+			{"delete", func(name string) {
+				deleteNamed(h, inv, h.snippets, "snippet", name, msgSnippetNotFound, msgSnippetDeleted)
+			}},
+		},
+		Invalid: msgInvalidSnippet,
+	})
+}
+
+func (h *commandHandler) listSnippets(inv *invocation) {
+	var lines []string
+	h.snippets.All()(func(name string, s snippet) bool {
+		preview, _, _ := strings.Cut(s.Text, "\n")
+		lines = append(lines, "`!"+name+"`: "+escapeMarkup(truncateText(preview, 50)))
+		return true
+	})
+
+	if len(lines) == 0 {
+		sendReply(h.session, inv, inv.text(msgNoSnippets))
+		return
+	}
+
+	slices.Sort(lines)
+	sendReply(h.session, inv, inv.textWith(msgSnippets, replyData{Report: formatBulletList(lines)}))
+}
+
+func (h *commandHandler) setSnippet(inv *invocation, name, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidSnippet, replyData{
+			Error: errors.New("the snippet must have a body"),
+		}))
+		return
+	}
+
+	s := snippet{
+		Text:     text,
+		AuthorID: inv.Author.ID,
+		Updated:  time.Now(),
+	}
+
+	if err := h.snippets.Store(name, s); err != nil {
+		slog.Error(
+			"Bot has failed to save a snippet.",
+			"name", name,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	h.acknowledge(inv, inv.textWith(msgSnippetSaved, replyData{Name: name}))
+}
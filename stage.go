@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/diamondburned/arikawa/v3/api"
+)
+
+// maxStageTopicLength is the maximum length of a stage instance's topic.
+const maxStageTopicLength = 120
+
+// stage opens a stage instance in the StageChannelID and announces it. The
+// topic is given in the command's arguments, and the body, if any, is
+// announced along with a link to the stage.
+func (h *commandHandler) stage(inv *invocation, command *parsedCommand) {
+	if !h.bot.StageChannelID.IsValid() {
+		sendRejection(h.session, inv, inv.text(msgNoStageChannel))
+		return
+	}
+
+	topic := strings.TrimSpace(command.Args)
+	if topic == "" || utf8.RuneCountInString(topic) > maxStageTopicLength {
+		sendRejection(h.session, inv, inv.text(msgInvalidStageTopic))
+		return
+	}
+
+	// Don't open the stage if the announcement can't be made anyway.
+	if remaining := h.bot.cooldownRemaining(); remaining > 0 && !h.bot.cooldownExempt(inv.member()) {
+		sendRejection(h.session, inv, inv.textWith(msgCooldown, replyData{Remaining: remaining.Round(time.Second)}))
+		return
+	}
+
+	_, err := h.session.CreateStageInstance(api.CreateStageInstanceData{
+		ChannelID:      h.bot.StageChannelID,
+		Topic:          topic,
+		AuditLogReason: api.AuditLogReason("stage opened by " + inv.Author.Tag()),
+	})
+	if err != nil {
+		slog.Error(
+			"Bot has failed to open the stage.",
+			"channel_id", h.bot.StageChannelID,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	slog.Info(
+		"Bot has opened the stage.",
+		"channel_id", h.bot.StageChannelID,
+		"author_id", inv.Author.ID,
+		"topic", topic)
+
+	link := localize(h.bot.locale(), msgStageLive, replyData{
+		Topic:   topic,
+		Channel: h.bot.StageChannelID.Mention(),
+	})
+
+	body := link
+	if command.Body != "" {
+		body = command.Body + "\n\n" + link
+	}
+
+	h.announce(inv, &parsedCommand{
+		Command: "announce",
+		Body:    body,
+		Options: command.Options,
+	})
+}
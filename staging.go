@@ -0,0 +1,292 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// stagedAnnouncement is an announcement that has been posted to the staging
+// channel and is waiting to be promoted. It is keyed by the ID of the staged
+// message.
+type stagedAnnouncement struct {
+	AuthorID   discord.UserID
+	Body       string
+	Options    announceOptions
+	ChannelIDs []discord.ChannelID
+	Created    time.Time
+	// Approvals are the approvers who have promoted the announcement so far,
+	// if it needs approval.
+	Approvals []discord.UserID
+}
+
+// stageAnnouncement posts the announcement into the staging channel instead
+// of its channels, where it waits for the promote command.
+func (h *commandHandler) stageAnnouncement(inv *invocation, pending *pendingAnnouncement, channelIDs []discord.ChannelID) {
+	h.downloadThen(inv, pending.Attachments, func(files []attachmentFile, err error) {
+		if err != nil {
+			sendRejection(h.session, inv, inv.textWith(msgAttachmentFailed, replyData{Error: err}))
+			return
+		}
+		h.sendStaged(inv, pending, channelIDs, files)
+	})
+}
+
+// sendStaged posts the announcement into the staging channel, along with the
+// files that its attachments were downloaded as.
+func (h *commandHandler) sendStaged(inv *invocation, pending *pendingAnnouncement, channelIDs []discord.ChannelID, files []attachmentFile) {
+	msg, err := h.bot.renderAnnouncement(pending.Options, pending.Body)
+	if err != nil {
+		// This was already checked when the announcement was made.
+		slog.Error(
+			"Bot has failed to render the pending announcement.",
+			"author_id", pending.AuthorID,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	msg.Attachments = pending.Attachments
+	msg.Files = files
+	// Drafts are for review, so they don't ping anyone.
+	msg.Mentions = &api.AllowedMentions{}
+
+	var staged *discord.Message
+	h.retry("stage announcement", inv, func() (err error) {
+		staged, err = h.sendAnnouncement(h.bot.StagingChannelID, msg, pending.Options)
+		return err
+	}, func(err error) {
+		if err != nil {
+			slog.Error(
+				"Bot has failed to post the announcement into the staging channel.",
+				"channel_id", h.bot.StagingChannelID,
+				"ref", inv.errorRef(),
+				"err", err)
+
+			replyInternalError(h.session, inv)
+			return
+		}
+
+		err = h.staged.Store(staged.ID, stagedAnnouncement{
+			AuthorID:   pending.AuthorID,
+			Body:       pending.Body,
+			Options:    pending.Options,
+			ChannelIDs: channelIDs,
+			Created:    time.Now(),
+		})
+		if err != nil {
+			slog.Error(
+				"Bot has failed to store the staged announcement. It cannot be promoted.",
+				"message_id", staged.ID,
+				"ref", inv.errorRef(),
+				"err", err)
+
+			replyInternalError(h.session, inv)
+			return
+		}
+
+		slog.Info(
+			"Bot has staged an announcement for review.",
+			"author_id", pending.AuthorID,
+			"message_id", staged.ID)
+
+		ref := messageRef{ChannelID: staged.ChannelID, MessageID: staged.ID}
+		h.acknowledge(inv, inv.textWith(msgStaged, replyData{Link: messageLink(h.bot.TargetGuildID, ref)}))
+
+		if pending.Command != nil {
+			h.deleteCommand(*pending.Command, pending.Options)
+		}
+	})
+}
+
+// latestStaged returns the ID of the announcement that was staged last.
+func (h *commandHandler) latestStaged() (discord.MessageID, bool) {
+	var latest discord.MessageID
+	h.staged.Keys()(func(id discord.MessageID) bool {
+		latest = max(latest, id)
+		return true
+	})
+	return latest, latest.IsValid()
+}
+
+// promote copies the staged announcement given in the command's arguments, or
+// the one that was staged last, to the channels that it was meant for.
+func (h *commandHandler) promote(inv *invocation, command *parsedCommand) {
+	if !h.bot.StagingChannelID.IsValid() {
+		sendRejection(h.session, inv, inv.text(msgNoStagingChannel))
+		return
+	}
+
+	var id discord.MessageID
+	if command.Args != "" {
+		var err error
+		if id, err = parseMessageID(command.Args); err != nil {
+			sendRejection(h.session, inv, inv.textWith(msgInvalidMessage, replyData{Error: err}))
+			return
+		}
+	} else {
+		var ok bool
+		if id, ok = h.latestStaged(); !ok {
+			sendRejection(h.session, inv, inv.text(msgNotStaged))
+			return
+		}
+	}
+
+	staged, ok, err := h.staged.Load(id)
+	if err != nil {
+		slog.Error(
+			"Bot has failed to look up the staged announcement.",
+			"message_id", id,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+	if !ok {
+		sendRejection(h.session, inv, inv.text(msgNotStaged))
+		return
+	}
+
+	if h.bot.needsApproval(staged.Options, staged.Body) && !h.approve(inv, id, &staged) {
+		return
+	}
+
+	// Copy the staged message as it is, rather than rendering the body
+	// again, so that what was reviewed is exactly what gets posted.
+	stagedRef := messageRef{ChannelID: h.bot.StagingChannelID, MessageID: id}
+	stagedMsg, err := h.session.Message(stagedRef.ChannelID, stagedRef.MessageID)
+	if err != nil {
+		slog.Error(
+			"Bot has failed to fetch the staged announcement.",
+			"message_id", id,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	// Scheduled announcements are checked against the cooldown once they
+	// are due.
+	if staged.Options.SendAt.After(time.Now()) {
+		scheduled := h.scheduleAnnouncement(inv, scheduledAnnouncement{
+			AuthorID:    staged.AuthorID,
+			Body:        staged.Body,
+			Options:     staged.Options,
+			ChannelIDs:  staged.ChannelIDs,
+			Attachments: stagedMsg.Attachments,
+			Created:     time.Now(),
+			Staged:      &stagedRef,
+		})
+		if scheduled {
+			h.forgetStaged(id)
+		}
+		return
+	}
+
+	h.downloadThen(inv, stagedMsg.Attachments, func(files []attachmentFile, err error) {
+		if err != nil {
+			sendRejection(h.session, inv, inv.textWith(msgAttachmentFailed, replyData{Error: err}))
+			return
+		}
+		h.sendPromoted(inv, staged, stagedMsg, files)
+	})
+}
+
+// sendPromoted posts the staged announcement, as copied from its message in
+// the staging channel, into its channels, along with the files that the
+// attachments of that message were downloaded as.
+func (h *commandHandler) sendPromoted(inv *invocation, staged stagedAnnouncement, stagedMsg *discord.Message, files []attachmentFile) {
+	id := stagedMsg.ID
+	stagedRef := messageRef{ChannelID: stagedMsg.ChannelID, MessageID: id}
+
+	if !h.checkCooldown(inv) {
+		return
+	}
+
+	msg := announcementMessage{
+		Content:     stagedMsg.Content,
+		Attachments: stagedMsg.Attachments,
+		Files:       files,
+		Flags:       stagedMsg.Flags & (discord.SuppressEmbeds | discord.SuppressNotifications),
+		TTS:         staged.Options.TTS,
+		Mentions:    h.bot.allowedMentions(staged.Options),
+	}
+	for _, embed := range stagedMsg.Embeds {
+		// Link previews are generated by Discord and can't be sent.
+		if embed.Type == "" || embed.Type == discord.NormalEmbed {
+			msg.Embeds = append(msg.Embeds, embed)
+		}
+	}
+
+	dedupe, ok := h.claimDedupeKey(staged.AuthorID, staged.Body)
+	if !ok {
+		sendRejection(h.session, inv, inv.text(msgDuplicate))
+		return
+	}
+
+	// Staged announcements aren't numbered until they are posted, so that
+	// those that are never promoted don't leave gaps.
+	number := h.nextNumber()
+	msg.Content = withFooter(msg.Content, h.bot.numberLine(number))
+
+	entry := &outboxEntry{
+		AuthorID:    staged.AuthorID,
+		Body:        staged.Body,
+		Options:     staged.Options,
+		ChannelIDs:  staged.ChannelIDs,
+		Created:     time.Now(),
+		Staged:      &stagedRef,
+		Attachments: stagedMsg.Attachments,
+		Number:      number,
+	}
+	h.storeOutbox(dedupe, entry)
+
+	h.deliverOutbox(inv, dedupe, entry, msg, func() {
+		sent := entry.Sent
+		if len(sent) == 0 {
+			h.deleteOutbox(dedupe)
+			h.releaseDedupeKey(dedupe)
+			h.releaseNumber(number)
+
+			slog.Error(
+				"Bot has failed to send the promoted announcement to any of its channels.",
+				"message_id", id,
+				"ref", inv.errorRef())
+
+			replyInternalError(h.session, inv)
+			return
+		}
+
+		h.finishAnnouncement(dedupe, entry, msg)
+
+		h.forgetStaged(id)
+
+		slog.Info(
+			"Bot has promoted a staged announcement.",
+			"author_id", staged.AuthorID,
+			"promoted_by", inv.Author.ID,
+			"message_id", sent[0].MessageID)
+
+		data := replyData{Link: messageLink(h.bot.TargetGuildID, sent[0])}
+		if len(sent) < len(entry.ChannelIDs) {
+			sendReply(h.session, inv, inv.textWith(msgPartiallyAnnounced, data))
+		} else {
+			h.acknowledge(inv, inv.textWith(msgAnnounced, data))
+		}
+	})
+}
+
+func (h *commandHandler) forgetStaged(id discord.MessageID) {
+	if err := h.staged.Delete(id); err != nil {
+		slog.Warn(
+			"Bot has failed to forget the promoted announcement. It may be promoted again.",
+			"message_id", id,
+			"err", err)
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/diamondburned/ningen/v3"
+	"libdb.so/persist"
+	persistbadgerdb "libdb.so/persist/driver/badgerdb"
+)
+
+// stateChecker looks for problems in the databases of the state directory.
+type stateChecker struct {
+	dir    string
+	repair bool
+	// session is used to check that referenced messages still exist. If nil,
+	// references aren't checked.
+	session *ningen.State
+
+	problems int
+	repaired int
+}
+
+func (c *stateChecker) report(database string, key any, problem string) {
+	c.problems++
+	fmt.Printf("%s: %v: %s\n", database, key, problem)
+}
+
+// messageGone returns true if Discord says that the message no longer exists.
+// References are only checked if the checker has a session.
+func (c *stateChecker) messageGone(ref messageRef) bool {
+	if c.session == nil {
+		return false
+	}
+
+	_, err := c.session.Client.Message(ref.ChannelID, ref.MessageID)
+	if err == nil {
+		return false
+	}
+
+	var httpErr *httputil.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+		return true
+	}
+
+	fmt.Fprintf(os.Stderr, "cannot check message %d in channel %d: %v\n", ref.MessageID, ref.ChannelID, err)
+	return false
+}
+
+// checkDatabase goes through every entry of the database, reporting the
+// entries that can't be decoded and those that check finds a problem with.
+// When repairing, every reported entry is deleted.
+func checkDatabase[K, V any](c *stateChecker, database string, check func(K, V) string) error {
+	path := filepath.Join(c.dir, database)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	driver, err := persistbadgerdb.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", database, err)
+	}
+	defer driver.Close()
+
+	keys := persist.CBOREncoder[K]()
+	values := persist.CBOREncoder[V]()
+
+	var bad [][]byte
+	err = driver.AcquireRO(func(tx persist.DriverReadOnlyTx) error {
+		return tx.Each(func(bk, bv []byte) error {
+			k, err := keys.Decode(bk)
+			if err != nil {
+				c.report(database, fmt.Sprintf("%x", bk), "malformed key: "+err.Error())
+				bad = append(bad, bytes.Clone(bk))
+				return nil
+			}
+
+			v, err := values.Decode(bv)
+			if err != nil {
+				c.report(database, k, "malformed value: "+err.Error())
+				bad = append(bad, bytes.Clone(bk))
+				return nil
+			}
+
+			if problem := check(k, v); problem != "" {
+				c.report(database, k, problem)
+				bad = append(bad, bytes.Clone(bk))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", database, err)
+	}
+
+	if !c.repair || len(bad) == 0 {
+		return nil
+	}
+
+	err = driver.AcquireRW(func(tx persist.DriverReadWriteTx) error {
+		for _, k := range bad {
+			if err := tx.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot repair %s: %w", database, err)
+	}
+
+	c.repaired += len(bad)
+	return nil
+}
+
+// checkState checks every database in the state directory.
+func (c *stateChecker) checkState() error {
+	// Other databases refer to the announcement records, so go through them
+	// first.
+	records := make(map[discord.MessageID]announcementRecord)
+	err := checkDatabase(c, "announcements-v1", func(id discord.MessageID, record announcementRecord) string {
+		if !record.ChannelID.IsValid() {
+			return "the record has no channel"
+		}
+		if c.messageGone(messageRef{ChannelID: record.ChannelID, MessageID: id}) {
+			return "the announcement no longer exists"
+		}
+		records[id] = record
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "last-sent-authors-v1", func(authorID discord.UserID, id discord.MessageID) string {
+		if !id.IsValid() {
+			return "the last announcement is not a valid ID"
+		}
+		// Announcements from before the bot kept records were always sent to
+		// the target channel.
+		channelID := settings.TargetChannelID
+		if record, ok := records[id]; ok {
+			channelID = record.ChannelID
+		}
+		if c.messageGone(messageRef{ChannelID: channelID, MessageID: id}) {
+			return fmt.Sprintf("the last announcement %d no longer exists", id)
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "subscribe-prompts-v1", func(channelID discord.ChannelID, id discord.MessageID) string {
+		if c.messageGone(messageRef{ChannelID: channelID, MessageID: id}) {
+			return fmt.Sprintf("the prompt %d no longer exists", id)
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "dedupe-keys-v1", func(string, time.Time) string {
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "outbox-v1", func(id string, entry outboxEntry) string {
+		switch {
+		case len(entry.ChannelIDs) == 0:
+			return "the entry has no channels to send to"
+		case time.Since(entry.Created) > maxOutboxAge:
+			return "the entry is too old to ever be sent"
+		default:
+			return ""
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "staged-v1", func(id discord.MessageID, staged stagedAnnouncement) string {
+		switch {
+		case len(staged.ChannelIDs) == 0:
+			return "the staged announcement has no channels to be promoted to"
+		case c.messageGone(messageRef{ChannelID: settings.StagingChannelID, MessageID: id}):
+			return "the staged announcement no longer exists"
+		default:
+			return ""
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "countdowns-v1", func(id discord.MessageID, cd countdown) string {
+		switch {
+		case time.Since(cd.Target) > time.Hour:
+			return "the countdown should have finished long ago"
+		case c.messageGone(messageRef{ChannelID: cd.ChannelID, MessageID: id}):
+			return "the countdown message no longer exists"
+		default:
+			return ""
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "templates-v1", func(name string, t announcementTemplate) string {
+		switch {
+		case !entryNamePattern.MatchString(name):
+			return "the template has an invalid name"
+		case t.Body == "":
+			return "the template has no body"
+		default:
+			return ""
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "snippets-v1", func(name string, s snippet) string {
+		switch {
+		case !entryNamePattern.MatchString(name):
+			return "the snippet has an invalid name"
+		case s.Text == "":
+			return "the snippet has no text"
+		default:
+			return ""
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "deletions-v1", func(_ discord.MessageID, at time.Time) string {
+		if at.IsZero() {
+			return "the deletion has no time"
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "bumps-v1", func(_ discord.MessageID, at time.Time) string {
+		if at.IsZero() {
+			return "the bump has no time"
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "slowmodes-v1", func(_ discord.ChannelID, restore slowmodeRestore) string {
+		if restore.Until.IsZero() {
+			return "the slowmode has no time to restore it"
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "stickies-v1", func(channelID discord.ChannelID, s stickyAnnouncement) string {
+		if s.Current.IsValid() && c.messageGone(messageRef{ChannelID: channelID, MessageID: s.Current}) {
+			return "the sticky copy no longer exists"
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabase(c, "runtime-state-v1", func(key string, _ runtimeState) string {
+		if key != runtimeStateKey {
+			return "unknown key"
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	return checkDatabase(c, schemaDatabase, func(database string, version int) string {
+		if !slices.Contains(stateDatabases, database) {
+			return "unknown database"
+		}
+		if latest := latestSchemaVersion(database); version < 1 || version > latest {
+			return fmt.Sprintf("schema version %d is not between 1 and %d", version, latest)
+		}
+		return ""
+	})
+}
+
+func checkStateSubcommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("check-state", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "delete every entry that has a problem")
+	verify := flags.Bool("discord", false, "check that referenced messages still exist on Discord, using $DISCORD_TOKEN")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "the config is invalid:", err)
+		return 1
+	}
+
+	release, err := lockStateOffline(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot check the state:", err)
+		return 1
+	}
+	defer release()
+
+	c := &stateChecker{
+		dir:    stateDirectory,
+		repair: *repair,
+	}
+
+	if *verify {
+		token := os.Getenv("DISCORD_TOKEN")
+		if token == "" {
+			fmt.Fprintln(os.Stderr, "checking against Discord requires $DISCORD_TOKEN to be set")
+			return 1
+		}
+		c.session = newSession(token).WithContext(ctx)
+	}
+
+	if err := c.checkState(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch {
+	case c.problems == 0:
+		fmt.Fprintln(os.Stderr, "the state has no problems")
+		return 0
+	case c.repair:
+		fmt.Fprintf(os.Stderr, "found %d problems and repaired %d\n", c.problems, c.repaired)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "found %d problems, run again with --repair to delete the affected entries\n", c.problems)
+		return 1
+	}
+}
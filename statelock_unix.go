@@ -0,0 +1,72 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"time"
+)
+
+// acquireStateLock blocks until this instance holds the exclusive lock on the
+// given file in the state directory. Only the instance that holds it may open
+// the state and serve commands; others wait on standby and take over as soon
+// as it exits or dies, since the kernel releases the lock along with the
+// process.
+//
+// Only instances on the same host are kept apart, which is enough for
+// restarts and deploys. Running redundant instances on several hosts isn't
+// supported: the state is a local database that only one process can open,
+// so a standby on another host would have none of the records, numbers or
+// outbox to fail over with, and flock isn't reliable on network filesystems
+// for the state directory to be shared either. Failing over between hosts
+// would need a shared state backend to hold a lease in, which this bot
+// doesn't have.
+//
+// The returned function releases the lock.
+func acquireStateLock(ctx context.Context, path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open lock file: %w", err)
+	}
+
+	waiting := false
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, fmt.Errorf("cannot lock lock file: %w", err)
+		}
+
+		if !waiting {
+			waiting = true
+			slog.Info(
+				"Another instance of this bot is running. This instance will wait on standby until it exits.",
+				"lock_file", path)
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	// Leave a note for operators about who holds the lock.
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// statsWindows maps each window that the stats command accepts to how far
+// back it looks. A zero duration covers all time.
+var statsWindows = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"all":   0,
+}
+
+// defaultStatsWindow is the window used when the stats command is given none.
+const defaultStatsWindow = "month"
+
+// authorStats is the summary of the announcements of one author.
+type authorStats struct {
+	AuthorID discord.UserID
+	Count    int
+	Last     time.Time
+}
+
+// announcementStats summarizes the recorded announcements per author, starting
+// from the given time. The authors who announced the most come first.
+func (h *commandHandler) announcementStats(since time.Time) []authorStats {
+	byAuthor := make(map[discord.UserID]*authorStats)
+	h.announcements.All()(func(_ discord.MessageID, record announcementRecord) bool {
+		if record.Time.Before(since) {
+			return true
+		}
+
+		stats, ok := byAuthor[record.AuthorID]
+		if !ok {
+			stats = &authorStats{AuthorID: record.AuthorID}
+			byAuthor[record.AuthorID] = stats
+		}
+
+		stats.Count++
+		if record.Time.After(stats.Last) {
+			stats.Last = record.Time
+		}
+		return true
+	})
+
+	all := make([]authorStats, 0, len(byAuthor))
+	for _, stats := range byAuthor {
+		all = append(all, *stats)
+	}
+
+	slices.SortFunc(all, func(a, b authorStats) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return b.Last.Compare(a.Last)
+	})
+
+	return all
+}
+
+// displayName returns the name that the user goes by in the target guild. The
+// user is deliberately not mentioned, so that listing them doesn't ping them.
+func (h *commandHandler) displayName(userID discord.UserID) string {
+	if isPseudonym(userID) {
+		return "forgotten user " + userID.String()
+	}
+	if member, err := h.session.Member(h.bot.TargetGuildID, userID); err == nil {
+		if member.Nick != "" {
+			return member.Nick
+		}
+		return member.User.DisplayOrUsername()
+	}
+	if user, err := h.session.User(userID); err == nil {
+		return user.DisplayOrUsername()
+	}
+	return userID.String()
+}
+
+func (h *commandHandler) showStats(inv *invocation, command *parsedCommand) {
+	window := strings.ToLower(strings.TrimSpace(command.Args))
+	if window == "" {
+		window = defaultStatsWindow
+	}
+
+	age, ok := statsWindows[window]
+	if !ok {
+		sendRejection(h.session, inv, inv.text(msgInvalidStatsWindow))
+		return
+	}
+
+	var since time.Time
+	if age > 0 {
+		since = time.Now().Add(-age)
+	}
+
+	all := h.announcementStats(since)
+	if len(all) == 0 {
+		sendReply(h.session, inv, inv.textWith(msgNoStats, replyData{Window: window}))
+		return
+	}
+
+	lines := make([]string, len(all))
+	for i, stats := range all {
+		lines[i] = fmt.Sprintf("**%s**: %d (%s)",
+			escapeMarkup(h.displayName(stats.AuthorID)),
+			stats.Count,
+			timestampMarkup(stats.Last, timestampRelative))
+	}
+
+	sendReply(h.session, inv, inv.textWith(msgStats, replyData{
+		Window: window,
+		Report: formatBulletList(lines),
+	}))
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// stickyRepostInterval is how often sticky announcements that have been
+// pushed up are posted again. It keeps the bot from reposting after every
+// message in a busy channel.
+const stickyRepostInterval = 30 * time.Second
+
+// stickyAnnouncement is an announcement that the bot keeps as the latest
+// message of a channel. It is keyed by the channel.
+type stickyAnnouncement struct {
+	// Announcement is the primary message of the announcement.
+	Announcement discord.MessageID
+	// Current is the copy of the announcement at the bottom of the channel.
+	// It is zero until the announcement is first pushed up, since the
+	// announcement itself is at the bottom until then.
+	Current discord.MessageID
+}
+
+// noteChannelActivity marks the sticky announcement of the channel, if any, as
+// pushed up by the new message.
+func (h *commandHandler) noteChannelActivity(ev *gateway.MessageCreateEvent) {
+	if ev.GuildID != h.bot.TargetGuildID || ev.Author.ID == h.bot.SelfID {
+		return
+	}
+	if _, ok, err := h.stickies.Load(ev.ChannelID); err == nil && ok {
+		h.pushedStickies[ev.ChannelID] = struct{}{}
+	}
+}
+
+// setSticky makes the announcement sticky in the channels of the given
+// messages, or stops it from being sticky in any channel. A channel only has
+// one sticky announcement, so making an announcement sticky replaces the old
+// one. Forum posts are always the first message of their own thread, so they
+// are skipped.
+func (h *commandHandler) setSticky(id discord.MessageID, messages []messageRef, sticky bool) {
+	var channelIDs []discord.ChannelID
+	for _, ref := range messages {
+		if !ref.isForumPost() {
+			channelIDs = append(channelIDs, ref.ChannelID)
+		}
+	}
+
+	type item struct {
+		channelID discord.ChannelID
+		s         stickyAnnouncement
+	}
+
+	var replaced []item
+	h.stickies.All()(func(channelID discord.ChannelID, s stickyAnnouncement) bool {
+		if s.Announcement == id && !sticky || s.Announcement != id && sticky && slices.Contains(channelIDs, channelID) {
+			replaced = append(replaced, item{channelID, s})
+		}
+		return true
+	})
+
+	for _, item := range replaced {
+		h.unstick(item.channelID, item.s)
+	}
+
+	if !sticky {
+		return
+	}
+
+	for _, channelID := range channelIDs {
+		if s, ok, err := h.stickies.Load(channelID); err == nil && ok && s.Announcement == id {
+			continue
+		}
+		if err := h.stickies.Store(channelID, stickyAnnouncement{Announcement: id}); err != nil {
+			slog.Error(
+				"Bot has failed to make the announcement sticky.",
+				"channel_id", channelID,
+				"message_id", id,
+				"err", err)
+		}
+	}
+}
+
+// unstick deletes the sticky copy of the announcement in the channel and
+// forgets it.
+func (h *commandHandler) unstick(channelID discord.ChannelID, s stickyAnnouncement) {
+	if s.Current.IsValid() {
+		if err := h.session.DeleteMessage(channelID, s.Current, "the announcement is no longer sticky"); err != nil {
+			slog.Warn(
+				"Bot has failed to delete the sticky copy of an announcement.",
+				"channel_id", channelID,
+				"message_id", s.Current,
+				"err", err)
+		}
+	}
+
+	if err := h.stickies.Delete(channelID); err != nil {
+		slog.Warn(
+			"Bot has failed to forget a sticky announcement.",
+			"channel_id", channelID,
+			"err", err)
+	}
+	delete(h.pushedStickies, channelID)
+}
+
+// stickyCopies returns the sticky copies of the announcement, so that they are
+// edited along with it.
+func (h *commandHandler) stickyCopies(id discord.MessageID) []messageRef {
+	var refs []messageRef
+	h.stickies.All()(func(channelID discord.ChannelID, s stickyAnnouncement) bool {
+		if s.Announcement == id && s.Current.IsValid() {
+			refs = append(refs, messageRef{ChannelID: channelID, MessageID: s.Current})
+		}
+		return true
+	})
+	return refs
+}
+
+// repostStickies posts the sticky announcements that have been pushed up
+// again at the bottom of their channels, replacing their old copies.
+func (h *commandHandler) repostStickies() {
+	channelIDs := make([]discord.ChannelID, 0, len(h.pushedStickies))
+	for channelID := range h.pushedStickies {
+		channelIDs = append(channelIDs, channelID)
+	}
+
+	for _, channelID := range channelIDs {
+		delete(h.pushedStickies, channelID)
+
+		s, ok, err := h.stickies.Load(channelID)
+		if err != nil || !ok {
+			continue
+		}
+
+		record, ok, err := h.announcements.Load(s.Announcement)
+		if err != nil || !ok {
+			// The announcement is gone, so there is nothing left to keep at
+			// the bottom.
+			h.unstick(channelID, s)
+			continue
+		}
+
+		// Records from before the body was kept only have what was posted.
+		var msg announcementMessage
+		if record.Body != "" {
+			msg, err = h.bot.renderAnnouncement(record.Options, withFooter(record.Body, h.bot.numberLine(record.Number), record.Footer))
+		} else if len(record.Revisions) > 0 {
+			msg.Content = record.Revisions[len(record.Revisions)-1].Content
+		}
+		if err != nil || msg.Content == "" && len(msg.Embeds) == 0 {
+			h.unstick(channelID, s)
+			continue
+		}
+
+		repost, err := h.session.SendMessageComplex(channelID, api.SendMessageData{
+			Content: msg.Content,
+			Embeds:  msg.Embeds,
+			// The announcement already notified everyone it should have.
+			Flags:           msg.Flags | discord.SuppressNotifications,
+			AllowedMentions: &api.AllowedMentions{},
+		})
+		if err != nil {
+			slog.Warn(
+				"Bot has failed to repost a sticky announcement. It will try again later.",
+				"channel_id", channelID,
+				"message_id", s.Announcement,
+				"err", err)
+			h.pushedStickies[channelID] = struct{}{}
+			continue
+		}
+
+		if s.Current.IsValid() {
+			if err := h.session.DeleteMessage(channelID, s.Current, "reposting the sticky announcement"); err != nil {
+				slog.Warn(
+					"Bot has failed to delete the old copy of a sticky announcement.",
+					"channel_id", channelID,
+					"message_id", s.Current,
+					"err", err)
+			}
+		}
+
+		s.Current = repost.ID
+		if err := h.stickies.Store(channelID, s); err != nil {
+			slog.Warn(
+				"Bot has failed to store the new copy of a sticky announcement.",
+				"channel_id", channelID,
+				"message_id", repost.ID,
+				"err", err)
+		}
+	}
+}
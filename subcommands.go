@@ -0,0 +1,394 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"libdb.so/persist"
+	persistbadgerdb "libdb.so/persist/driver/badgerdb"
+)
+
+// subcommand is a mode that the binary can be run in.
+type subcommand struct {
+	// Usage is the arguments that the subcommand takes.
+	Usage string
+	// Description describes what the subcommand does.
+	Description string
+	// Run runs the subcommand with the remaining arguments and returns the
+	// exit code.
+	Run func(ctx context.Context, args []string) int
+}
+
+// defaultSubcommand is the subcommand that is run if none is given.
+const defaultSubcommand = "run"
+
+// subcommands maps each subcommand name to its subcommand.
+var subcommands = map[string]subcommand{
+	"run": {
+		Description: "run the bot (default)",
+		Run: func(ctx context.Context, args []string) int {
+			return run(ctx)
+		},
+	},
+	"backup": {
+		Usage:       "<file.tar.gz>",
+		Description: "archive the state directory while the bot is stopped",
+		Run:         backupState,
+	},
+	"audit": {
+		Usage:       "[verify]",
+		Description: "list every announcement that the bot has posted, or check that the audit log of alerts hasn't been tampered with",
+		Run:         auditAnnouncements,
+	},
+	"backfill": {
+		Description: "remember who made the announcements in the target channel's history",
+		Run:         backfillSubcommand,
+	},
+	"check-state": {
+		Usage:       "[--repair] [--discord]",
+		Description: "look for broken entries in the state directory",
+		Run:         checkStateSubcommand,
+	},
+	"doctor": {
+		Description: "check that the bot has what it needs on Discord",
+		Run:         doctorSubcommand,
+	},
+	"migrate-state": {
+		Description: "bring the state directory up to the current version",
+		Run:         migrateState,
+	},
+	"validate-config": {
+		Usage:       "[file]",
+		Description: "check the config file, or $CONFIG_FILE, for mistakes",
+		Run:         validateConfig,
+	},
+	"version": {
+		Description: "print the version of the bot",
+		Run:         printVersion,
+	},
+}
+
+// subcommandNames returns the names of all subcommands in order.
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// stateLockFile is the name of the lock file in the state directory that the
+// running instance holds.
+const stateLockFile = "state.lock"
+
+// lockStateOffline takes the state lock for a subcommand that works on the
+// state directory directly, which cannot be done while the bot is running.
+func lockStateOffline(ctx context.Context) (func(), error) {
+	if _, err := os.Stat(stateDirectory); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	release, err := acquireStateLock(ctx, filepath.Join(stateDirectory, stateLockFile))
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, errors.New("the bot is still running, so it must be stopped first")
+	}
+	return release, err
+}
+
+func backupState(ctx context.Context, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: backup <file.tar.gz>")
+		return 2
+	}
+
+	release, err := lockStateOffline(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot back up the state:", err)
+		return 1
+	}
+	defer release()
+
+	if err := writeBackup(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, "cannot back up the state:", err)
+		os.Remove(args[0])
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "backed up", stateDirectory, "to", args[0])
+	return 0
+}
+
+// writeBackup writes the state directory into a gzipped tarball at dst.
+func writeBackup(dst string) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(stateDirectory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(stateDirectory, path)
+		if err != nil || name == "." || name == stateLockFile {
+			return err
+		}
+		if name == controlSocketDirectory {
+			return filepath.SkipDir
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func auditAnnouncements(ctx context.Context, args []string) int {
+	release, err := lockStateOffline(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot read the state:", err)
+		return 1
+	}
+	defer release()
+
+	if len(args) > 0 {
+		if len(args) != 1 || args[0] != "verify" {
+			fmt.Fprintln(os.Stderr, "usage: audit [verify]")
+			return 2
+		}
+		return verifyAuditSubcommand()
+	}
+
+	announcements, err := persist.NewMap[discord.MessageID, announcementRecord](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "announcements-v1"),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot open the announcements database:", err)
+		return 1
+	}
+	defer closeStore("announcements", announcements)
+
+	type row struct {
+		id     discord.MessageID
+		record announcementRecord
+	}
+
+	var rows []row
+	announcements.All()(func(id discord.MessageID, record announcementRecord) bool {
+		rows = append(rows, row{id, record})
+		return true
+	})
+
+	slices.SortFunc(rows, func(a, b row) int {
+		return a.record.Time.Compare(b.record.Time)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tMESSAGE\tCHANNEL\tAUTHOR\tCOPIES")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n",
+			row.record.Time.Format(time.RFC3339),
+			row.id,
+			row.record.ChannelID,
+			row.record.AuthorID,
+			len(row.record.Copies))
+	}
+	w.Flush()
+
+	return 0
+}
+
+func verifyAuditSubcommand() int {
+	auditLog, err := persist.NewMap[uint64, auditEntry](
+		persistbadgerdb.Open,
+		filepath.Join(stateDirectory, "audit-log-v1"),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot open the audit log:", err)
+		return 1
+	}
+	defer closeStore("audit log", auditLog)
+
+	n, hash, err := verifyAuditLog(auditLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "the audit log is broken after %d intact entries: %v\n", n, err)
+		return 1
+	}
+
+	// A chain that was recomputed from scratch is intact too, so its end has
+	// to match what was posted into the audit channel.
+	fmt.Fprintf(os.Stderr, "the audit log of alerts is intact with %d entries\n", n)
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "compare its latest hash against the last alert in the audit channel: entry %d, %s\n", n, hash)
+	}
+	fmt.Fprintln(os.Stderr, "announcement records aren't covered by the audit log")
+	return 0
+}
+
+// stateDatabases lists the databases that the current version of the bot
+// keeps in the state directory.
+var stateDatabases = []string{
+	"last-sent-authors-v1",
+	"announcements-v1",
+	"subscribe-prompts-v1",
+	"dedupe-keys-v1",
+	"outbox-v1",
+	"staged-v1",
+	"countdowns-v1",
+	"templates-v1",
+	"snippets-v1",
+	"deletions-v1",
+	"scheduled-v1",
+	"stickies-v1",
+	"bumps-v1",
+	"slowmodes-v1",
+	"audit-log-v1",
+	"signatures-v1",
+	"search-terms-v1",
+	"search-documents-v1",
+	"runtime-state-v1",
+	schemaDatabase,
+}
+
+func migrateState(ctx context.Context, args []string) int {
+	release, err := lockStateOffline(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot migrate the state:", err)
+		return 1
+	}
+	defer release()
+
+	entries, err := os.ReadDir(stateDirectory)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot read the state directory:", err)
+		return 1
+	}
+
+	// Point out anything that this version doesn't know about, since it may
+	// have been left by a newer version.
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != controlSocketDirectory && !slices.Contains(stateDatabases, entry.Name()) {
+			fmt.Fprintln(os.Stderr, "unknown database in the state directory:", entry.Name())
+		}
+	}
+
+	applied, err := migrateStateDirectory(stateDirectory)
+	for _, migration := range applied {
+		fmt.Fprintln(os.Stderr, "migrated", migration)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot migrate the state:", err)
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "the state directory is at the current version")
+	return 0
+}
+
+func printVersion(ctx context.Context, args []string) int {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		fmt.Println("message-for-me (unknown version)")
+		return 0
+	}
+
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			version += " (" + setting.Value + ")"
+		}
+	}
+
+	fmt.Println("message-for-me", version, info.GoVersion)
+	return 0
+}
+
+func validateConfig(ctx context.Context, args []string) int {
+	var path string
+	switch len(args) {
+	case 0:
+		path = os.Getenv(configFileEnv)
+	case 1:
+		path = args[0]
+	default:
+		fmt.Fprintln(os.Stderr, "usage: validate-config [file]")
+		return 2
+	}
+
+	s := settings
+	if path != "" {
+		var err error
+		s, err = loadSettings(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	errs := s.validate()
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "the config is valid")
+	return 0
+}
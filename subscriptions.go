@@ -0,0 +1,144 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// subscribeEmoji is the reaction that members use on the subscribe prompt to
+// get the notification role.
+const subscribeEmoji = "🔔"
+
+// subscribe adds or removes the notification role on the author.
+func (h *commandHandler) subscribe(inv *invocation, subscribe bool) {
+	if !h.bot.AnnounceRoleID.IsValid() {
+		sendRejection(h.session, inv, inv.text(msgNoAnnounceRole))
+		return
+	}
+
+	var err error
+	if subscribe {
+		err = h.session.AddRole(h.bot.TargetGuildID, inv.Author.ID, h.bot.AnnounceRoleID, api.AddRoleData{
+			AuditLogReason: "member subscribed to announcements",
+		})
+	} else {
+		err = h.session.RemoveRole(h.bot.TargetGuildID, inv.Author.ID, h.bot.AnnounceRoleID,
+			"member unsubscribed from announcements")
+	}
+	if err != nil {
+		slog.Error(
+			"Bot has failed to update the notification role of the author.",
+			"author_id", inv.Author.ID,
+			"role_id", h.bot.AnnounceRoleID,
+			"subscribe", subscribe,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	if subscribe {
+		h.acknowledge(inv, inv.text(msgSubscribed))
+	} else {
+		h.acknowledge(inv, inv.text(msgUnsubscribed))
+	}
+}
+
+// repostSubscribePrompt replaces the subscribe prompt in the target channel
+// with a new one, so that it stays right below the latest announcement.
+func (h *commandHandler) repostSubscribePrompt() {
+	channelID := h.bot.TargetChannelID
+
+	oldID, ok, err := h.subscribePrompts.Load(channelID)
+	if err != nil {
+		slog.Warn(
+			"Bot has failed to look up the subscribe prompt.",
+			"channel_id", channelID,
+			"err", err)
+	}
+	if ok {
+		if err := h.session.DeleteMessage(channelID, oldID, "replacing the subscribe prompt"); err != nil {
+			slog.Warn(
+				"Bot has failed to delete the old subscribe prompt.",
+				"channel_id", channelID,
+				"message_id", oldID,
+				"err", err)
+		}
+	}
+
+	prompt, err := h.session.SendMessageComplex(channelID, api.SendMessageData{
+		Content: localize(h.bot.locale(), msgSubscribePrompt, replyData{}),
+		// The prompt should never ping anyone.
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		slog.Error(
+			"Bot has failed to post the subscribe prompt.",
+			"channel_id", channelID,
+			"err", err)
+		return
+	}
+
+	if err := h.subscribePrompts.Store(channelID, prompt.ID); err != nil {
+		slog.Warn(
+			"Bot has failed to store the subscribe prompt.",
+			"channel_id", channelID,
+			"message_id", prompt.ID,
+			"err", err)
+	}
+
+	// React first so that members only have to click.
+	if err := h.session.React(channelID, prompt.ID, subscribeEmoji); err != nil {
+		slog.Warn(
+			"Bot has failed to react to the subscribe prompt.",
+			"channel_id", channelID,
+			"message_id", prompt.ID,
+			"err", err)
+	}
+}
+
+// handleSubscribeReaction gives or takes the notification role from a member
+// who reacted to the subscribe prompt.
+func (h *commandHandler) handleSubscribeReaction(userID discord.UserID, channelID discord.ChannelID, messageID discord.MessageID, emoji discord.Emoji, add bool) {
+	if !h.bot.SubscribePrompt || !h.bot.AnnounceRoleID.IsValid() {
+		return
+	}
+
+	if userID == h.bot.SelfID || emoji.Name != subscribeEmoji || emoji.IsCustom() {
+		return
+	}
+
+	promptID, ok, err := h.subscribePrompts.Load(channelID)
+	if err != nil || !ok || promptID != messageID {
+		return
+	}
+
+	if add {
+		err = h.session.AddRole(h.bot.TargetGuildID, userID, h.bot.AnnounceRoleID, api.AddRoleData{
+			AuditLogReason: "member reacted to the subscribe prompt",
+		})
+	} else {
+		err = h.session.RemoveRole(h.bot.TargetGuildID, userID, h.bot.AnnounceRoleID,
+			"member unreacted to the subscribe prompt")
+	}
+	if err != nil {
+		slog.Error(
+			"Bot has failed to update the notification role of a member.",
+			"user_id", userID,
+			"role_id", h.bot.AnnounceRoleID,
+			"subscribe", add,
+			"err", err)
+	}
+}
+
+func (h *commandHandler) handleReactionAdd(ev *gateway.MessageReactionAddEvent) {
+	h.handleSubscribeReaction(ev.UserID, ev.ChannelID, ev.MessageID, ev.Emoji, true)
+}
+
+func (h *commandHandler) handleReactionRemove(ev *gateway.MessageReactionRemoveEvent) {
+	h.handleSubscribeReaction(ev.UserID, ev.ChannelID, ev.MessageID, ev.Emoji, false)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// placeholderPattern matches the placeholders in a template, such as
+// "{{version}}". Helpers such as "{{time: ...}}" aren't placeholders, since
+// they are expanded after the template is filled in.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-z][a-z0-9_-]*)\s*\}\}`)
+
+// announcementTemplate is a saved announcement that authors fill in using
+// the template command. It is keyed by its name.
+type announcementTemplate struct {
+	// Options is the front matter that announcements from the template get.
+	// Options that the author gives when using it take precedence.
+	Options  string
+	Body     string
+	AuthorID discord.UserID
+	Updated  time.Time
+}
+
+// placeholders returns the names of the placeholders in the template, in the
+// order that they first appear.
+func (t announcementTemplate) placeholders() []string {
+	var names []string
+	for _, m := range placeholderPattern.FindAllStringSubmatch(t.Options+"\n"+t.Body, -1) {
+		if !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// fillPlaceholders replaces the placeholders in the text with the given
+// values.
+func fillPlaceholders(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return values[placeholderPattern.FindStringSubmatch(match)[1]]
+	})
+}
+
+// parseTemplateValues parses the values to fill a template in with, given as
+//
+//	name: value
+//
+// with each value on its own line.
+func parseTemplateValues(text string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("value %q is missing a name", line)
+		}
+		values[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return values, nil
+}
+
+// template handles the subcommands of the template command:
+//
+//	template list
+//	template save <name>
+//	template use <name>
+//	template delete <name>
+//
+// Saving takes the template as the body, and using it takes the values to
+// fill it in with as the body.
+func (h *commandHandler) template(inv *invocation, command *parsedCommand) {
+	h.runNamedCommand(inv, command.Args, namedCommand{
+		Kind: "template",
+		Actions: []namedAction{
+			{"list", func(string) { h.listTemplates(inv) }},
+			{"save", func(name string) { h.saveTemplate(inv, name, command) }},
+			{"use", func(name string) { h.useTemplate(inv, name, command) }},
+			{"delete", func(name string) {
+				deleteNamed(h, inv, h.templates, "template", name, msgTemplateNotFound, msgTemplateDeleted)
+			}},
+		},
+		Invalid: msgInvalidTemplate,
+	})
+}
+
+func (h *commandHandler) listTemplates(inv *invocation) {
+	var lines []string
+	h.templates.All()(func(name string, t announcementTemplate) bool {
+		line := "`" + name + "`"
+		if names := t.placeholders(); len(names) > 0 {
+			line += ": " + strings.Join(names, ", ")
+		}
+		lines = append(lines, line)
+		return true
+	})
+
+	if len(lines) == 0 {
+		sendReply(h.session, inv, inv.text(msgNoTemplates))
+		return
+	}
+
+	slices.Sort(lines)
+	sendReply(h.session, inv, inv.textWith(msgTemplates, replyData{Report: formatBulletList(lines)}))
+}
+
+func (h *commandHandler) saveTemplate(inv *invocation, name string, command *parsedCommand) {
+	if strings.TrimSpace(command.Body) == "" {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidTemplate, replyData{
+			Error: errors.New("the template must have a body"),
+		}))
+		return
+	}
+
+	// Placeholders may stand in for option values, so the options are only
+	// checked once the template is used.
+	t := announcementTemplate{
+		Options:  command.Options,
+		Body:     command.Body,
+		AuthorID: inv.Author.ID,
+		Updated:  time.Now(),
+	}
+
+	if err := h.templates.Store(name, t); err != nil {
+		slog.Error(
+			"Bot has failed to save a template.",
+			"name", name,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+
+	h.acknowledge(inv, inv.textWith(msgTemplateSaved, replyData{Name: name}))
+}
+
+func (h *commandHandler) useTemplate(inv *invocation, name string, command *parsedCommand) {
+	// Using a template announces, so it is held back like announce is.
+	switch {
+	case h.draining:
+		sendRejection(h.session, inv, inv.text(msgDraining))
+		return
+	case h.bot.Runtime.Paused:
+		sendRejection(h.session, inv, inv.textWith(msgPaused, replyData{Reason: h.bot.Runtime.PauseReason}))
+		return
+	}
+
+	if member := inv.member(); member != nil {
+		perms, err := memberPermissions(h.session, *h.bot, inv.Author.ID, member)
+		if err != nil || !canUse(*h.bot, "announce", member, perms) {
+			sendRejection(h.session, inv, inv.text(msgNotAuthorized))
+			return
+		}
+	}
+
+	t, ok, err := h.templates.Load(name)
+	if err != nil {
+		slog.Error(
+			"Bot has failed to load a template.",
+			"name", name,
+			"ref", inv.errorRef(),
+			"err", err)
+
+		replyInternalError(h.session, inv)
+		return
+	}
+	if !ok {
+		sendRejection(h.session, inv, inv.textWith(msgTemplateNotFound, replyData{Name: name}))
+		return
+	}
+
+	values, err := parseTemplateValues(command.Body)
+	if err != nil {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidTemplate, replyData{Error: err}))
+		return
+	}
+
+	names := t.placeholders()
+	var missing []string
+	for _, p := range names {
+		if _, ok := values[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	for p := range values {
+		if !slices.Contains(names, p) {
+			sendRejection(h.session, inv, inv.textWith(msgInvalidTemplate, replyData{
+				Error: fmt.Errorf("the template has no %q to fill in", p),
+			}))
+			return
+		}
+	}
+	if len(missing) > 0 {
+		sendRejection(h.session, inv, inv.textWith(msgInvalidTemplate, replyData{
+			Error: fmt.Errorf("the template still needs %s", strings.Join(missing, ", ")),
+		}))
+		return
+	}
+
+	// The author's own options are added after the template's, so that they
+	// win.
+	options := fillPlaceholders(t.Options, values)
+	if command.Options != "" {
+		options += "\n" + command.Options
+	}
+
+	h.announce(inv, &parsedCommand{
+		Command: "announce",
+		Body:    fillPlaceholders(t.Body, values),
+		Options: options,
+	})
+}
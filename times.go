@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeHelperPattern matches the time helper in announcement bodies, such as
+// "{{time: friday 18:00 CET}}".
+var timeHelperPattern = regexp.MustCompile(`\{\{\s*time:\s*([^{}]*?)\s*\}\}`)
+
+// zoneAbbreviations are the timezone abbreviations that may end a time. Each
+// is a fixed offset, so "CET" is always UTC+1, even in summer.
+var zoneAbbreviations = map[string]time.Duration{
+	"UTC":  0,
+	"GMT":  0,
+	"WET":  0,
+	"BST":  time.Hour,
+	"WEST": 1 * time.Hour,
+	"CET":  time.Hour,
+	"CEST": 2 * time.Hour,
+	"EET":  2 * time.Hour,
+	"EEST": 3 * time.Hour,
+	"MSK":  3 * time.Hour,
+	"IST":  5*time.Hour + 30*time.Minute,
+	"JST":  9 * time.Hour,
+	"AEST": 10 * time.Hour,
+	"AEDT": 11 * time.Hour,
+	"EST":  -5 * time.Hour,
+	"EDT":  -4 * time.Hour,
+	"CST":  -6 * time.Hour,
+	"CDT":  -5 * time.Hour,
+	"MST":  -7 * time.Hour,
+	"MDT":  -6 * time.Hour,
+	"PST":  -8 * time.Hour,
+	"PDT":  -7 * time.Hour,
+}
+
+// clockLayouts are the layouts that the time of day may be written in.
+var clockLayouts = []string{"15:04", "3:04pm", "3pm"}
+
+// convertTimes replaces the time helpers in the body with Discord timestamp
+// markup, so that every reader sees the time in their own timezone. Times
+// without a timezone are read in the configured Timezone.
+func (b botState) convertTimes(body string, now time.Time) (string, error) {
+	if !timeHelperPattern.MatchString(body) {
+		return body, nil
+	}
+
+	var errs []error
+	body = timeHelperPattern.ReplaceAllStringFunc(body, func(match string) string {
+		text := timeHelperPattern.FindStringSubmatch(match)[1]
+		t, err := parseHumanTime(text, now.In(b.timezone()))
+		if err != nil {
+			errs = append(errs, err)
+			return match
+		}
+		return timestampMarkup(t, timestampFull) + " (" + timestampMarkup(t, timestampRelative) + ")"
+	})
+	return body, errors.Join(errs...)
+}
+
+// timezone returns the configured Timezone, or UTC if there is none. The
+// setting is checked when the config is loaded.
+func (s botSettings) timezone() *time.Location {
+	if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// parseHumanTime parses a time such as "friday 18:00 CET", "tomorrow 9am",
+// "2024-06-01 18:00 Europe/Berlin" or "2024-06-01T18:00:00+02:00". The time is
+// read in the location of now unless it ends with a timezone. A time of day
+// without a date is the next time that it comes around.
+func parseHumanTime(text string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) < 1 || len(fields) > 3 {
+		return time.Time{}, fmt.Errorf("%q is not a time", text)
+	}
+
+	loc := now.Location()
+	if len(fields) > 1 {
+		if l, ok := parseZone(strings.Fields(text)[len(fields)-1]); ok {
+			loc = l
+			fields = fields[:len(fields)-1]
+		}
+	}
+	now = now.In(loc)
+
+	clock, ok := parseClock(fields[len(fields)-1])
+	if !ok {
+		return time.Time{}, fmt.Errorf("%q has no time of day, such as 18:00 or 6pm", text)
+	}
+	at := func(date time.Time) time.Time {
+		return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+
+	if len(fields) == 1 {
+		t := at(now)
+		if !t.After(now) {
+			t = at(now.AddDate(0, 0, 1))
+		}
+		return t, nil
+	}
+
+	switch day := fields[0]; day {
+	case "today":
+		return at(now), nil
+	case "tomorrow":
+		return at(now.AddDate(0, 0, 1)), nil
+	default:
+		if date, err := time.ParseInLocation("2006-01-02", day, loc); err == nil {
+			return at(date), nil
+		}
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			name := strings.ToLower(wd.String())
+			if day != name && day != name[:3] {
+				continue
+			}
+			days := (int(wd) - int(now.Weekday()) + 7) % 7
+			t := at(now.AddDate(0, 0, days))
+			if !t.After(now) {
+				t = t.AddDate(0, 0, 7)
+			}
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("%q is not a day, such as friday or 2024-06-01", fields[0])
+	}
+}
+
+// parseLongDuration parses a duration such as "90m" or "12h", or a number of
+// days such as "3d".
+func parseLongDuration(v string) (time.Duration, error) {
+	days, ok := strings.CutSuffix(v, "d")
+	if !ok {
+		return time.ParseDuration(v)
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number of days", v)
+	}
+	return time.Duration(n) * 24 * time.Hour, nil
+}
+
+// parseClock parses the time of day, such as "18:00" or "6pm".
+func parseClock(text string) (time.Time, bool) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseZone parses a timezone abbreviation such as "CET", an IANA name such
+// as "Europe/Berlin" or an offset such as "+02:00".
+func parseZone(text string) (*time.Location, bool) {
+	if offset, ok := zoneAbbreviations[strings.ToUpper(text)]; ok {
+		return time.FixedZone(strings.ToUpper(text), int(offset.Seconds())), true
+	}
+	if strings.Contains(text, "/") {
+		if loc, err := time.LoadLocation(text); err == nil {
+			return loc, true
+		}
+	}
+	if t, err := time.Parse("-07:00", text); err == nil {
+		return t.Location(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+)
+
+// fallbackTokenEnv is the environment variable that holds the token of a
+// second account that the bot falls back to if Discord refuses the one in
+// $DISCORD_TOKEN.
+const fallbackTokenEnv = "DISCORD_TOKEN_FALLBACK"
+
+// errTokenRefused is returned by checkToken if Discord won't let the token
+// connect.
+var errTokenRefused = errors.New("token is refused")
+
+// checkToken checks that Discord accepts the token and that it may still
+// identify on the gateway. It returns errTokenRefused if it doesn't, or
+// another error if it can't tell.
+func checkToken(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	session := newSession(token).WithContext(ctx)
+
+	me, err := session.Me()
+	if err != nil {
+		var httpErr *httputil.HTTPError
+		if errors.As(err, &httpErr) && (httpErr.Status == http.StatusUnauthorized || httpErr.Status == http.StatusForbidden) {
+			return fmt.Errorf("%w: %v", errTokenRefused, err)
+		}
+		return err
+	}
+
+	// Only bot accounts have a limit on how often they may identify.
+	if !me.Bot {
+		return nil
+	}
+
+	data, err := session.BotURL()
+	if err != nil {
+		return err
+	}
+	if limit := data.StartLimit; limit != nil && limit.Remaining == 0 {
+		return fmt.Errorf("%w: no sessions may be started for another %v",
+			errTokenRefused, limit.ResetAfter.Duration())
+	}
+
+	return nil
+}
+
+// pickToken returns the token to connect with and whether it is the
+// fallback. The fallback token is only used if Discord refuses the primary
+// one. The state isn't tied to either account, so both share it, although
+// neither can edit the announcements that the other has posted.
+func pickToken(ctx context.Context, primary, fallback string) (string, bool) {
+	if fallback == "" {
+		return primary, false
+	}
+
+	err := checkToken(ctx, primary)
+	if !errors.Is(err, errTokenRefused) {
+		if err != nil {
+			slog.Warn(
+				"Bot could not check its token. It will try to connect with it anyway.",
+				"err", err)
+		}
+		return primary, false
+	}
+
+	if err := checkToken(ctx, fallback); err != nil {
+		slog.Error(
+			"Bot could not use its fallback token either. It will try the primary token anyway.",
+			"err", err)
+		return primary, false
+	}
+
+	slog.Warn(
+		"Discord has refused the primary token, so the bot will use the fallback token.",
+		"err", err)
+	return fallback, true
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// translateAPIKeyEnv is the environment variable that holds the API key of the
+// translation provider.
+const translateAPIKeyEnv = "TRANSLATE_API_KEY"
+
+// translateTimeout is how long a single translation may take.
+const translateTimeout = 10 * time.Second
+
+// translationSettings configures the translation of announcements.
+type translationSettings struct {
+	// Provider is the translation provider to use, either "deepl" or
+	// "libretranslate". If empty, announcements are not translated.
+	Provider string
+	// URL is the base URL of the provider's API. It may be left empty for
+	// DeepL, in which case its free API is used.
+	URL string
+	// Channels maps each language code, such as "de", to the channel that
+	// translated copies of announcements are posted in.
+	Channels map[string]discord.ChannelID
+}
+
+// translator translates text into another language.
+type translator interface {
+	Translate(ctx context.Context, text, lang string) (string, error)
+}
+
+// newTranslator creates the translator configured in the settings. It returns
+// nil if translation is disabled.
+func newTranslator(s translationSettings) (translator, error) {
+	apiKey := os.Getenv(translateAPIKeyEnv)
+
+	switch s.Provider {
+	case "":
+		return nil, nil
+	case "deepl":
+		url := s.URL
+		if url == "" {
+			url = "https://api-free.deepl.com"
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("DeepL requires $%s to be set", translateAPIKeyEnv)
+		}
+		return deeplTranslator{url: url, apiKey: apiKey}, nil
+	case "libretranslate":
+		if s.URL == "" {
+			return nil, errors.New("LibreTranslate requires a URL")
+		}
+		return libreTranslator{url: s.URL, apiKey: apiKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown translation provider %q", s.Provider)
+	}
+}
+
+// postJSON posts the request as JSON to the URL and decodes the response into
+// resp, unless resp is nil.
+func postJSON(ctx context.Context, url string, header http.Header, req, resp any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+type deeplTranslator struct {
+	url    string
+	apiKey string
+}
+
+func (t deeplTranslator) Translate(ctx context.Context, text, lang string) (string, error) {
+	req := struct {
+		Text       []string `json:"text"`
+		TargetLang string   `json:"target_lang"`
+	}{
+		Text:       []string{text},
+		TargetLang: strings.ToUpper(lang),
+	}
+
+	var resp struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+
+	header := http.Header{"Authorization": {"DeepL-Auth-Key " + t.apiKey}}
+	if err := postJSON(ctx, strings.TrimSuffix(t.url, "/")+"/v2/translate", header, req, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Translations) == 0 {
+		return "", errors.New("DeepL returned no translations")
+	}
+	return resp.Translations[0].Text, nil
+}
+
+type libreTranslator struct {
+	url    string
+	apiKey string
+}
+
+func (t libreTranslator) Translate(ctx context.Context, text, lang string) (string, error) {
+	req := struct {
+		Q      string `json:"q"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+		Format string `json:"format"`
+		APIKey string `json:"api_key,omitempty"`
+	}{
+		Q:      text,
+		Source: "auto",
+		Target: strings.ToLower(lang),
+		Format: "text",
+		APIKey: t.apiKey,
+	}
+
+	var resp struct {
+		TranslatedText string `json:"translatedText"`
+	}
+
+	if err := postJSON(ctx, strings.TrimSuffix(t.url, "/")+"/translate", nil, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.TranslatedText, nil
+}
+
+// translateAnnouncement renders the announcement translated into the given
+// language. Embeds are kept as they are, and the translated copy never pings
+// anyone, since the original already did.
+func translateAnnouncement(t translator, bot botState, body string, opts announceOptions, lang string) (announcementMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), translateTimeout)
+	defer cancel()
+
+	text, embeds, err := cutEmbeds(body)
+	if err != nil {
+		return announcementMessage{}, err
+	}
+
+	if text != "" {
+		text, err = t.Translate(ctx, text, lang)
+		if err != nil {
+			return announcementMessage{}, fmt.Errorf("cannot translate the body: %w", err)
+		}
+	}
+
+	if opts.Title != "" {
+		opts.Title, err = t.Translate(ctx, opts.Title, lang)
+		if err != nil {
+			return announcementMessage{}, fmt.Errorf("cannot translate the title: %w", err)
+		}
+	}
+
+	opts.NoPing = true
+
+	msg, err := bot.renderAnnouncement(opts, text)
+	if err != nil {
+		return announcementMessage{}, err
+	}
+	msg.Embeds = embeds
+	msg.Mentions = &api.AllowedMentions{}
+
+	return msg, nil
+}
+
+// postTranslations posts a translated copy of the announcement with the given
+// primary message into each of the translation channels, and records the
+// copies once they are posted. Translating takes a while, so it is done in
+// the background.
+func (h *commandHandler) postTranslations(id discord.MessageID, body string, opts announceOptions) {
+	if h.translator == nil || len(h.bot.Translation.Channels) == 0 {
+		return
+	}
+
+	t := h.translator
+	sender := h.sender()
+
+	h.inBackground(func() func() {
+		copies := make(map[string]messageRef, len(sender.bot.Translation.Channels))
+		for lang, channelID := range sender.bot.Translation.Channels {
+			msg, err := translateAnnouncement(t, sender.bot, body, opts, lang)
+			if err != nil {
+				slog.Error(
+					"Bot has failed to translate the announcement.",
+					"lang", lang,
+					"err", err)
+				continue
+			}
+
+			var sent *discord.Message
+			err = withRetry("send translation", func() (err error) {
+				sent, err = sender.send(channelID, msg, opts)
+				return err
+			})
+			if err != nil {
+				slog.Error(
+					"Bot has failed to send the translated announcement.",
+					"lang", lang,
+					"channel_id", channelID,
+					"err", err)
+				continue
+			}
+
+			copies[lang] = messageRef{ChannelID: sent.ChannelID, MessageID: sent.ID}
+		}
+
+		return func() { h.recordTranslations(id, body, copies) }
+	})
+}
+
+// recordTranslations adds the translated copies of the announcement to its
+// record. body is what was translated, so that copies of an announcement
+// that was edited in the meantime can be brought up to date.
+func (h *commandHandler) recordTranslations(id discord.MessageID, body string, copies map[string]messageRef) {
+	if len(copies) == 0 {
+		return
+	}
+
+	record, ok, err := h.announcements.Load(id)
+	if err != nil || !ok {
+		slog.Warn(
+			"Bot has failed to look up the announcement to record its translations. They won't be edited or deleted along with it.",
+			"message_id", id,
+			"err", err)
+		return
+	}
+
+	if record.Translations == nil {
+		record.Translations = make(map[string]messageRef, len(copies))
+	}
+	maps.Copy(record.Translations, copies)
+
+	if err := h.announcements.Store(id, record); err != nil {
+		slog.Warn(
+			"Bot has failed to record the translations of the announcement. They won't be edited or deleted along with it.",
+			"message_id", id,
+			"err", err)
+		return
+	}
+
+	if record.Body != body {
+		h.editTranslations(id, copies, record.Body, record.Options)
+	}
+}
+
+// editTranslations re-translates the edited announcement with the given
+// primary message and edits each of its translated copies in the background.
+func (h *commandHandler) editTranslations(id discord.MessageID, copies map[string]messageRef, body string, opts announceOptions) {
+	if h.translator == nil || len(copies) == 0 {
+		return
+	}
+
+	t := h.translator
+	sender := h.sender()
+
+	h.inBackground(func() func() {
+		var errs []error
+		for lang, ref := range copies {
+			msg, err := translateAnnouncement(t, sender.bot, body, opts, lang)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", lang, err))
+				continue
+			}
+
+			err = withRetry("edit translation", func() error {
+				return sender.edit(ref, msg)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", lang, err))
+			}
+		}
+
+		if err := errors.Join(errs...); err != nil {
+			slog.Error(
+				"Bot has failed to edit some of the translated announcements.",
+				"message_id", id,
+				"err", err)
+		}
+		return nil
+	})
+}
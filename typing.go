@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+const (
+	// typingDelay is how long a command may take before the bot shows that
+	// it's typing. Quick commands are replied to before then.
+	typingDelay = time.Second
+	// typingInterval is how often the typing indicator is renewed. Discord
+	// clears it after about 10 seconds.
+	typingInterval = 8 * time.Second
+)
+
+// startTyping shows the typing indicator in the channel of the command for as
+// long as it takes to handle, once it has taken longer than typingDelay. The
+// returned function stops it. Only message commands get the indicator, since
+// interactions have their own.
+func (h *commandHandler) startTyping(inv *invocation) (stop func()) {
+	if inv.Message == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		timer := time.NewTimer(typingDelay)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+			}
+
+			if err := h.session.Typing(inv.ChannelID); err != nil {
+				slog.Debug(
+					"Bot has failed to show that it is typing.",
+					"channel_id", inv.ChannelID,
+					"err", err)
+				return
+			}
+			timer.Reset(typingInterval)
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/diamondburned/ningen/v3"
+)
+
+// watchdogCheckInterval is how often the watchdog checks the gateway.
+const watchdogCheckInterval = 30 * time.Second
+
+// minWatchdogTimeout is the shortest WatchdogTimeout that may be configured.
+// Discord asks for heartbeats less than a minute apart, so anything shorter
+// would restart healthy sessions.
+const minWatchdogTimeout = time.Minute
+
+// connectWithWatchdog connects the session to Discord like session.Connect,
+// except that the connection is torn down and opened again from scratch if
+// nothing has come from the gateway for longer than timeout. If timeout is
+// zero, the connection is never restarted.
+func connectWithWatchdog(ctx context.Context, session *ningen.State, monitor *gatewayMonitor, timeout time.Duration) error {
+	if timeout <= 0 {
+		return session.Connect(ctx)
+	}
+
+	ticker := time.NewTicker(watchdogCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		connectCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- session.Connect(connectCtx) }()
+
+		// The new connection needs time to come up before it can be judged.
+		started := time.Now()
+		restart := false
+
+		for !restart {
+			select {
+			case err := <-done:
+				cancel()
+				return err
+			case <-ticker.C:
+			}
+
+			last := monitor.status().lastSignOfLife()
+			if last.Before(started) {
+				last = started
+			}
+
+			if time.Since(last) > timeout {
+				slog.Error(
+					"Bot has heard nothing from the gateway for too long. It will reconnect from scratch.",
+					"since", last,
+					"timeout", timeout)
+				restart = true
+			}
+		}
+
+		// Closing the session invalidates it, so the new connection
+		// identifies again instead of resuming whatever got stuck.
+		cancel()
+		if err := <-done; err != nil {
+			slog.Warn(
+				"Bot's stuck gateway connection has closed with an error.",
+				"err", err)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}